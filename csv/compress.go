@@ -0,0 +1,310 @@
+package csv
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"io"
+
+	dsbzip2 "github.com/dsnet/compress/bzip2"
+	"github.com/flarco/g"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Codec is a compression format for CSV streams.
+type Codec string
+
+const (
+	CodecNone  Codec = ""
+	CodecGzip  Codec = "gzip"
+	CodecZstd  Codec = "zstd"
+	CodecBzip2 Codec = "bzip2"
+)
+
+var (
+	magicGzip  = []byte{0x1f, 0x8b}
+	magicZstd  = []byte{0x28, 0xb5, 0x2f, 0xfd}
+	magicBzip2 = []byte("BZh")
+
+	// bzip2 streams start with "BZh", the block size level '1'-'9', then
+	// the magic of the first block, or of the end of stream if empty.
+	magicBzip2Block = []byte{0x31, 0x41, 0x59, 0x26, 0x53, 0x59}
+	magicBzip2End   = []byte{0x17, 0x72, 0x45, 0x38, 0x50, 0x90}
+)
+
+// magicMaxLen is the number of bytes needed by DetectCodec.
+const magicMaxLen = 10
+
+// DetectCodec returns the codec matching the magic bytes at the start of data.
+// For bzip2, the whole stream header is checked, so that plain text starting
+// with "BZh" is not mistaken for it.
+func DetectCodec(data []byte) Codec {
+	switch {
+	case bytes.HasPrefix(data, magicGzip):
+		return CodecGzip
+	case bytes.HasPrefix(data, magicZstd):
+		return CodecZstd
+	case isBzip2Header(data):
+		return CodecBzip2
+	}
+	return CodecNone
+}
+
+// isBzip2Header reports whether data starts with a bzip2 stream header.
+func isBzip2Header(data []byte) bool {
+	if len(data) < magicMaxLen || !bytes.HasPrefix(data, magicBzip2) {
+		return false
+	}
+	if level := data[3]; level < '1' || level > '9' {
+		return false
+	}
+	block := data[4:magicMaxLen]
+	return bytes.Equal(block, magicBzip2Block) || bytes.Equal(block, magicBzip2End)
+}
+
+// Decompress peeks at the first bytes of r and returns a reader
+// decompressing it, along with the detected codec.
+// Uncompressed input is returned as is. If the returned reader is an
+// io.Closer, as for zstd, it should be closed once done to release the
+// decoder.
+func Decompress(r io.Reader) (reader io.Reader, codec Codec, err error) {
+	data, reader, err := g.Peek(r, magicMaxLen)
+	if err != nil {
+		return r, CodecNone, g.Error(err, "could not peek for compression codec")
+	}
+
+	codec = DetectCodec(data)
+	switch codec {
+	case CodecGzip:
+		reader, err = gzip.NewReader(reader)
+	case CodecZstd:
+		var dec *zstd.Decoder
+		dec, err = zstd.NewReader(reader, zstd.WithDecoderConcurrency(1))
+		if err == nil {
+			reader = dec.IOReadCloser()
+		}
+	case CodecBzip2:
+		reader = bzip2.NewReader(reader)
+	}
+	if err != nil {
+		err = g.Error(err, "could not create %s reader", codec)
+	}
+
+	return
+}
+
+// decompressReader detects the codec on the first Read, so that
+// constructing a reader never blocks on the underlying stream. The
+// decompressor is closed once the stream ends or fails.
+type decompressReader struct {
+	r      io.Reader
+	err    error
+	loaded bool
+}
+
+func newDecompressReader(r io.Reader) *decompressReader {
+	return &decompressReader{r: r}
+}
+
+func (dr *decompressReader) Read(p []byte) (n int, err error) {
+	if !dr.loaded {
+		dr.loaded = true
+		dr.r, _, dr.err = Decompress(dr.r)
+	}
+	if dr.err != nil {
+		return 0, dr.err
+	}
+	n, err = dr.r.Read(p)
+	if err != nil {
+		dr.err = err
+		if closer, ok := dr.r.(io.Closer); ok {
+			closer.Close()
+		}
+	}
+	return n, err
+}
+
+// PartFunc opens the destination of the part numbered n, starting at 0.
+type PartFunc func(n int) (io.WriteCloser, error)
+
+// CompressOptions configures a writer from NewCompressedWriterSize.
+type CompressOptions struct {
+	Codec Codec // Compression codec, CodecNone to write plain CSV
+	Level int   // Codec specific level, 0 for the default
+
+	// MaxBytes and MaxRows trigger a roll over to a new part once the
+	// uncompressed bytes or records written to the current part reach them.
+	// Zero means no limit.
+	MaxBytes int64
+	MaxRows  int64
+
+	// Header, if set, is written at the start of every part.
+	Header []string
+}
+
+// NewCompressedWriterSize returns a new Writer compressing its output with
+// opts.Codec, along with buffer size. Parts are opened with open, starting
+// with part 0. A new part is only opened when a record is written after a
+// threshold of opts is reached, so no empty trailing part is created.
+// Close must be called to terminate the compressed stream of the last part.
+func NewCompressedWriterSize(open PartFunc, size int, opts CompressOptions) (*Writer, error) {
+	w := &Writer{
+		Comma: ",",
+		Quote: '"',
+		parts: &partWriter{open: open, opts: opts, size: size},
+	}
+	if err := w.parts.next(w); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// CompressedBytes returns the number of bytes written to the destination,
+// after compression. For uncompressed writers it equals Bytes once flushed.
+func (w *Writer) CompressedBytes() int64 {
+	if w.parts == nil {
+		return int64(w.bytes)
+	}
+	return w.parts.compressed
+}
+
+// Parts returns the number of parts opened so far.
+func (w *Writer) Parts() int {
+	if w.parts == nil {
+		return 1
+	}
+	return w.parts.number
+}
+
+// Close flushes the buffered data, and for compressed writers terminates
+// the compressed stream and closes the current part.
+func (w *Writer) Close() error {
+	if w.parts == nil {
+		return w.w.Flush()
+	}
+	return w.parts.close(w)
+}
+
+// partWriter tracks the current part of a compressed Writer.
+type partWriter struct {
+	open       PartFunc
+	opts       CompressOptions
+	size       int
+	number     int   // number of parts opened
+	rows       int64 // records written to the current part
+	bytesStart int   // Writer bytes when the current part started
+	compressed int64 // compressed bytes written, for all parts
+	roll       bool  // whether a new part must be opened before the next record
+	fresh      bool  // whether the current part has no record yet
+
+	file       io.WriteCloser
+	compressor io.WriteCloser
+}
+
+// prepare opens a new part if needed, writing the header to it.
+func (pw *partWriter) prepare(w *Writer) (tbw int, err error) {
+	if pw.roll {
+		if err = pw.close(w); err != nil {
+			return 0, err
+		}
+		if err = pw.next(w); err != nil {
+			return 0, err
+		}
+	}
+	if !pw.fresh {
+		return 0, nil
+	}
+	pw.fresh = false
+	if len(pw.opts.Header) > 0 {
+		tbw, err = w.encodeRecord(pw.opts.Header, nil)
+	}
+	return
+}
+
+// recorded counts a record, flagging a roll over when a threshold is reached.
+func (pw *partWriter) recorded(w *Writer) {
+	pw.rows++
+	if pw.opts.MaxRows > 0 && pw.rows >= pw.opts.MaxRows {
+		pw.roll = true
+	}
+	if pw.opts.MaxBytes > 0 && int64(w.bytes-pw.bytesStart) >= pw.opts.MaxBytes {
+		pw.roll = true
+	}
+}
+
+// next opens the following part and points the Writer buffer at it.
+func (pw *partWriter) next(w *Writer) (err error) {
+	pw.file, err = pw.open(pw.number)
+	if err != nil {
+		return g.Error(err, "could not open part %d", pw.number)
+	}
+
+	counter := &countingWriter{w: pw.file, n: &pw.compressed}
+	pw.compressor, err = newCompressor(counter, pw.opts.Codec, pw.opts.Level)
+	if err != nil {
+		pw.file.Close()
+		return g.Error(err, "could not create %s writer", pw.opts.Codec)
+	}
+
+	if w.w == nil {
+		w.w = bufio.NewWriterSize(pw.compressor, pw.size)
+	} else {
+		w.w.Reset(pw.compressor)
+	}
+	pw.number++
+	pw.rows = 0
+	pw.bytesStart = w.bytes
+	pw.roll = false
+	pw.fresh = true
+	return nil
+}
+
+// close flushes and terminates the current part.
+func (pw *partWriter) close(w *Writer) error {
+	eg := g.ErrorGroup{}
+	eg.Capture(w.w.Flush())
+	eg.Capture(pw.compressor.Close())
+	eg.Capture(pw.file.Close())
+	return eg.Err()
+}
+
+// newCompressor returns a writer compressing into w with codec.
+func newCompressor(w io.Writer, codec Codec, level int) (io.WriteCloser, error) {
+	switch codec {
+	case CodecNone:
+		return nopWriteCloser{w}, nil
+	case CodecGzip:
+		if level == 0 {
+			level = gzip.DefaultCompression
+		}
+		return gzip.NewWriterLevel(w, level)
+	case CodecZstd:
+		opts := []zstd.EOption{zstd.WithEncoderConcurrency(1)}
+		if level > 0 {
+			opts = append(opts, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(level)))
+		}
+		return zstd.NewWriter(w, opts...)
+	case CodecBzip2:
+		return dsbzip2.NewWriter(w, &dsbzip2.WriterConfig{Level: level})
+	}
+	return nil, g.Error("unsupported codec: %s", codec)
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+// countingWriter counts the bytes written through it into n.
+type countingWriter struct {
+	w io.Writer
+	n *int64
+}
+
+func (cw *countingWriter) Write(p []byte) (n int, err error) {
+	n, err = cw.w.Write(p)
+	*cw.n = *cw.n + int64(n)
+	return
+}
@@ -0,0 +1,122 @@
+package csv
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type bufferCloser struct {
+	bytes.Buffer
+	closed bool
+}
+
+func (b *bufferCloser) Close() error {
+	b.closed = true
+	return nil
+}
+
+func TestCompressedRoundTrip(t *testing.T) {
+	records := [][]string{
+		{"id", "name"},
+		{"1", "Rob"},
+		{"2", "Ken, \"the\" legend"},
+	}
+
+	for _, codec := range []Codec{CodecNone, CodecGzip, CodecZstd, CodecBzip2} {
+		buf := &bufferCloser{}
+		w, err := NewCompressedWriterSize(func(n int) (io.WriteCloser, error) {
+			return buf, nil
+		}, 4096, CompressOptions{Codec: codec})
+		if !assert.NoError(t, err, codec) {
+			continue
+		}
+		for _, record := range records {
+			_, err = w.Write(record)
+			assert.NoError(t, err, codec)
+		}
+		assert.NoError(t, w.Close(), codec)
+		assert.True(t, buf.closed, codec)
+		assert.Equal(t, codec, DetectCodec(buf.Bytes()), codec)
+		assert.EqualValues(t, buf.Len(), w.CompressedBytes(), codec)
+		assert.Equal(t, 38, w.Bytes(), codec)
+
+		data := buf.Bytes()
+		rows, err := NewReader(bytes.NewReader(data)).ReadAll()
+		assert.NoError(t, err, codec)
+		assert.Equal(t, records, rows, codec)
+
+		rows, err = NewCsv().NewReader(bytes.NewReader(data)).ReadAll()
+		assert.NoError(t, err, codec)
+		assert.Equal(t, records, rows, codec)
+	}
+}
+
+func TestCompressedRollOver(t *testing.T) {
+	parts := []*bufferCloser{}
+	open := func(n int) (io.WriteCloser, error) {
+		parts = append(parts, &bufferCloser{})
+		return parts[n], nil
+	}
+
+	w, err := NewCompressedWriterSize(open, 4096, CompressOptions{
+		Codec:   CodecGzip,
+		MaxRows: 2,
+		Header:  []string{"id"},
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	for _, id := range []string{"1", "2", "3", "4"} {
+		_, err = w.Write([]string{id})
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, w.Close())
+
+	if assert.Len(t, parts, 2) && assert.Equal(t, 2, w.Parts()) {
+		for i, part := range parts {
+			assert.True(t, part.closed)
+			rows, err := NewReader(part).ReadAll()
+			assert.NoError(t, err)
+			assert.Equal(t, [][]string{{"id"}, {string(rune('1' + 2*i))}, {string(rune('2' + 2*i))}}, rows)
+		}
+	}
+
+	// byte threshold, uncompressed
+	parts = parts[:0]
+	w, _ = NewCompressedWriterSize(open, 4096, CompressOptions{MaxBytes: 6})
+	for _, record := range []string{"ab", "cd", "efgh", "i"} {
+		w.Write([]string{record})
+	}
+	assert.NoError(t, w.Close())
+	if assert.Len(t, parts, 2) {
+		assert.Equal(t, "ab\ncd\n", parts[0].String())
+		assert.Equal(t, "efgh\ni\n", parts[1].String())
+	}
+}
+
+func TestDetectCodecPlainText(t *testing.T) {
+	for _, text := range []string{"BZh,level\n1,2\n", "BZh9\n", "BZh"} {
+		assert.Equal(t, CodecNone, DetectCodec([]byte(text)), text)
+
+		rows, err := NewReader(bytes.NewReader([]byte(text))).ReadAll()
+		assert.NoError(t, err, text)
+		assert.NotEmpty(t, rows, text)
+
+		rows, err = NewCsv().NewReader(bytes.NewReader([]byte(text))).ReadAll()
+		assert.NoError(t, err, text)
+		assert.NotEmpty(t, rows, text)
+	}
+
+	// an empty bzip2 stream has no block, only the end of stream magic
+	buf := &bufferCloser{}
+	w, err := NewCompressedWriterSize(func(n int) (io.WriteCloser, error) {
+		return buf, nil
+	}, 4096, CompressOptions{Codec: CodecBzip2})
+	if assert.NoError(t, err) {
+		assert.NoError(t, w.Close())
+		assert.Equal(t, CodecBzip2, DetectCodec(buf.Bytes()))
+	}
+}
@@ -43,6 +43,12 @@ type CsvReader struct {
 	token      Token
 	cell       Cell
 	row        Row
+	header     []string
+
+	projection *Projection
+	cellIndex  int   // index of the current cell in the source record
+	slots      []int // output position per source column, -1 if skipped
+	rowSlots   []int // output position of each cell in row
 }
 
 type Row []Cell
@@ -73,10 +79,13 @@ func NewCsv(options ...CsvOptions) *Csv {
 	return &Csv{options: opts}
 }
 
+// NewReader returns a new CsvReader that reads from r.
+// Input compressed with gzip, zstd or bzip2 is detected on the first read
+// and decompressed transparently.
 func (c *Csv) NewReader(r io.Reader) *CsvReader {
 
 	cr := &CsvReader{
-		reader: bufio.NewReaderSize(r, 100*1024),
+		reader: bufio.NewReaderSize(newDecompressReader(r), 100*1024),
 		state:  StateRead,
 		csv:    c,
 		cell:   make(Cell, 0, 1),
@@ -84,7 +93,33 @@ func (c *Csv) NewReader(r io.Reader) *CsvReader {
 	return cr
 }
 
+// Read reads one record (a slice of fields) from the reader.
+// With the Header option, the first record is kept as the header (see
+// Header) and still returned. With a Projection, only the selected columns
+// are returned, and records failing its Filter or Where are skipped.
 func (cr *CsvReader) Read() (row []string, err error) {
+	if cr.csv.options.Header && cr.header == nil {
+		return cr.readHeader()
+	}
+
+	for {
+		row, err = cr.readRecord()
+		if err != nil || cr.projection == nil || !cr.projection.filters() {
+			return
+		}
+		keep, err := cr.projection.keep(RowView{cr: cr})
+		if err != nil {
+			return nil, g.Error(err, "csv: could not filter record at line %d", cr.line)
+		}
+		if keep {
+			return cr.Row(), nil
+		}
+	}
+}
+
+// readRecord reads the next record. With a Projection, the record
+// is not materialized, as it may still be filtered out.
+func (cr *CsvReader) readRecord() (row []string, err error) {
 	var ok bool
 	for {
 		line, hasMore, err := cr.reader.ReadLine()
@@ -139,10 +174,25 @@ func (cr *CsvReader) endToken(endColumn int) {
 }
 
 func (cr *CsvReader) endCell() {
-	if len(cr.cell) > 0 {
+	if len(cr.cell) == 0 {
+		return
+	}
+
+	if cr.slots == nil {
+		cr.row = append(cr.row, cr.cell)
+		cr.cell = make(Cell, 0, 1)
+		return
+	}
+
+	// with a projection, skipped cells are dropped and their tokens reused
+	if cr.cellIndex < len(cr.slots) && cr.slots[cr.cellIndex] >= 0 {
 		cr.row = append(cr.row, cr.cell)
+		cr.rowSlots = append(cr.rowSlots, cr.slots[cr.cellIndex])
+		cr.cell = make(Cell, 0, 1)
+	} else {
+		cr.cell = cr.cell[:0]
 	}
-	cr.cell = make(Cell, 0, 1)
+	cr.cellIndex++
 }
 
 // ReadAll reads all the remaining records from r.
@@ -172,6 +222,8 @@ func (cr *CsvReader) readLine(line []byte) (row []string, ok bool, err error) {
 		cr.column = -1
 		cr.cell = make(Cell, 0, 1)
 		cr.row = cr.row[:0] // reset
+		cr.rowSlots = cr.rowSlots[:0]
+		cr.cellIndex = 0
 		if cap(cr.row) < cr.numFields {
 			cr.row = make(Row, 0, cr.numFields)
 		}
@@ -246,7 +298,9 @@ func (cr *CsvReader) readLine(line []byte) (row []string, ok bool, err error) {
 	ok = cr.state != StateInQuote
 	if ok {
 		cr.endCell()
-		row = cr.Row()
+		if cr.projection == nil || !cr.projection.filters() {
+			row = cr.Row()
+		}
 		cr.state = StateRowEnded
 	}
 
@@ -270,6 +324,16 @@ func (cr *CsvReader) Row() (row []string) {
 	// converts once to reduce allocations
 	lineBuffer := cr.lineBuffer.String()
 
+	if cr.slots != nil {
+		row = make([]string, cr.projection.width())
+		for i, cell = range cr.row {
+			for _, token = range cell {
+				row[cr.rowSlots[i]] = row[cr.rowSlots[i]] + lineBuffer[token.Start:token.End]
+			}
+		}
+		return
+	}
+
 	row = make([]string, len(cr.row))
 	for i, cell = range cr.row {
 		for _, token = range cell {
@@ -281,9 +345,11 @@ func (cr *CsvReader) Row() (row []string) {
 
 func (c *Csv) NewWriter(w io.Writer) *Writer {
 	cr := &Writer{
-		Comma: string(c.options.Delimiter),
-		w:     bufio.NewWriterSize(w, 100*1024),
-		bytes: 0,
+		Comma:  string(c.options.Delimiter),
+		Quote:  c.options.Quote,
+		Escape: c.options.Escape,
+		w:      bufio.NewWriterSize(w, 100*1024),
+		bytes:  0,
 	}
 	return cr
 }
@@ -0,0 +1,502 @@
+package csv
+
+import (
+	"bytes"
+	"encoding"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/flarco/g"
+	"github.com/spf13/cast"
+)
+
+var (
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	timeType            = reflect.TypeOf(time.Time{})
+)
+
+// structField is a struct field bound to a CSV column.
+type structField struct {
+	name      string
+	index     []int // the index sequence, through embedded structs
+	omitEmpty bool
+}
+
+var fieldCache sync.Map // map[reflect.Type][]structField
+
+// cachedStructFields returns the CSV columns of struct type t.
+// The column name is taken from the `csv` tag, then the `json` key, then the
+// field name. Fields tagged `csv:"-"`, or `json:"-"` without a `csv` tag,
+// and unexported fields are ignored. The fields of embedded structs without
+// a name in their tags are columns of t, unless hidden by a field of the
+// same name at a shallower depth, as with encoding/json.
+func cachedStructFields(t reflect.Type) []structField {
+	if f, ok := fieldCache.Load(t); ok {
+		return f.([]structField)
+	}
+
+	all := appendStructFields(nil, t, nil)
+	fields := []structField{}
+	for _, field := range all {
+		dominant := true
+		for _, other := range all {
+			if other.name == field.name && len(other.index) < len(field.index) {
+				dominant = false
+				break
+			}
+		}
+		for _, other := range fields {
+			if other.name == field.name {
+				dominant = false
+				break
+			}
+		}
+		if dominant {
+			fields = append(fields, field)
+		}
+	}
+
+	f, _ := fieldCache.LoadOrStore(t, fields)
+	return f.([]structField)
+}
+
+// appendStructFields appends the fields of struct type t, whose index
+// sequence starts with index, flattening embedded structs.
+func appendStructFields(fields []structField, t reflect.Type, index []int) []structField {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("csv")
+		if tag == "-" {
+			continue
+		}
+		name, opts := parseTag(tag)
+		if name == "" {
+			jsonTag := sf.Tag.Get("json")
+			if jsonTag == "-" {
+				continue
+			}
+			name = strings.Split(jsonTag, ",")[0]
+		}
+
+		fieldIndex := append(index[:len(index):len(index)], i)
+		if ft, ok := structType(sf.Type); sf.Anonymous && ok && name == "" && ft != timeType &&
+			!reflect.PointerTo(ft).Implements(textMarshalerType) {
+			if sf.IsExported() || sf.Type.Kind() != reflect.Ptr {
+				fields = appendStructFields(fields, ft, fieldIndex)
+			}
+			continue
+		}
+		if !sf.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		fields = append(fields, structField{name: name, index: fieldIndex, omitEmpty: opts.Contains("omitempty")})
+	}
+	return fields
+}
+
+// fieldByIndex returns the field of the struct v at index, through
+// embedded struct pointers, which are allocated if alloc is set. It
+// returns false for a nil embedded pointer which is not allocated.
+func fieldByIndex(v reflect.Value, index []int, alloc bool) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				if !alloc || !v.CanSet() {
+					return reflect.Value{}, false
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+// structType returns the struct type underlying t, dereferencing pointers.
+func structType(t reflect.Type) (reflect.Type, bool) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t, t.Kind() == reflect.Struct
+}
+
+// A Decoder reads CSV records and decodes them into structs, binding the
+// header names to struct fields. Records are decoded one at a time, so a
+// whole file is never loaded in memory.
+type Decoder struct {
+	reader CsvReaderLike
+	header []string
+
+	disallowUnknownFields bool
+	line                  int
+	indexes               map[reflect.Type][]int // struct field index per column, -1 if none
+}
+
+// NewDecoder returns a new Decoder that reads from r with a Reader.
+// The first record is read as the header.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{reader: NewReader(r)}
+}
+
+// NewDecoder returns a new Decoder that reads from r with a CsvReader.
+// The first record is read as the header.
+func (c *Csv) NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{reader: c.NewReader(r)}
+}
+
+// DisallowUnknownFields causes Decode to return an error when the header
+// contains a column which does not match any struct field.
+func (d *Decoder) DisallowUnknownFields() { d.disallowUnknownFields = true }
+
+// Header returns the header record, reading it if needed.
+func (d *Decoder) Header() ([]string, error) {
+	if d.header == nil {
+		header, err := d.reader.Read()
+		if err != nil {
+			return nil, err
+		}
+		d.header = append([]string{}, header...)
+		d.line++
+	}
+	return d.header, nil
+}
+
+// Decode reads the next record and stores it in the struct pointed to by v.
+// At the end of the input stream, Decode returns io.EOF.
+func (d *Decoder) Decode(v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return g.Error("csv: Decode requires a non-nil pointer, got %s", reflect.TypeOf(v))
+	}
+	t, ok := structType(rv.Type())
+	if !ok {
+		return g.Error("csv: Decode requires a pointer to a struct, got %s", rv.Type())
+	}
+
+	if _, err := d.Header(); err != nil {
+		return err
+	}
+
+	indexes, err := d.columnIndexes(t)
+	if err != nil {
+		return err
+	}
+
+	record, err := d.reader.Read()
+	if err != nil {
+		return err
+	}
+	d.line++
+
+	rv = indirect(rv)
+	fields := cachedStructFields(t)
+	for col, value := range record {
+		if col >= len(indexes) || indexes[col] < 0 {
+			continue
+		}
+		field := fields[indexes[col]]
+		fv, ok := fieldByIndex(rv, field.index, true)
+		if !ok {
+			continue
+		}
+		if err := decodeValue(fv, value); err != nil {
+			return g.Error(err, "could not decode column %s on record %d", field.name, d.line)
+		}
+	}
+
+	return nil
+}
+
+// columnIndexes maps each header column to a field of t, matching names
+// exactly first, then case-insensitively.
+func (d *Decoder) columnIndexes(t reflect.Type) ([]int, error) {
+	if indexes, ok := d.indexes[t]; ok {
+		return indexes, nil
+	}
+
+	fields := cachedStructFields(t)
+	indexes := make([]int, len(d.header))
+	for col, name := range d.header {
+		indexes[col] = -1
+		for i, field := range fields {
+			if field.name == name {
+				indexes[col] = i
+				break
+			}
+		}
+		if indexes[col] >= 0 {
+			continue
+		}
+		for i, field := range fields {
+			if strings.EqualFold(field.name, name) {
+				indexes[col] = i
+				break
+			}
+		}
+		if indexes[col] < 0 && d.disallowUnknownFields {
+			return nil, g.Error("csv: unknown field %q for %s", name, t)
+		}
+	}
+
+	if d.indexes == nil {
+		d.indexes = map[reflect.Type][]int{}
+	}
+	d.indexes[t] = indexes
+	return indexes, nil
+}
+
+// indirect walks down v allocating pointers as needed,
+// until it gets to a non-pointer.
+func indirect(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+// decodeValue sets v from the field text s. Types implementing
+// encoding.TextUnmarshaler decode themselves. An empty s sets pointers to nil
+// and other values to their zero value.
+func decodeValue(v reflect.Value, s string) error {
+	if s == "" && v.Kind() != reflect.String {
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	}
+
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return decodeValue(v.Elem(), s)
+	}
+
+	if v.Type() == timeType {
+		t, err := cast.ToTimeE(s)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	}
+
+	if v.CanAddr() && v.Addr().Type().Implements(textUnmarshalerType) {
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(n)
+	case reflect.Interface:
+		if v.NumMethod() != 0 {
+			return g.Error("cannot decode into %s", v.Type())
+		}
+		v.Set(reflect.ValueOf(s))
+	default:
+		return g.Error("cannot decode into %s", v.Type())
+	}
+	return nil
+}
+
+// An Encoder writes structs as CSV records, preceded by a header made of
+// the struct field names.
+type Encoder struct {
+	w      *Writer
+	fields []structField
+	typ    reflect.Type
+}
+
+// NewEncoder returns a new Encoder that writes to w with a Writer.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: NewWriter(w)}
+}
+
+// NewEncoder returns a new Encoder that writes to w with the Csv options.
+func (c *Csv) NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: c.NewWriter(w)}
+}
+
+// Writer returns the underlying Writer, to adjust its options.
+func (e *Encoder) Writer() *Writer {
+	return e.w
+}
+
+// Encode writes v as a record. v must be a struct, a pointer to a struct,
+// or a slice of them. The header is written before the first record, and
+// all records must be of the same type.
+func (e *Encoder) Encode(v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array {
+		for i := 0; i < rv.Len(); i++ {
+			if err := e.encodeStruct(rv.Index(i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return e.encodeStruct(rv)
+}
+
+func (e *Encoder) encodeStruct(rv reflect.Value) error {
+	if !rv.IsValid() {
+		return g.Error("csv: cannot encode nil value")
+	}
+	t, ok := structType(rv.Type())
+	if !ok {
+		return g.Error("csv: Encode requires a struct, got %s", rv.Type())
+	}
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return g.Error("csv: cannot encode nil %s", rv.Type())
+		}
+		rv = rv.Elem()
+	}
+
+	if e.typ == nil {
+		e.typ = t
+		e.fields = cachedStructFields(t)
+		header := make([]string, len(e.fields))
+		for i, field := range e.fields {
+			header[i] = field.name
+		}
+		if _, err := e.w.Write(header); err != nil {
+			return err
+		}
+	} else if e.typ != t {
+		return g.Error("csv: cannot encode %s after %s", t, e.typ)
+	}
+
+	record := make([]string, len(e.fields))
+	for i, field := range e.fields {
+		fv, ok := fieldByIndex(rv, field.index, false)
+		if !ok || field.omitEmpty && fv.IsZero() {
+			continue
+		}
+		s, err := encodeValue(fv)
+		if err != nil {
+			return g.Error(err, "could not encode column %s", field.name)
+		}
+		record[i] = s
+	}
+	_, err := e.w.Write(record)
+	return err
+}
+
+// Flush writes any buffered data to the underlying io.Writer.
+func (e *Encoder) Flush() error {
+	return e.w.w.Flush()
+}
+
+// encodeValue returns the field text of v. Types implementing
+// encoding.TextMarshaler encode themselves. Nil pointers are empty.
+func encodeValue(v reflect.Value) (string, error) {
+	if v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return "", nil
+		}
+		if v.Kind() == reflect.Ptr && v.Type().Implements(textMarshalerType) {
+			b, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+			return string(b), err
+		}
+		return encodeValue(v.Elem())
+	}
+
+	if v.Type() == timeType {
+		return formatValue(v.Interface()), nil
+	}
+
+	if v.Type().Implements(textMarshalerType) {
+		b, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+		return string(b), err
+	}
+	if v.CanAddr() && v.Addr().Type().Implements(textMarshalerType) {
+		b, err := v.Addr().Interface().(encoding.TextMarshaler).MarshalText()
+		return string(b), err
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, v.Type().Bits()), nil
+	}
+	return "", g.Error("cannot encode %s", v.Type())
+}
+
+// Marshal returns the CSV encoding of v, a slice of structs (or pointers to
+// structs), with a header made of the struct field names.
+func Marshal(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	e := NewEncoder(&buf)
+	if err := e.Encode(v); err != nil {
+		return nil, err
+	}
+	if err := e.Flush(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Unmarshal parses the CSV data, header first, and appends each record to
+// the slice pointed to by v.
+func Unmarshal(data []byte, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Slice {
+		return g.Error("csv: Unmarshal requires a pointer to a slice, got %s", reflect.TypeOf(v))
+	}
+
+	slice := rv.Elem()
+	elemType := slice.Type().Elem()
+	d := NewDecoder(bytes.NewReader(data))
+	for {
+		elem := reflect.New(elemType)
+		err := d.Decode(elem.Interface())
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		slice.Set(reflect.Append(slice, elem.Elem()))
+	}
+}
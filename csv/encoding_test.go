@@ -0,0 +1,144 @@
+package csv
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type level int
+
+func (l *level) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "low":
+		*l = 1
+	case "high":
+		*l = 2
+	default:
+		*l = 0
+	}
+	return nil
+}
+
+func (l level) MarshalText() ([]byte, error) {
+	return []byte([]string{"none", "low", "high"}[l]), nil
+}
+
+type person struct {
+	ID      int        `csv:"id"`
+	Name    string     `csv:"name"`
+	Email   string     `json:"email"`
+	Rating  *float64   `csv:"rating,omitempty"`
+	Created time.Time  `csv:"created"`
+	Level   level      `csv:"level"`
+	Ignored string     `csv:"-"`
+	Updated *time.Time `csv:"updated"`
+	secret  string
+}
+
+func TestDecoder(t *testing.T) {
+	in := `id,Name,email,rating,created,level,extra,updated
+1,Rob,rob@example.com,4.5,2019-08-19 17:02:09,high,x,
+2,"Ken, T",,,2020-01-02T03:04:05Z,low,y,2021-01-01
+`
+	d := NewDecoder(strings.NewReader(in))
+	var p person
+	if !assert.NoError(t, d.Decode(&p)) {
+		return
+	}
+	assert.Equal(t, 1, p.ID)
+	assert.Equal(t, "Rob", p.Name)
+	assert.Equal(t, "rob@example.com", p.Email)
+	if assert.NotNil(t, p.Rating) {
+		assert.Equal(t, 4.5, *p.Rating)
+	}
+	assert.Equal(t, time.Date(2019, 8, 19, 17, 2, 9, 0, time.UTC), p.Created)
+	assert.Equal(t, level(2), p.Level)
+	assert.Nil(t, p.Updated)
+
+	p = person{}
+	if !assert.NoError(t, d.Decode(&p)) {
+		return
+	}
+	assert.Equal(t, "Ken, T", p.Name)
+	assert.Nil(t, p.Rating)
+	assert.Equal(t, level(1), p.Level)
+	assert.NotNil(t, p.Updated)
+
+	assert.Equal(t, io.EOF, d.Decode(&p))
+
+	d = NewCsv(CsvOptions{Delimiter: "|"}).NewDecoder(strings.NewReader("id|extra\n1|x\n"))
+	d.DisallowUnknownFields()
+	assert.Error(t, d.Decode(&p))
+
+	d = NewDecoder(strings.NewReader("id\nabc\n"))
+	err := d.Decode(&p)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "column id on record 2")
+	}
+}
+
+func TestMarshalUnmarshal(t *testing.T) {
+	rating := 3.25
+	people := []person{
+		{ID: 1, Name: "Rob", Email: "rob@example.com", Rating: &rating, Created: time.Date(2019, 8, 19, 17, 2, 9, 0, time.UTC), Level: 2},
+		{ID: 2, Name: "Ken, T", Ignored: "ignored", secret: "secret"},
+	}
+
+	data, err := Marshal(people)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, `id,name,email,rating,created,level,updated
+1,Rob,rob@example.com,3.25,2019-08-19T17:02:09Z,high,
+2,"Ken, T",,,0001-01-01T00:00:00Z,none,
+`, string(data))
+
+	var decoded []*person
+	if assert.NoError(t, Unmarshal(data, &decoded)) && assert.Len(t, decoded, 2) {
+		people[1].Ignored = ""
+		people[1].secret = ""
+		assert.Equal(t, people[0], *decoded[0])
+		assert.Equal(t, people[1], *decoded[1])
+	}
+
+	assert.Error(t, Unmarshal(data, decoded))
+}
+
+type audit struct {
+	CreatedBy string `csv:"created_by"`
+	Note      string `json:"-"`
+}
+
+type Contact struct {
+	Email string `csv:"email"`
+	Name  string `csv:"name"`
+}
+
+type account struct {
+	ID int `csv:"id"`
+	audit
+	*Contact
+	Token string `json:"-"`
+	Name  string `csv:"name"` // hides Contact.Name
+}
+
+func TestMarshalEmbedded(t *testing.T) {
+	accounts := []account{
+		{ID: 1, audit: audit{CreatedBy: "rob", Note: "x"}, Contact: &Contact{Email: "a@b.c", Name: "hidden"}, Token: "t", Name: "A"},
+		{ID: 2, Name: "B"},
+	}
+	data, err := Marshal(accounts)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "id,created_by,email,name\n1,rob,a@b.c,A\n2,,,B\n", string(data))
+
+	var decoded []account
+	if assert.NoError(t, Unmarshal(data, &decoded)) && assert.Len(t, decoded, 2) {
+		assert.Equal(t, account{ID: 1, audit: audit{CreatedBy: "rob"}, Contact: &Contact{Email: "a@b.c"}, Name: "A"}, decoded[0])
+	}
+}
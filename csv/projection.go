@@ -0,0 +1,277 @@
+package csv
+
+import (
+	"strings"
+
+	"github.com/flarco/g"
+)
+
+// Projection selects the columns returned by a CsvReader and filters its
+// records. Cells of unselected columns are skipped without allocation, and
+// records failing Filter are dropped before being materialized.
+type Projection struct {
+	// Columns selects columns by name, in the given order. It requires the
+	// Header option. Names are matched exactly, then case-insensitively.
+	Columns []string
+
+	// Indexes selects columns by 0-based index, in the given order.
+	// Only used when Columns is empty.
+	Indexes []int
+
+	// Filter, if set, is called for each record after the header. Only
+	// selected columns can be read from the RowView.
+	Filter func(row RowView) bool
+
+	// Where, if set, keeps the records for which the filter expression is
+	// true, see g.CompileFilter, in addition to Filter. Its fields must be
+	// selected columns, read as strings which compare as numbers when
+	// numeric, or as null when empty. It requires the Header option.
+	Where string
+
+	names []string      // output column names, once resolved
+	where *g.FilterExpr // compiled Where
+}
+
+// filters returns true if records may be filtered out.
+func (p *Projection) filters() bool {
+	return p.Filter != nil || p.where != nil
+}
+
+// keep returns true if the record of the row passes Filter and Where.
+func (p *Projection) keep(row RowView) (bool, error) {
+	if p.Filter != nil && !p.Filter(row) {
+		return false, nil
+	}
+	if p.where == nil {
+		return true, nil
+	}
+	record := make(map[string]any, len(p.where.Fields()))
+	for _, field := range p.where.Fields() {
+		if value := row.Field(field); value != "" {
+			record[field] = value
+		} else {
+			record[field] = nil
+		}
+	}
+	return p.where.Match(record)
+}
+
+// checkWhere returns an error if a field of Where is not a selected
+// column. Columns selected by index are named once the header is read.
+func (p *Projection) checkWhere() error {
+	if p.where == nil {
+		return nil
+	}
+	names := p.Columns
+	if len(names) == 0 {
+		names = p.names
+	}
+	for _, field := range p.where.Fields() {
+		if !g.In(field, names...) && !containsFold(names, field) {
+			return g.Error("csv: filter field %q is not a projected column", field)
+		}
+	}
+	return nil
+}
+
+// containsFold returns true if names contains name, ignoring case.
+func containsFold(names []string, name string) bool {
+	for _, n := range names {
+		if strings.EqualFold(n, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// width returns the number of output columns.
+func (p *Projection) width() int {
+	if len(p.Columns) > 0 {
+		return len(p.Columns)
+	}
+	return len(p.Indexes)
+}
+
+// SetProjection sets the projection of the records read from now on.
+// Selection by index applies immediately, while selection by name is
+// resolved when the header is read.
+func (cr *CsvReader) SetProjection(p Projection) error {
+	if len(p.Columns) > 0 && len(p.Indexes) > 0 {
+		return g.Error("csv: projection must select columns by name or by index, not both")
+	}
+	if len(p.Columns) > 0 && !cr.csv.options.Header {
+		return g.Error("csv: projection by column name requires the Header option")
+	}
+	if p.Where != "" {
+		if !cr.csv.options.Header {
+			return g.Error("csv: projection filter expression requires the Header option")
+		}
+		where, err := g.CompileFilter(p.Where)
+		if err != nil {
+			return g.Error(err, "csv: invalid projection filter expression")
+		}
+		p.where = where
+	}
+
+	cr.projection = &p
+	cr.slots = nil
+	if len(p.Indexes) > 0 {
+		if err := cr.setSlots(p.Indexes); err != nil {
+			return err
+		}
+	} else if len(p.Columns) > 0 && cr.header != nil {
+		if err := cr.resolveColumns(); err != nil {
+			return err
+		}
+	}
+	if len(p.Indexes) > 0 && cr.header == nil {
+		return nil // checked with the names of the header
+	}
+	return p.checkWhere()
+}
+
+// Header returns the header record, when the Header option is set and the
+// first record was read.
+func (cr *CsvReader) Header() []string {
+	return cr.header
+}
+
+// readHeader reads the first record as the header. The header is never
+// filtered, but is projected as the following records.
+func (cr *CsvReader) readHeader() (row []string, err error) {
+	slots := cr.slots
+	cr.slots = nil
+	row, err = cr.readRecord()
+	if err == nil && row == nil {
+		row = cr.Row() // not materialized when a Filter is set
+	}
+	cr.slots = slots
+	if err != nil {
+		return row, err
+	}
+	cr.header = row
+
+	if cr.projection == nil {
+		return row, nil
+	}
+	if len(cr.projection.Columns) > 0 {
+		if err = cr.resolveColumns(); err != nil {
+			return nil, err
+		}
+	} else if len(cr.projection.Indexes) > 0 {
+		if err = cr.setSlots(cr.projection.Indexes); err != nil {
+			return nil, err // names the columns
+		}
+	}
+	if err = cr.projection.checkWhere(); err != nil {
+		return nil, err
+	}
+
+	projected := make([]string, cr.projection.width())
+	for i, slot := range cr.slots {
+		if slot >= 0 && i < len(row) {
+			projected[slot] = row[i]
+		}
+	}
+	return projected, nil
+}
+
+// resolveColumns maps the projection column names to header indexes.
+func (cr *CsvReader) resolveColumns() error {
+	indexes := make([]int, len(cr.projection.Columns))
+	for i, name := range cr.projection.Columns {
+		indexes[i] = -1
+		for j, h := range cr.header {
+			if h == name {
+				indexes[i] = j
+				break
+			}
+		}
+		if indexes[i] >= 0 {
+			continue
+		}
+		for j, h := range cr.header {
+			if strings.EqualFold(h, name) {
+				indexes[i] = j
+				break
+			}
+		}
+		if indexes[i] < 0 {
+			return g.Error("csv: projected column %q not found in header", name)
+		}
+	}
+	return cr.setSlots(indexes)
+}
+
+// setSlots builds the output position of each source column.
+func (cr *CsvReader) setSlots(indexes []int) error {
+	last := -1
+	for _, index := range indexes {
+		if index < 0 {
+			return g.Error("csv: invalid projected column index %d", index)
+		}
+		if index > last {
+			last = index
+		}
+	}
+
+	cr.slots = make([]int, last+1)
+	for i := range cr.slots {
+		cr.slots[i] = -1
+	}
+	cr.projection.names = make([]string, len(indexes))
+	for slot, index := range indexes {
+		if cr.slots[index] >= 0 {
+			return g.Error("csv: column %d is projected more than once", index)
+		}
+		cr.slots[index] = slot
+		if index < len(cr.header) {
+			cr.projection.names[slot] = cr.header[index]
+		}
+	}
+	return nil
+}
+
+// RowView gives access to the selected columns of the current record,
+// materializing only the values which are read.
+type RowView struct {
+	cr *CsvReader
+}
+
+// Len returns the number of selected columns.
+func (rv RowView) Len() int {
+	return rv.cr.projection.width()
+}
+
+// Get returns the value of the selected column at position i of the
+// projection. Missing values are empty.
+func (rv RowView) Get(i int) string {
+	lineBuffer := rv.cr.lineBuffer.String()
+	for k, slot := range rv.cr.rowSlots {
+		if slot != i {
+			continue
+		}
+		var value strings.Builder
+		for _, token := range rv.cr.row[k] {
+			value.WriteString(lineBuffer[token.Start:token.End])
+		}
+		return value.String()
+	}
+	return ""
+}
+
+// Field returns the value of the selected column with the given name,
+// requiring the Header option.
+func (rv RowView) Field(name string) string {
+	for i, n := range rv.cr.projection.names {
+		if n == name {
+			return rv.Get(i)
+		}
+	}
+	for i, n := range rv.cr.projection.names {
+		if strings.EqualFold(n, name) {
+			return rv.Get(i)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,108 @@
+package csv
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCsvReaderProjection(t *testing.T) {
+	in := `id,name,city,age
+1,"Rob, P",NYC,30
+2,Ken,"Los
+Angeles",25
+3,Jane,,41
+`
+	// by index, re-ordered
+	r := NewCsv().NewReader(strings.NewReader(in))
+	assert.NoError(t, r.SetProjection(Projection{Indexes: []int{3, 1}}))
+	rows, err := r.ReadAll()
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{{"age", "name"}, {"30", "Rob, P"}, {"25", "Ken"}, {"41", "Jane"}}, rows)
+
+	// by name with header, filtered
+	r = NewCsv(CsvOptions{Header: true}).NewReader(strings.NewReader(in))
+	err = r.SetProjection(Projection{
+		Columns: []string{"City", "id"},
+		Filter: func(row RowView) bool {
+			return row.Len() == 2 && row.Field("city") != "" && row.Get(1) != "1"
+		},
+	})
+	assert.NoError(t, err)
+	rows, err = r.ReadAll()
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{{"city", "id"}, {"Los\nAngeles", "2"}}, rows)
+	assert.Equal(t, []string{"id", "name", "city", "age"}, r.Header())
+
+	// filter without projection of columns
+	r = NewCsv(CsvOptions{Header: true}).NewReader(strings.NewReader(in))
+	r.SetProjection(Projection{Columns: []string{"id"}, Filter: func(row RowView) bool { return row.Get(0) == "3" }})
+	rows, err = r.ReadAll()
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{{"id"}, {"3"}}, rows)
+
+	// filter expression
+	r = NewCsv(CsvOptions{Header: true}).NewReader(strings.NewReader(in))
+	err = r.SetProjection(Projection{Columns: []string{"id", "name", "age"}, Where: `age >= 30 and name like '%e'`})
+	assert.NoError(t, err)
+	rows, err = r.ReadAll()
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{{"id", "name", "age"}, {"3", "Jane", "41"}}, rows)
+	r = NewCsv(CsvOptions{Header: true}).NewReader(strings.NewReader(in))
+	assert.NoError(t, r.SetProjection(Projection{Indexes: []int{0, 3}, Where: `AGE < 30`}))
+	rows, err = r.ReadAll()
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{{"id", "age"}, {"2", "25"}}, rows)
+
+	// empty cells are null
+	for where, expected := range map[string][][]string{
+		`age < 30`:    {{"id", "age"}, {"1", "25"}},
+		`age is null`: {{"id", "age"}, {"2", ""}},
+	} {
+		r = NewCsv(CsvOptions{Header: true}).NewReader(strings.NewReader("id,age\n1,25\n2,\n"))
+		assert.NoError(t, r.SetProjection(Projection{Columns: []string{"id", "age"}, Where: where}))
+		rows, err = r.ReadAll()
+		assert.NoError(t, err)
+		assert.Equal(t, expected, rows, where)
+	}
+
+	r = NewCsv(CsvOptions{Header: true}).NewReader(strings.NewReader(in))
+	assert.Error(t, r.SetProjection(Projection{Columns: []string{"id"}, Where: `age >=`}))
+	assert.ErrorContains(t, r.SetProjection(Projection{Columns: []string{"id"}, Where: `age >= 30`}), `filter field "age" is not a projected column`)
+	assert.Error(t, r.SetProjection(Projection{Where: `age >= 30`}))
+	assert.NoError(t, r.SetProjection(Projection{Indexes: []int{0}, Where: `age >= 30`}))
+	_, err = r.Read()
+	assert.ErrorContains(t, err, `filter field "age" is not a projected column`)
+	r = NewCsv(CsvOptions{Header: true}).NewReader(strings.NewReader(in))
+	assert.NoError(t, r.SetProjection(Projection{Columns: []string{"id", "age"}, Where: `age / (id - 2) > 1`}))
+	_, err = r.ReadAll()
+	assert.ErrorContains(t, err, "division by zero")
+
+	// errors
+	r = NewCsv().NewReader(strings.NewReader(in))
+	assert.Error(t, r.SetProjection(Projection{Columns: []string{"id"}}))
+	assert.Error(t, r.SetProjection(Projection{Indexes: []int{1, 1}}))
+	r = NewCsv(CsvOptions{Header: true}).NewReader(strings.NewReader(in))
+	r.SetProjection(Projection{Columns: []string{"missing"}})
+	_, err = r.Read()
+	assert.Error(t, err)
+}
+
+func BenchmarkCsvReaderProjection(b *testing.B) {
+	var sb strings.Builder
+	for i := 0; i < 200; i++ {
+		sb.WriteString("field,")
+	}
+	sb.WriteString("field\n")
+	data := strings.Repeat(sb.String(), 100)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r := NewCsv().NewReader(strings.NewReader(data))
+		r.SetProjection(Projection{Indexes: []int{0, 100, 200}})
+		if _, err := r.ReadAll(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -177,11 +177,13 @@ type Reader struct {
 }
 
 // NewReader returns a new Reader that reads from r.
+// Input compressed with gzip, zstd or bzip2 is detected on the first read
+// and decompressed transparently.
 func NewReader(r io.Reader) *Reader {
 	return &Reader{
 		Comma: ",",
 		// r:     bufio.NewReader(r),
-		r: bufio.NewReaderSize(r, 128*1024),
+		r: bufio.NewReaderSize(newDecompressReader(r), 128*1024),
 	}
 }
 
@@ -6,12 +6,37 @@ package csv
 
 import (
 	"bufio"
+	"fmt"
 	"io"
+	"math/big"
+	"reflect"
+	"strconv"
 	"strings"
+	"time"
 	"unicode"
 	"unicode/utf8"
+
+	"github.com/spf13/cast"
 )
 
+// QuoteMode controls when a Writer encloses fields in quotes.
+type QuoteMode int
+
+const (
+	// QuoteMinimal quotes only fields which need it (see fieldNeedsQuotes).
+	QuoteMinimal QuoteMode = iota
+	// QuoteAll quotes every non-null field.
+	QuoteAll
+	// QuoteNonNumeric quotes every non-null field which is not a number.
+	QuoteNonNumeric
+	// QuoteNone never quotes. Special characters are prefixed with Escape
+	// when it is set, otherwise they are written verbatim.
+	QuoteNone
+)
+
+// Formatter converts a non-nil value into its field text.
+type Formatter func(val any) string
+
 // A Writer writes records using CSV encoding.
 //
 // As returned by NewWriter, a Writer writes records terminated by a
@@ -22,22 +47,53 @@ import (
 //
 // If UseCRLF is true, the Writer ends each output line with \r\n instead of \n.
 //
+// Quote is the quote character and Escape the character used to escape an
+// embedded quote. When Escape is 0 or equal to Quote, embedded quotes are
+// doubled, as in RFC 4180. Otherwise (e.g. '\\') both embedded quotes and
+// escape characters are prefixed with Escape.
+//
+// Null is written for nil values passed to WriteRow. If DistinctNull is true,
+// non-nil fields whose text equals Null are quoted so that a reader can tell
+// them apart (e.g. Postgres COPY, where an unquoted empty field is NULL).
+//
 // The writes of individual records are buffered.
 // After all data has been written, the client should call the
 // Flush method to guarantee all data has been forwarded to
 // the underlying io.Writer.  Any errors that occurred should
 // be checked by calling the Error method.
 type Writer struct {
-	Comma   string // Field delimiter (set to ',' by NewWriter)
-	UseCRLF bool   // True to use \r\n as the line terminator
-	w       *bufio.Writer
-	bytes   int
+	Comma        string    // Field delimiter (set to ',' by NewWriter)
+	UseCRLF      bool      // True to use \r\n as the line terminator
+	Quote        byte      // Quote character (set to '"' by NewWriter)
+	Escape       byte      // Escape character for embedded quotes (0 to double them)
+	QuoteMode    QuoteMode // When to quote fields
+	Null         string    // Text written for nil values in WriteRow
+	DistinctNull bool      // True to quote non-nil fields equal to Null
+
+	// Formatters holds per-column formatters used by WriteRow, indexed by
+	// column position. Nil entries (or missing ones) use formatValue.
+	Formatters []Formatter
+
+	w     *bufio.Writer
+	bytes int
+	parts *partWriter // set for compressed writers
 }
 
+// fieldKind describes the origin of a field value, as far as quoting goes.
+type fieldKind int
+
+const (
+	kindText   fieldKind = iota // untyped text, as passed to Write
+	kindString                  // typed non-numeric value, as passed to WriteRow
+	kindNumber
+	kindNull
+)
+
 // NewWriter returns a new Writer that writes to w.
 func NewWriter(w io.Writer) *Writer {
 	return &Writer{
 		Comma: ",",
+		Quote: '"',
 		w:     bufio.NewWriterSize(w, 40960),
 		bytes: 0,
 	}
@@ -47,6 +103,7 @@ func NewWriter(w io.Writer) *Writer {
 func NewWriterSize(w io.Writer, size int) *Writer {
 	return &Writer{
 		Comma: ",",
+		Quote: '"',
 		w:     bufio.NewWriterSize(w, size),
 		bytes: 0,
 	}
@@ -58,6 +115,56 @@ func NewWriterSize(w io.Writer, size int) *Writer {
 // that the record is written to the underlying io.Writer.
 // it returns the total number of bytes written.
 func (w *Writer) Write(record []string) (tbw int, err error) {
+	return w.writeRecord(record, nil)
+}
+
+// WriteRow writes a single record of arbitrary values, such as a data.Row.
+// Nil values and nil pointers are written as Null. For QuoteNonNumeric,
+// quoting follows the Go type of the value: numbers are never quoted and
+// other values always are, even strings which look numeric such as "007".
+// Values are converted with the column Formatter when set, or formatValue
+// otherwise.
+// it returns the total number of bytes written.
+func (w *Writer) WriteRow(row []any) (tbw int, err error) {
+	record := make([]string, len(row))
+	kinds := make([]fieldKind, len(row))
+	for i, val := range row {
+		switch {
+		case isNilValue(val):
+			record[i] = w.Null
+			kinds[i] = kindNull
+			continue
+		case isNumberValue(val):
+			kinds[i] = kindNumber
+		default:
+			kinds[i] = kindString
+		}
+		if i < len(w.Formatters) && w.Formatters[i] != nil {
+			record[i] = w.Formatters[i](val)
+		} else {
+			record[i] = formatValue(val)
+		}
+	}
+	return w.writeRecord(record, kinds)
+}
+
+func (w *Writer) writeRecord(record []string, kinds []fieldKind) (tbw int, err error) {
+	if w.parts != nil {
+		if tbw, err = w.parts.prepare(w); err != nil {
+			return tbw, err
+		}
+	}
+
+	bw, err := w.encodeRecord(record, kinds)
+	tbw = tbw + bw
+	if err == nil && w.parts != nil {
+		w.parts.recorded(w)
+	}
+	return tbw, err
+}
+
+// encodeRecord writes a single record to the buffer.
+func (w *Writer) encodeRecord(record []string, kinds []fieldKind) (tbw int, err error) {
 	defer func() { w.bytes = w.bytes + tbw }()
 
 	if !validDelim(w.Comma) {
@@ -73,67 +180,28 @@ func (w *Writer) Write(record []string) (tbw int, err error) {
 			}
 		}
 
-		// If we don't have to have a quoted field then just
-		// write out the field and continue to the next field.
-		if !w.fieldNeedsQuotes(field) {
-			bw, err := w.w.WriteString(field)
-			tbw = tbw + bw
-			if err != nil {
-				return tbw, err
-			}
-			continue
-		}
-
-		if err := w.w.WriteByte('"'); err != nil {
-			return tbw, err
+		kind := kindText
+		if kinds != nil {
+			kind = kinds[n]
 		}
-		tbw++
-		for len(field) > 0 {
-			// Search for special characters.
-			i := strings.IndexAny(field, "\"\r\n")
-			if i < 0 {
-				i = len(field)
-			}
 
-			// Copy verbatim everything before the special character.
-			bw, err := w.w.WriteString(field[:i])
-			tbw = tbw + bw
-			if err != nil {
-				return tbw, err
-			}
-			field = field[i:]
-
-			// Encode the special character.
-			if len(field) > 0 {
-				var err error
-				var bw int
-				switch field[0] {
-				case '"':
-					bw, err = w.w.WriteString(`""`)
-				case '\r':
-					if !w.UseCRLF {
-						err = w.w.WriteByte('\r')
-						bw++
-					}
-				case '\n':
-					if w.UseCRLF {
-						bw, err = w.w.WriteString("\r\n")
-					} else {
-						err = w.w.WriteByte('\n')
-						bw++
-					}
-				}
-				field = field[1:]
-				tbw = tbw + bw
-				if err != nil {
-					return tbw, err
-				}
-			}
+		var bw int
+		switch {
+		case kind == kindNull:
+			bw, err = w.w.WriteString(field)
+		case w.QuoteMode == QuoteNone:
+			bw, err = w.writeUnquoted(field)
+		case w.shouldQuote(field, kind):
+			bw, err = w.writeQuoted(field)
+		default:
+			// If we don't have to have a quoted field then just
+			// write out the field and continue to the next field.
+			bw, err = w.w.WriteString(field)
 		}
-		if err := w.w.WriteByte('"'); err != nil {
+		tbw = tbw + bw
+		if err != nil {
 			return tbw, err
 		}
-		tbw++
 	}
 	var bw int
 	if w.UseCRLF {
@@ -146,6 +214,143 @@ func (w *Writer) Write(record []string) (tbw int, err error) {
 	return tbw, err
 }
 
+// shouldQuote reports whether a non-null field must be quoted under QuoteMode.
+func (w *Writer) shouldQuote(field string, kind fieldKind) bool {
+	if w.DistinctNull && field == w.Null {
+		return true
+	}
+	switch w.QuoteMode {
+	case QuoteAll:
+		return true
+	case QuoteNonNumeric:
+		if kind == kindNumber || (kind == kindText && isNumericString(field)) {
+			return w.fieldNeedsQuotes(field)
+		}
+		return true
+	}
+	return w.fieldNeedsQuotes(field)
+}
+
+// writeQuoted writes field enclosed in quotes, escaping embedded quotes.
+func (w *Writer) writeQuoted(field string) (tbw int, err error) {
+	quote, escape := w.quoteChars()
+	if err = w.w.WriteByte(quote); err != nil {
+		return tbw, err
+	}
+	tbw++
+
+	specials := string([]byte{quote, escape}) + "\r\n"
+	for len(field) > 0 {
+		// Search for special characters.
+		i := strings.IndexAny(field, specials)
+		if i < 0 {
+			i = len(field)
+		}
+
+		// Copy verbatim everything before the special character.
+		bw, err := w.w.WriteString(field[:i])
+		tbw = tbw + bw
+		if err != nil {
+			return tbw, err
+		}
+		field = field[i:]
+
+		// Encode the special character.
+		if len(field) > 0 {
+			var err error
+			var bw int
+			switch field[0] {
+			case quote, escape:
+				bw, err = w.w.Write([]byte{escape, field[0]})
+			case '\r':
+				if !w.UseCRLF {
+					err = w.w.WriteByte('\r')
+					bw++
+				}
+			case '\n':
+				if w.UseCRLF {
+					bw, err = w.w.WriteString("\r\n")
+				} else {
+					err = w.w.WriteByte('\n')
+					bw++
+				}
+			}
+			field = field[1:]
+			tbw = tbw + bw
+			if err != nil {
+				return tbw, err
+			}
+		}
+	}
+	if err = w.w.WriteByte(quote); err != nil {
+		return tbw, err
+	}
+	tbw++
+	return tbw, nil
+}
+
+// writeUnquoted writes field without quotes. If Escape is set, the delimiter,
+// newlines and the escape character itself are prefixed with it (as expected
+// by Hive or MySQL LOAD DATA), otherwise the field is written verbatim.
+func (w *Writer) writeUnquoted(field string) (tbw int, err error) {
+	if w.Escape == 0 {
+		return w.w.WriteString(field)
+	}
+
+	for len(field) > 0 {
+		i := w.indexUnquotedSpecial(field)
+		if i < 0 {
+			i = len(field)
+		}
+
+		bw, err := w.w.WriteString(field[:i])
+		tbw = tbw + bw
+		if err != nil {
+			return tbw, err
+		}
+		field = field[i:]
+
+		if len(field) > 0 {
+			size := 1
+			if strings.HasPrefix(field, w.Comma) {
+				size = len(w.Comma)
+			}
+			if err = w.w.WriteByte(w.Escape); err != nil {
+				return tbw, err
+			}
+			bw, err = w.w.WriteString(field[:size])
+			tbw = tbw + bw + 1
+			if err != nil {
+				return tbw, err
+			}
+			field = field[size:]
+		}
+	}
+	return tbw, nil
+}
+
+// indexUnquotedSpecial returns the index of the first character of field
+// which must be escaped when writing unquoted, or -1.
+func (w *Writer) indexUnquotedSpecial(field string) int {
+	i := strings.IndexAny(field, string([]byte{w.Escape})+"\r\n")
+	if j := strings.Index(field, w.Comma); j >= 0 && (i < 0 || j < i) {
+		i = j
+	}
+	return i
+}
+
+// quoteChars returns the effective quote and escape characters.
+func (w *Writer) quoteChars() (quote, escape byte) {
+	quote, escape = w.Quote, w.Escape
+	if quote == 0 {
+		quote = '"'
+	}
+	if escape == 0 {
+		escape = quote
+	}
+	return
+}
+
 // Bytes returns the number of bytes written
 func (w *Writer) Bytes() int {
 	return w.bytes
@@ -196,10 +401,131 @@ func (w *Writer) fieldNeedsQuotes(field string) bool {
 		return true
 	}
 
-	if strings.Contains(field, w.Comma) || strings.ContainsAny(field, "\"\r\n") {
+	quote, escape := w.quoteChars()
+	if strings.Contains(field, w.Comma) || strings.ContainsAny(field, string([]byte{quote, escape})+"\r\n") {
 		return true
 	}
 
 	r1, _ := utf8.DecodeRuneInString(field)
 	return unicode.IsSpace(r1)
 }
+
+// isNumericString reports whether field is a plain decimal number, such as
+// -12, 3.50 or 1e-7.
+func isNumericString(field string) bool {
+	i := 0
+	if i < len(field) && (field[i] == '+' || field[i] == '-') {
+		i++
+	}
+	digits := 0
+	for ; i < len(field) && field[i] >= '0' && field[i] <= '9'; i++ {
+		digits++
+	}
+	if i < len(field) && field[i] == '.' {
+		for i++; i < len(field) && field[i] >= '0' && field[i] <= '9'; i++ {
+			digits++
+		}
+	}
+	if digits == 0 {
+		return false
+	}
+	if i < len(field) && (field[i] == 'e' || field[i] == 'E') {
+		i++
+		if i < len(field) && (field[i] == '+' || field[i] == '-') {
+			i++
+		}
+		start := i
+		for ; i < len(field) && field[i] >= '0' && field[i] <= '9'; i++ {
+		}
+		if i == start {
+			return false
+		}
+	}
+	return i == len(field)
+}
+
+// isNilValue reports whether val is nil or a typed nil pointer, such as a
+// nil *time.Time.
+func isNilValue(val any) bool {
+	if val == nil {
+		return true
+	}
+	v := reflect.ValueOf(val)
+	return v.Kind() == reflect.Pointer && v.IsNil()
+}
+
+// isNumberValue reports whether val holds a Go numeric type, or a pointer
+// to one.
+func isNumberValue(val any) bool {
+	switch val.(type) {
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64,
+		float32, float64, *big.Int, *big.Float:
+		return true
+	}
+	switch reflect.Indirect(reflect.ValueOf(val)).Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// formatValue is the default conversion of a non-nil value into field text.
+// Times use time.RFC3339Nano and floats the shortest exact representation
+// without exponent.
+func formatValue(val any) string {
+	switch v := val.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case *time.Time:
+		if v == nil {
+			return ""
+		}
+		return v.Format(time.RFC3339Nano)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32)
+	}
+	str, err := cast.ToStringE(val)
+	if err != nil {
+		return fmt.Sprint(val)
+	}
+	return str
+}
+
+// TimeFormatter returns a Formatter writing time values with layout.
+// Other values fall back to the default formatting.
+func TimeFormatter(layout string) Formatter {
+	return func(val any) string {
+		switch v := val.(type) {
+		case time.Time:
+			return v.Format(layout)
+		case *time.Time:
+			if v != nil {
+				return v.Format(layout)
+			}
+		}
+		return formatValue(val)
+	}
+}
+
+// FloatFormatter returns a Formatter writing float values with
+// strconv.FormatFloat, using format and precision prec.
+// Other values fall back to the default formatting.
+func FloatFormatter(format byte, prec int) Formatter {
+	return func(val any) string {
+		switch v := val.(type) {
+		case float64:
+			return strconv.FormatFloat(v, format, prec, 64)
+		case float32:
+			return strconv.FormatFloat(float64(v), format, prec, 32)
+		}
+		return formatValue(val)
+	}
+}
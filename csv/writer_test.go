@@ -8,6 +8,7 @@ import (
 	"bytes"
 	"errors"
 	"testing"
+	"time"
 )
 
 var writeTests = []struct {
@@ -67,6 +68,81 @@ func TestWrite(t *testing.T) {
 	}
 }
 
+var writeOptionTests = []struct {
+	Name   string
+	Writer Writer
+	Input  [][]string
+	Output string
+}{
+	{Name: "quote-all", Writer: Writer{QuoteMode: QuoteAll}, Input: [][]string{{"a", "1", ""}}, Output: `"a","1",""` + "\n"},
+	{Name: "quote-non-numeric", Writer: Writer{QuoteMode: QuoteNonNumeric}, Input: [][]string{{"a", "1", "-2.5e3", "1.2.3"}}, Output: `"a",1,-2.5e3,"1.2.3"` + "\n"},
+	{Name: "quote-none", Writer: Writer{QuoteMode: QuoteNone}, Input: [][]string{{`a"b`, "c,d"}}, Output: `a"b,c,d` + "\n"},
+	{Name: "quote-none-escape", Writer: Writer{QuoteMode: QuoteNone, Escape: '\\'}, Input: [][]string{{`a\b`, "c,d", "e\nf"}}, Output: `a\\b,c\,d,e\` + "\nf\n"},
+	{Name: "custom-quote", Writer: Writer{Quote: '\''}, Input: [][]string{{"it's", `say "hi"`, "a,b"}}, Output: `'it''s',say "hi",'a,b'` + "\n"},
+	{Name: "backslash-escape", Writer: Writer{Escape: '\\'}, Input: [][]string{{`a"b`, `c\d`, "e"}}, Output: `"a\"b","c\\d",e` + "\n"},
+	{Name: "distinct-null", Writer: Writer{DistinctNull: true}, Input: [][]string{{"", "a"}}, Output: `"",a` + "\n"},
+	{Name: "distinct-null-sentinel", Writer: Writer{Null: `\N`, DistinctNull: true}, Input: [][]string{{`\N`, ""}}, Output: `"\N",` + "\n"},
+}
+
+func TestWriteOptions(t *testing.T) {
+	for _, tt := range writeOptionTests {
+		b := &bytes.Buffer{}
+		f := NewWriter(b)
+		f.QuoteMode = tt.Writer.QuoteMode
+		f.Escape = tt.Writer.Escape
+		f.Null = tt.Writer.Null
+		f.DistinctNull = tt.Writer.DistinctNull
+		if tt.Writer.Quote != 0 {
+			f.Quote = tt.Writer.Quote
+		}
+		if err := f.WriteAll(tt.Input); err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.Name, err)
+		}
+		if out := b.String(); out != tt.Output {
+			t.Errorf("%s: out=%q want %q", tt.Name, out, tt.Output)
+		}
+		if f.Bytes() != len(tt.Output) {
+			t.Errorf("%s: bytes=%d want %d", tt.Name, f.Bytes(), len(tt.Output))
+		}
+	}
+}
+
+func TestWriteRow(t *testing.T) {
+	ts := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+	qty := 4
+	rows := [][]any{
+		{"a", 1, 2.50, ts, nil, ""},
+		{"b", int64(-3), float32(0.1), &ts, true, nil},
+		{"007", &qty, "1e3", (*time.Time)(nil), false, (*int)(nil)},
+	}
+
+	b := &bytes.Buffer{}
+	w := NewWriter(b)
+	w.Null = `\N`
+	w.QuoteMode = QuoteNonNumeric
+	for _, row := range rows {
+		if _, err := w.WriteRow(row); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	w.Flush()
+	want := `"a",1,2.5,"2021-03-04T05:06:07Z",\N,""` + "\n" +
+		`"b",-3,0.1,"2021-03-04T05:06:07Z","true",\N` + "\n" +
+		`"007",4,"1e3",\N,"false",\N` + "\n"
+	if out := b.String(); out != want {
+		t.Errorf("out=%q want %q", out, want)
+	}
+
+	b.Reset()
+	w = NewWriter(b)
+	w.Formatters = []Formatter{nil, FloatFormatter('f', 2), TimeFormatter("2006-01-02")}
+	w.WriteRow([]any{"x", 3.14159, ts})
+	w.Flush()
+	if out, want := b.String(), "x,3.14,2021-03-04\n"; out != want {
+		t.Errorf("out=%q want %q", out, want)
+	}
+}
+
 type errorWriter struct{}
 
 func (e errorWriter) Write(b []byte) (int, error) {
@@ -0,0 +1,118 @@
+package data
+
+import (
+	"io"
+	"sort"
+
+	"github.com/flarco/g"
+	"github.com/flarco/g/csv"
+)
+
+// ReadCSV reads a dataset from CSV, the first record being the header.
+// Values are kept as strings.
+func ReadCSV(r io.Reader) (Dataset, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return NewDataset(), nil
+	} else if err != nil {
+		return Dataset{}, g.Error(err, "could not read CSV header")
+	}
+
+	d := NewDataset(header...)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return d, g.Error(err, "could not read CSV record %d", len(d.Rows)+1)
+		}
+
+		row := make(Row, len(record))
+		for i, val := range record {
+			row[i] = val
+		}
+		d.Rows = append(d.Rows, row)
+	}
+	return d, nil
+}
+
+// WriteCSV writes the dataset as CSV, header first. Nil values are
+// written empty.
+func (d *Dataset) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	if _, err := writer.Write(d.Fields); err != nil {
+		return g.Error(err, "could not write CSV header")
+	}
+	for i, row := range d.Rows {
+		if _, err := writer.WriteRow(row); err != nil {
+			return g.Error(err, "could not write CSV record %d", i+1)
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// Records returns the rows as maps of field name to value
+func (d *Dataset) Records() []map[string]interface{} {
+	records := make([]map[string]interface{}, len(d.Rows))
+	for i, row := range d.Rows {
+		rec := make(map[string]interface{}, len(d.Fields))
+		for col, field := range d.Fields {
+			rec[field] = cellValue(row, col)
+		}
+		records[i] = rec
+	}
+	return records
+}
+
+// NewDatasetFromRecords creates a dataset from records. Fields are the
+// union of the record keys, sorted by name.
+func NewDatasetFromRecords(records []map[string]interface{}) Dataset {
+	fieldSet := map[string]bool{}
+	for _, rec := range records {
+		for key := range rec {
+			fieldSet[key] = true
+		}
+	}
+	fields := make([]string, 0, len(fieldSet))
+	for key := range fieldSet {
+		fields = append(fields, key)
+	}
+	sort.Strings(fields)
+
+	d := NewDataset(fields...)
+	for _, rec := range records {
+		row := make(Row, len(fields))
+		for col, field := range fields {
+			row[col] = rec[field]
+		}
+		d.Rows = append(d.Rows, row)
+	}
+	return d
+}
+
+// MarshalRecords returns the JSON array of records of the dataset
+func (d *Dataset) MarshalRecords() ([]byte, error) {
+	return g.JSONMarshal(d.Records())
+}
+
+// UnmarshalRecords creates a dataset from a JSON array of records
+func UnmarshalRecords(data []byte) (Dataset, error) {
+	records := []map[string]interface{}{}
+	if err := g.JSONUnmarshal(data, &records); err != nil {
+		return Dataset{}, g.Error(err, "could not unmarshal JSON records")
+	}
+	return NewDatasetFromRecords(records), nil
+}
+
+// Pretty returns the dataset rendered as a table
+func (d *Dataset) Pretty() string {
+	rows := make([][]any, len(d.Rows))
+	for i, row := range d.Rows {
+		rows[i] = append([]any{}, row...) // PrettyTable replaces nil values
+	}
+	return g.PrettyTable(d.Fields, rows)
+}
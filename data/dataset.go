@@ -0,0 +1,642 @@
+package data
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/flarco/g"
+	"github.com/spf13/cast"
+)
+
+// ColumnIndex returns the index of the column named key (case-insensitive)
+func (d *Dataset) ColumnIndex(key string) (int, bool) {
+	col, ok := d.FieldMap[strings.ToLower(key)]
+	return col, ok
+}
+
+// Append adds a row to the dataset
+func (d *Dataset) Append(row ...interface{}) {
+	d.Rows = append(d.Rows, row)
+}
+
+// Value returns the value of column key for row i, nil if the column does not exist
+func (d *Dataset) Value(i int, key string) interface{} {
+	col, ok := d.ColumnIndex(key)
+	if !ok || col >= len(d.Rows[i]) {
+		return nil
+	}
+	return d.Rows[i][col]
+}
+
+// Int returns the value of column key for row i as an int64
+func (d *Dataset) Int(i int, key string) int64 {
+	return cast.ToInt64(d.Value(i, key))
+}
+
+// Float returns the value of column key for row i as a float64
+func (d *Dataset) Float(i int, key string) float64 {
+	return cast.ToFloat64(d.Value(i, key))
+}
+
+// Bool returns the value of column key for row i as a bool
+func (d *Dataset) Bool(i int, key string) bool {
+	return cast.ToBool(d.Value(i, key))
+}
+
+// Time returns the value of column key for row i as a time.Time
+func (d *Dataset) Time(i int, key string) time.Time {
+	return cast.ToTime(d.Value(i, key))
+}
+
+// Column returns all the values of column key
+func (d *Dataset) Column(key string) []interface{} {
+	values := make([]interface{}, len(d.Rows))
+	for i := range d.Rows {
+		values[i] = d.Value(i, key)
+	}
+	return values
+}
+
+// SortKey is a column to sort by
+type SortKey struct {
+	Column string
+	Desc   bool
+}
+
+// Sort sorts the rows in place by the keys, in order. The sort is stable,
+// so rows which are equal on all keys keep their relative order.
+// Nil values sort first.
+func (d *Dataset) Sort(keys ...SortKey) error {
+	cols := make([]int, len(keys))
+	for i, key := range keys {
+		col, ok := d.ColumnIndex(key.Column)
+		if !ok {
+			return g.Error("column %s not found", key.Column)
+		}
+		cols[i] = col
+	}
+
+	sort.SliceStable(d.Rows, func(a, b int) bool {
+		for i, key := range keys {
+			c := CompareValues(cellValue(d.Rows[a], cols[i]), cellValue(d.Rows[b], cols[i]))
+			if c == 0 {
+				continue
+			}
+			if key.Desc {
+				return c > 0
+			}
+			return c < 0
+		}
+		return false
+	})
+	return nil
+}
+
+// Filter returns a new dataset with the rows for which keep returns true.
+// The rows are shared with the original dataset.
+func (d *Dataset) Filter(keep func(i int) bool) Dataset {
+	nd := NewDataset(d.Fields...)
+	for i, row := range d.Rows {
+		if keep(i) {
+			nd.Rows = append(nd.Rows, row)
+		}
+	}
+	return nd
+}
+
+// FilterExpr returns a new dataset with the rows for which the filter
+// expression is true, see g.CompileFilter. The rows are shared with the
+// original dataset.
+func (d *Dataset) FilterExpr(expr string) (Dataset, error) {
+	f, err := g.CompileFilter(expr)
+	if err != nil {
+		return Dataset{}, err
+	}
+
+	record := map[string]interface{}{}
+	nd := NewDataset(d.Fields...)
+	for i, row := range d.Rows {
+		for _, field := range f.Fields() {
+			record[field] = d.Value(i, field)
+		}
+		match, err := f.Match(record)
+		if err != nil {
+			return Dataset{}, g.Error(err, "could not filter row %d", i)
+		}
+		if match {
+			nd.Rows = append(nd.Rows, row)
+		}
+	}
+	return nd, nil
+}
+
+// AggFunc is an aggregation function
+type AggFunc string
+
+const (
+	AggSum   AggFunc = "sum"
+	AggCount AggFunc = "count"
+	AggMin   AggFunc = "min"
+	AggMax   AggFunc = "max"
+	AggAvg   AggFunc = "avg"
+)
+
+// Aggregation is an aggregation of a column, named As in the output
+// (defaults to func_column). An empty Column with AggCount counts rows.
+type Aggregation struct {
+	Func   AggFunc
+	Column string
+	As     string
+}
+
+func (a Aggregation) name() string {
+	if a.As != "" {
+		return a.As
+	}
+	if a.Column == "" {
+		return string(a.Func)
+	}
+	return string(a.Func) + "_" + a.Column
+}
+
+// aggState accumulates an aggregation for a group. Sums are exact: an
+// int64 while the values are integers, a big.Int once it overflows, and a
+// float64 from the first value which is not an integer.
+type aggState struct {
+	count int64
+	isum  int64
+	bsum  *big.Int // the integer sum, once it overflows isum
+	fsum  float64
+	float bool        // fsum holds the sum
+	value interface{} // min or max
+}
+
+// add adds val to the sum.
+func (s *aggState) add(val interface{}) error {
+	if !s.float {
+		if i, ok := int64Value(val); ok && s.bsum == nil {
+			if sum := s.isum + i; (sum >= s.isum) == (i >= 0) {
+				s.isum = sum
+				return nil
+			}
+		}
+		if i := bigInt(val); i != nil {
+			s.bsum = new(big.Int).Add(s.intSum(), i)
+			return nil
+		}
+		s.fsum, _ = new(big.Float).SetInt(s.intSum()).Float64()
+		s.float = true
+	}
+
+	if b := bigValue(val); b != nil && isNumber(val) {
+		f, _ := b.Float64()
+		s.fsum += f
+		return nil
+	}
+	f, err := cast.ToFloat64E(val)
+	if err != nil {
+		return err
+	}
+	s.fsum += f
+	return nil
+}
+
+// intSum returns the integer sum.
+func (s *aggState) intSum() *big.Int {
+	if s.bsum != nil {
+		return s.bsum
+	}
+	return big.NewInt(s.isum)
+}
+
+// sum returns the sum, as an int64 if it is an integer small enough.
+func (s *aggState) sum() interface{} {
+	switch {
+	case s.float:
+		return s.fsum
+	case s.bsum != nil && !s.bsum.IsInt64():
+		return s.bsum
+	}
+	return s.intSum().Int64()
+}
+
+// avg returns the average, or nil without values.
+func (s *aggState) avg() interface{} {
+	switch {
+	case s.count == 0:
+		return nil
+	case s.float:
+		return s.fsum / float64(s.count)
+	}
+	avg, _ := new(big.Float).Quo(new(big.Float).SetInt(s.intSum()), new(big.Float).SetInt64(s.count)).Float64()
+	return avg
+}
+
+// GroupBy groups the rows by the key columns and aggregates each group.
+// The output has the key columns followed by the aggregations, with groups
+// in order of first appearance. Nil values are ignored by aggregations.
+// Sums of integers are exact int64 values, or *big.Int values when they
+// overflow, and other sums and averages are float64 values.
+func (d *Dataset) GroupBy(keys []string, aggs ...Aggregation) (Dataset, error) {
+	keyCols, err := d.columnIndexes(keys)
+	if err != nil {
+		return Dataset{}, err
+	}
+
+	aggCols := make([]int, len(aggs))
+	fields := append([]string{}, keys...)
+	for i, agg := range aggs {
+		aggCols[i] = -1
+		switch agg.Func {
+		case AggSum, AggCount, AggMin, AggMax, AggAvg:
+		default:
+			return Dataset{}, g.Error("invalid aggregation function: %s", agg.Func)
+		}
+		if agg.Column != "" {
+			col, ok := d.ColumnIndex(agg.Column)
+			if !ok {
+				return Dataset{}, g.Error("column %s not found", agg.Column)
+			}
+			aggCols[i] = col
+		} else if agg.Func != AggCount {
+			return Dataset{}, g.Error("aggregation %s requires a column", agg.Func)
+		}
+		fields = append(fields, agg.name())
+	}
+
+	type group struct {
+		key    Row
+		states []aggState
+	}
+	groups := []*group{}
+	groupMap := map[string]*group{}
+
+	for _, row := range d.Rows {
+		id := rowKey(row, keyCols)
+		grp, ok := groupMap[id]
+		if !ok {
+			grp = &group{key: make(Row, len(keyCols)), states: make([]aggState, len(aggs))}
+			for i, col := range keyCols {
+				grp.key[i] = cellValue(row, col)
+			}
+			groupMap[id] = grp
+			groups = append(groups, grp)
+		}
+
+		for i, agg := range aggs {
+			state := &grp.states[i]
+			if aggCols[i] < 0 {
+				state.count++
+				continue
+			}
+			val := cellValue(row, aggCols[i])
+			if val == nil {
+				continue
+			}
+			state.count++
+			switch agg.Func {
+			case AggSum, AggAvg:
+				if err := state.add(val); err != nil {
+					return Dataset{}, g.Error(err, "could not aggregate %s of column %s", agg.Func, agg.Column)
+				}
+			case AggMin:
+				if state.value == nil || CompareValues(val, state.value) < 0 {
+					state.value = val
+				}
+			case AggMax:
+				if state.value == nil || CompareValues(val, state.value) > 0 {
+					state.value = val
+				}
+			}
+		}
+	}
+
+	nd := NewDataset(fields...)
+	for _, grp := range groups {
+		row := append(Row{}, grp.key...)
+		for i, agg := range aggs {
+			state := grp.states[i]
+			switch agg.Func {
+			case AggSum:
+				row = append(row, state.sum())
+			case AggCount:
+				row = append(row, state.count)
+			case AggMin, AggMax:
+				row = append(row, state.value)
+			case AggAvg:
+				row = append(row, state.avg())
+			}
+		}
+		nd.Rows = append(nd.Rows, row)
+	}
+	return nd, nil
+}
+
+// JoinKind is the kind of a join
+type JoinKind string
+
+const (
+	JoinInner JoinKind = "inner"
+	JoinLeft  JoinKind = "left"
+)
+
+// Join joins the dataset with right on the key columns, present in both.
+// The output has the columns of d followed by the non-key columns of right,
+// suffixed with `_right` when the name already exists. With JoinLeft, rows
+// of d without a match are kept with nil values for right's columns.
+// Nil keys never match.
+func (d *Dataset) Join(right Dataset, keys []string, kind JoinKind) (Dataset, error) {
+	if kind != JoinInner && kind != JoinLeft {
+		return Dataset{}, g.Error("invalid join kind: %s", kind)
+	}
+
+	leftCols, err := d.columnIndexes(keys)
+	if err != nil {
+		return Dataset{}, err
+	}
+	rightCols, err := right.columnIndexes(keys)
+	if err != nil {
+		return Dataset{}, g.Error(err, "invalid join key for right dataset")
+	}
+
+	isKey := map[int]bool{}
+	for _, col := range rightCols {
+		isKey[col] = true
+	}
+
+	fields := append([]string{}, d.Fields...)
+	fieldMap := Fields(fields).AsMap()
+	rightKeep := []int{}
+	for col, name := range right.Fields {
+		if isKey[col] {
+			continue
+		}
+		if _, ok := fieldMap[strings.ToLower(name)]; ok {
+			name = name + "_right"
+		}
+		fields = append(fields, name)
+		fieldMap[strings.ToLower(name)] = len(fields) - 1
+		rightKeep = append(rightKeep, col)
+	}
+
+	index := map[string][]Row{}
+	for _, row := range right.Rows {
+		if hasNil(row, rightCols) {
+			continue
+		}
+		id := rowKey(row, rightCols)
+		index[id] = append(index[id], row)
+	}
+
+	nd := NewDataset(fields...)
+	for _, row := range d.Rows {
+		var matches []Row
+		if !hasNil(row, leftCols) {
+			matches = index[rowKey(row, leftCols)]
+		}
+		if len(matches) == 0 && kind == JoinLeft {
+			matches = []Row{nil}
+		}
+		for _, match := range matches {
+			newRow := make(Row, 0, len(fields))
+			for col := range d.Fields {
+				newRow = append(newRow, cellValue(row, col))
+			}
+			for _, col := range rightKeep {
+				newRow = append(newRow, cellValue(match, col))
+			}
+			nd.Rows = append(nd.Rows, newRow)
+		}
+	}
+	return nd, nil
+}
+
+// columnIndexes returns the indexes of the columns named keys
+func (d *Dataset) columnIndexes(keys []string) ([]int, error) {
+	cols := make([]int, len(keys))
+	for i, key := range keys {
+		col, ok := d.ColumnIndex(key)
+		if !ok {
+			return nil, g.Error("column %s not found", key)
+		}
+		cols[i] = col
+	}
+	return cols, nil
+}
+
+// cellValue returns row[col], or nil when out of range
+func cellValue(row Row, col int) interface{} {
+	if col < len(row) {
+		return row[col]
+	}
+	return nil
+}
+
+// hasNil returns true if one of the cols is nil in row
+func hasNil(row Row, cols []int) bool {
+	for _, col := range cols {
+		if cellValue(row, col) == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// rowKey returns a string identifying the values of cols in row.
+// Numbers of different types are equal if their values are.
+func rowKey(row Row, cols []int) string {
+	parts := make([]string, len(cols))
+	for i, col := range cols {
+		val := cellValue(row, col)
+		switch v := val.(type) {
+		case nil:
+			parts[i] = "\x01"
+		case time.Time:
+			parts[i] = v.UTC().Format(time.RFC3339Nano)
+		default:
+			if isNumber(val) {
+				parts[i] = numberKey(val)
+			} else {
+				parts[i] = fmt.Sprint(val)
+			}
+		}
+	}
+	return strings.Join(parts, "\x00")
+}
+
+// isNumber returns true if val is of a numeric type
+func isNumber(val interface{}) bool {
+	switch val.(type) {
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64,
+		float32, float64, *big.Int, *big.Float:
+		return true
+	}
+	return false
+}
+
+// numberKey returns the exact decimal text of a number, which is the same
+// for equal values of different types, such as 2, int64(2) and 2.0.
+func numberKey(val interface{}) string {
+	switch v := val.(type) {
+	case float32:
+		return floatKey(float64(v))
+	case float64:
+		return floatKey(v)
+	case *big.Int:
+		return v.String()
+	case *big.Float:
+		if v.IsInt() {
+			i, _ := v.Int(nil)
+			return i.String()
+		}
+		return v.Text('g', -1)
+	}
+	if i, ok := int64Value(val); ok {
+		return strconv.FormatInt(i, 10)
+	}
+	return strconv.FormatUint(cast.ToUint64(val), 10)
+}
+
+// floatKey returns the text of f, as an integer if it is integral.
+func floatKey(f float64) string {
+	switch {
+	case f != math.Trunc(f) || math.IsInf(f, 0):
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	case math.Abs(f) < math.MaxInt64:
+		return strconv.FormatInt(int64(f), 10)
+	}
+	i, _ := big.NewFloat(f).Int(nil)
+	return i.String()
+}
+
+// int64Value returns val as an int64 if it is a signed integer, or an
+// unsigned one small enough.
+func int64Value(val interface{}) (int64, bool) {
+	switch v := val.(type) {
+	case int:
+		return int64(v), true
+	case int8:
+		return int64(v), true
+	case int16:
+		return int64(v), true
+	case int32:
+		return int64(v), true
+	case int64:
+		return v, true
+	case uint:
+		return int64(v), v <= math.MaxInt64
+	case uint8:
+		return int64(v), true
+	case uint16:
+		return int64(v), true
+	case uint32:
+		return int64(v), true
+	case uint64:
+		return int64(v), v <= math.MaxInt64
+	}
+	return 0, false
+}
+
+// bigInt returns the integer val as a big.Int, or nil if it is not an
+// integer.
+func bigInt(val interface{}) *big.Int {
+	switch v := val.(type) {
+	case *big.Int:
+		return v
+	case uint:
+		return new(big.Int).SetUint64(uint64(v))
+	case uint64:
+		return new(big.Int).SetUint64(v)
+	}
+	if i, ok := int64Value(val); ok {
+		return big.NewInt(i)
+	}
+	return nil
+}
+
+// bigValue returns the number val as an exact big.Float, or nil for NaN.
+func bigValue(val interface{}) *big.Float {
+	switch v := val.(type) {
+	case float32:
+		return bigValue(float64(v))
+	case float64:
+		if math.IsNaN(v) {
+			return nil
+		}
+		return big.NewFloat(v)
+	case *big.Int:
+		return new(big.Float).SetInt(v)
+	case *big.Float:
+		return v
+	}
+	if i, ok := int64Value(val); ok {
+		return new(big.Float).SetInt64(i)
+	}
+	return new(big.Float).SetUint64(cast.ToUint64(val))
+}
+
+// compareNumbers compares the numbers a and b exactly, so that large
+// integers and big values are not rounded to float64.
+func compareNumbers(a, b interface{}) int {
+	if ia, ok := int64Value(a); ok {
+		if ib, ok := int64Value(b); ok {
+			switch {
+			case ia < ib:
+				return -1
+			case ia > ib:
+				return 1
+			}
+			return 0
+		}
+	}
+
+	ba, bb := bigValue(a), bigValue(b)
+	if ba == nil || bb == nil {
+		// NaN is not ordered
+		return 0
+	}
+	return ba.Cmp(bb)
+}
+
+// CompareValues compares a and b, returning -1, 0 or 1.
+// Nil sorts first, numbers compare numerically, times chronologically,
+// false before true, and other values by their string representation.
+func CompareValues(a, b interface{}) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	}
+
+	if isNumber(a) && isNumber(b) {
+		return compareNumbers(a, b)
+	}
+
+	if ta, ok := a.(time.Time); ok {
+		if tb, ok := b.(time.Time); ok {
+			return ta.Compare(tb)
+		}
+	}
+
+	if ba, ok := a.(bool); ok {
+		if bb, ok := b.(bool); ok {
+			switch {
+			case ba == bb:
+				return 0
+			case !ba:
+				return -1
+			}
+			return 1
+		}
+	}
+
+	return strings.Compare(cast.ToString(a), cast.ToString(b))
+}
@@ -0,0 +1,167 @@
+package data
+
+import (
+	"bytes"
+	"math"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func sales() Dataset {
+	d := NewDataset("id", "region", "amount", "qty")
+	d.Append(1, "east", 10.5, 2)
+	d.Append(2, "west", 3.0, nil)
+	d.Append(3, "east", 7.25, 1)
+	d.Append(4, nil, 1.0, 5)
+	d.Append(5, "west", 4.0, 3)
+	return d
+}
+
+func TestDatasetAccessors(t *testing.T) {
+	d := sales()
+	assert.Equal(t, int64(3), d.Int(2, "ID"))
+	assert.Equal(t, 7.25, d.Float(2, "amount"))
+	assert.Equal(t, "east", d.String(2, "region"))
+	assert.True(t, d.Bool(0, "id"))
+	assert.Nil(t, d.Value(0, "missing"))
+	assert.Equal(t, []interface{}{2, nil, 1, 5, 3}, d.Column("qty"))
+}
+
+func TestDatasetSortFilter(t *testing.T) {
+	d := sales()
+	assert.NoError(t, d.Sort(SortKey{Column: "region"}, SortKey{Column: "amount", Desc: true}))
+	assert.Equal(t, []interface{}{4, 1, 3, 5, 2}, d.Column("id"))
+
+	assert.Error(t, d.Sort(SortKey{Column: "missing"}))
+
+	f := d.Filter(func(i int) bool { return d.Float(i, "amount") > 3.5 })
+	assert.Equal(t, []interface{}{1, 3, 5}, f.Column("id"))
+	assert.Equal(t, d.Fields, f.Fields)
+
+	f, err := d.FilterExpr(`amount > 3.5 and (region = 'west' or qty is null or Qty >= 2)`)
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{1, 5}, f.Column("id"))
+
+	_, err = d.FilterExpr(`amount >`)
+	assert.Error(t, err)
+}
+
+func TestDatasetGroupBy(t *testing.T) {
+	d := sales()
+	g, err := d.GroupBy(
+		[]string{"region"},
+		Aggregation{Func: AggCount},
+		Aggregation{Func: AggSum, Column: "amount"},
+		Aggregation{Func: AggAvg, Column: "qty", As: "avg_qty"},
+		Aggregation{Func: AggMin, Column: "amount"},
+		Aggregation{Func: AggMax, Column: "id"},
+	)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, Fields{"region", "count", "sum_amount", "avg_qty", "min_amount", "max_id"}, g.Fields)
+	assert.Equal(t, []Row{
+		{"east", int64(2), 17.75, 1.5, 7.25, 3},
+		{"west", int64(2), 7.0, 3.0, 3.0, 5},
+		{nil, int64(1), 1.0, 5.0, 1.0, 4},
+	}, g.Rows)
+
+	_, err = d.GroupBy([]string{"region"}, Aggregation{Func: AggSum})
+	assert.Error(t, err)
+	_, err = d.GroupBy([]string{"region"}, Aggregation{Func: "median", Column: "qty"})
+	assert.Error(t, err)
+
+	// integer sums are exact
+	const big53 = int64(1) << 53
+	nums := NewDataset("k", "n")
+	nums.Append("a", int64(math.MaxInt64))
+	nums.Append("a", 1)
+	nums.Append("b", big53+1)
+	nums.Append("b", 1)
+	nums.Append("c", 1)
+	nums.Append("c", 0.5)
+	nums.Append("d", nil)
+	g, err = nums.GroupBy([]string{"k"}, Aggregation{Func: AggSum, Column: "n"}, Aggregation{Func: AggAvg, Column: "n"})
+	if assert.NoError(t, err) {
+		overflow := new(big.Int).Add(big.NewInt(math.MaxInt64), big.NewInt(1))
+		assert.Equal(t, []interface{}{overflow, big53 + 2, 1.5, int64(0)}, g.Column("sum_n"))
+		assert.Equal(t, []interface{}{4.611686018427387904e18, float64(big53/2 + 1), 0.75, nil}, g.Column("avg_n"))
+	}
+}
+
+func TestDatasetExactNumbers(t *testing.T) {
+	const big53 = int64(1) << 53
+	d := NewDataset("id", "n")
+	d.Append(big53+1, 1)
+	d.Append(big53, 2)
+	d.Append(float64(big53), 3)
+	d.Append(big.NewInt(7), 4)
+	d.Append(new(big.Int).Lsh(big.NewInt(1), 70), 5)
+	d.Append(big.NewFloat(7.5), 6)
+	d.Append(uint64(7), 7)
+
+	g, err := d.GroupBy([]string{"id"}, Aggregation{Func: AggCount})
+	if assert.NoError(t, err) {
+		assert.Equal(t, []interface{}{int64(1), int64(2), int64(2), int64(1), int64(1)}, g.Column("count"))
+	}
+
+	assert.NoError(t, d.Sort(SortKey{Column: "id"}))
+	assert.Equal(t, []interface{}{4, 7, 6, 2, 3, 1, 5}, d.Column("n"))
+
+	assert.Equal(t, -1, CompareValues(big53, big53+1))
+	assert.Equal(t, 1, CompareValues(uint64(math.MaxUint64), int64(math.MaxInt64)))
+	assert.Equal(t, 0, CompareValues(big.NewInt(3), 3.0))
+	assert.Equal(t, -1, CompareValues(big.NewInt(3), big.NewFloat(3.5)))
+}
+
+func TestDatasetJoin(t *testing.T) {
+	d := sales()
+	regions := NewDataset("region", "manager", "id")
+	regions.Append("east", "Ann", 100)
+	regions.Append("west", "Bob", 200)
+	regions.Append("west", "Cid", 300)
+
+	j, err := d.Join(regions, []string{"region"}, JoinInner)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, Fields{"id", "region", "amount", "qty", "manager", "id_right"}, j.Fields)
+	assert.Equal(t, []interface{}{1, 2, 2, 3, 5, 5}, j.Column("id"))
+	assert.Equal(t, []interface{}{"Ann", "Bob", "Cid", "Ann", "Bob", "Cid"}, j.Column("manager"))
+
+	j, err = d.Join(regions, []string{"region"}, JoinLeft)
+	assert.NoError(t, err)
+	assert.Len(t, j.Rows, 7)
+	assert.Equal(t, Row{4, nil, 1.0, 5, nil, nil}, j.Rows[4])
+
+	_, err = d.Join(regions, []string{"qty"}, JoinInner)
+	assert.Error(t, err)
+}
+
+func TestDatasetConvert(t *testing.T) {
+	d := sales()
+
+	var buf bytes.Buffer
+	assert.NoError(t, d.WriteCSV(&buf))
+	assert.Equal(t, "id,region,amount,qty\n1,east,10.5,2\n2,west,3,\n3,east,7.25,1\n4,,1,5\n5,west,4,3\n", buf.String())
+
+	c, err := ReadCSV(strings.NewReader(buf.String()))
+	assert.NoError(t, err)
+	assert.Equal(t, d.Fields, c.Fields)
+	assert.Equal(t, Row{"2", "west", "3", ""}, c.Rows[1])
+
+	data, err := d.MarshalRecords()
+	assert.NoError(t, err)
+	r, err := UnmarshalRecords(data)
+	assert.NoError(t, err)
+	assert.Equal(t, Fields{"amount", "id", "qty", "region"}, r.Fields)
+	assert.Equal(t, Row{1.0, 4.0, 5.0, nil}, r.Rows[3])
+
+	out := d.Pretty()
+	assert.Contains(t, out, "REGION")
+	assert.Contains(t, out, "10.5")
+	assert.Nil(t, d.Rows[3][1])
+}
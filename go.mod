@@ -3,6 +3,7 @@ module github.com/flarco/g
 go 1.20
 
 require (
+	github.com/dsnet/compress v0.0.1
 	github.com/fatih/color v1.9.0
 	github.com/getsentry/sentry-go v0.27.0
 	github.com/go-redis/redis/v8 v8.8.0
@@ -10,6 +11,7 @@ require (
 	github.com/integrii/flaggy v1.5.2
 	github.com/jedib0t/go-pretty v4.3.0+incompatible
 	github.com/json-iterator/go v1.1.12
+	github.com/klauspost/compress v1.17.9
 	github.com/orcaman/concurrent-map/v2 v2.0.1
 	github.com/rs/zerolog v1.34.0
 	github.com/samber/lo v1.39.0
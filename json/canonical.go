@@ -0,0 +1,174 @@
+package json
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"strconv"
+	"unicode/utf16"
+)
+
+// MarshalCanonical returns the canonical JSON encoding of v, as defined
+// by the JSON Canonicalization Scheme (RFC 8785), so that equal values
+// produce the same bytes across services and languages, to be hashed or
+// signed. v is first encoded as by Marshal, without HTML escaping; see
+// Canonicalize for the canonical form.
+func MarshalCanonical(v any) ([]byte, error) {
+	e := newEncodeState()
+	defer encodeStatePool.Put(e)
+	if err := e.marshal(v, encOpts{registry: activeRegistry(nil)}); err != nil {
+		return nil, err
+	}
+	return Canonicalize(e.Bytes())
+}
+
+// Canonicalize returns the canonical form (RFC 8785) of the JSON-encoded
+// data:
+//
+//   - no whitespace between tokens;
+//   - object members sorted by key, comparing the UTF-16 code units;
+//   - numbers formatted as by ECMAScript, after conversion to float64,
+//     such as 1e+21, 0.000001 or 1e-7; -0 is formatted as 0;
+//   - strings escaped minimally: only '"', '\' and control characters are
+//     escaped, as \b, \t, \n, \f, \r or \u00xx.
+//
+// Numbers which overflow a float64 are an error. As with Unmarshal, the
+// last of duplicate object members wins.
+func Canonicalize(data []byte) ([]byte, error) {
+	var d decodeState
+	if err := checkValid(data, &d.scan); err != nil {
+		return nil, err
+	}
+	d.useNumber = true
+	d.init(data)
+	var v any
+	if err := d.unmarshal(&v); err != nil {
+		return nil, err
+	}
+	return appendCanonical(make([]byte, 0, len(data)), v)
+}
+
+// appendCanonical appends the canonical encoding of v, an interface{}
+// tree decoded with UseNumber.
+func appendCanonical(b []byte, v any) ([]byte, error) {
+	switch v := v.(type) {
+	case nil:
+		return append(b, "null"...), nil
+	case bool:
+		return strconv.AppendBool(b, v), nil
+	case Number:
+		f, err := strconv.ParseFloat(string(v), 64)
+		if err != nil {
+			return nil, errors.New("json: number " + string(v) + " cannot be canonicalized: out of range")
+		}
+		return appendESNumber(b, f), nil
+	case string:
+		return appendCanonicalString(b, v), nil
+	case []any:
+		b = append(b, '[')
+		for i, elem := range v {
+			if i > 0 {
+				b = append(b, ',')
+			}
+			var err error
+			if b, err = appendCanonical(b, elem); err != nil {
+				return nil, err
+			}
+		}
+		return append(b, ']'), nil
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool { return lessUTF16(keys[i], keys[j]) })
+		b = append(b, '{')
+		for i, key := range keys {
+			if i > 0 {
+				b = append(b, ',')
+			}
+			b = appendCanonicalString(b, key)
+			b = append(b, ':')
+			var err error
+			if b, err = appendCanonical(b, v[key]); err != nil {
+				return nil, err
+			}
+		}
+		return append(b, '}'), nil
+	}
+	return nil, errors.New("json: cannot canonicalize value of unexpected type")
+}
+
+// appendESNumber appends f formatted as by the ECMAScript Number
+// toString conversion.
+func appendESNumber(b []byte, f float64) []byte {
+	if f == 0 {
+		// also -0
+		return append(b, '0')
+	}
+	format := byte('f')
+	if abs := math.Abs(f); abs < 1e-6 || abs >= 1e21 {
+		format = 'e'
+	}
+	start := len(b)
+	b = strconv.AppendFloat(b, f, format, -1, 64)
+	if format == 'e' {
+		// clean up e-09 to e-9
+		n := len(b)
+		if n-start >= 4 && b[n-4] == 'e' && b[n-3] == '-' && b[n-2] == '0' {
+			b[n-2] = b[n-1]
+			b = b[:n-1]
+		}
+	}
+	return b
+}
+
+// appendCanonicalString appends s quoted with the minimal escaping of
+// RFC 8785. s is valid UTF-8, as produced by the decoder.
+func appendCanonicalString(b []byte, s string) []byte {
+	b = append(b, '"')
+	start := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c >= 0x20 && c != '"' && c != '\\' {
+			continue
+		}
+		b = append(b, s[start:i]...)
+		switch c {
+		case '"', '\\':
+			b = append(b, '\\', c)
+		case '\b':
+			b = append(b, '\\', 'b')
+		case '\t':
+			b = append(b, '\\', 't')
+		case '\n':
+			b = append(b, '\\', 'n')
+		case '\f':
+			b = append(b, '\\', 'f')
+		case '\r':
+			b = append(b, '\\', 'r')
+		default:
+			b = append(b, '\\', 'u', '0', '0', hex[c>>4], hex[c&0xF])
+		}
+		start = i + 1
+	}
+	b = append(b, s[start:]...)
+	return append(b, '"')
+}
+
+// lessUTF16 reports whether a sorts before b when compared as UTF-16
+// code units.
+func lessUTF16(a, b string) bool {
+	ua, ub := utf16.Encode([]rune(a)), utf16.Encode([]rune(b))
+	for i := 0; i < len(ua) && i < len(ub); i++ {
+		if ua[i] != ub[i] {
+			return ua[i] < ub[i]
+		}
+	}
+	return len(ua) < len(ub)
+}
+
+// SetCanonical specifies whether values are encoded in the canonical form
+// of RFC 8785, as by MarshalCanonical. Indentation and HTML escaping do
+// not apply to canonical output.
+func (enc *Encoder) SetCanonical(on bool) { enc.canonical = on }
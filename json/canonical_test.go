@@ -0,0 +1,108 @@
+package json
+
+import (
+	"bytes"
+	"math"
+	"testing"
+)
+
+func TestCanonicalize(t *testing.T) {
+	tests := []struct{ in, want string }{
+		// from RFC 8785, section 3.2.2
+		{
+			in: `{
+				"numbers": [333333333.33333329, 1E30, 4.50, 2e-3, 0.000000000000000000000000001],
+				"string": "\u20ac$\u000F\u000aA'\u0042\u0022\u005c\\\"\/",
+				"literals": [null, true, false]
+			}`,
+			want: `{"literals":[null,true,false],"numbers":[333333333.3333333,1e+30,4.5,0.002,1e-27],"string":"€$\u000f\nA'B\"\\\\\"/"}`,
+		},
+		// from RFC 8785, section 3.2.3
+		{
+			in: `{
+				"\u20ac": "Euro Sign",
+				"\r": "Carriage Return",
+				"\ufb33": "Hebrew Letter Dalet With Dagesh",
+				"1": "One",
+				"\ud83d\ude00": "Emoji: Grinning Face",
+				"\u0080": "Control",
+				"\u00f6": "Latin Small Letter O With Diaeresis"
+			}`,
+			want: "{\"\\r\":\"Carriage Return\",\"1\":\"One\",\"\u0080\":\"Control\",\"ö\":\"Latin Small Letter O With Diaeresis\"," +
+				"\"€\":\"Euro Sign\",\"😀\":\"Emoji: Grinning Face\",\"\ufb33\":\"Hebrew Letter Dalet With Dagesh\"}",
+		},
+		{in: `"<&>\u2028\b\f\u001f"`, want: "\"<&>\u2028\\b\\f\\u001f\""},
+		{in: `[-0, 0.0, 1e2, -1.5E-7, 100000000000000000000, 1e21]`, want: `[0,0,100,-1.5e-7,100000000000000000000,1e+21]`},
+		{in: `{"b": {"z": 1, "a": 2}, "a": []}`, want: `{"a":[],"b":{"a":2,"z":1}}`},
+	}
+	for _, tt := range tests {
+		got, err := Canonicalize([]byte(tt.in))
+		if err != nil {
+			t.Errorf("Canonicalize(%s): %v", tt.in, err)
+		} else if string(got) != tt.want {
+			t.Errorf("Canonicalize(%s):\ngot  %s\nwant %s", tt.in, got, tt.want)
+		}
+	}
+
+	for _, in := range []string{`1e400`, `{"a":}`, `[1] 2`} {
+		if _, err := Canonicalize([]byte(in)); err == nil {
+			t.Errorf("Canonicalize(%s): expected error", in)
+		}
+	}
+}
+
+func TestCanonicalNumbers(t *testing.T) {
+	// from RFC 8785, appendix B
+	tests := []struct {
+		bits uint64
+		want string
+	}{
+		{0x0000000000000000, "0"},
+		{0x8000000000000000, "0"},
+		{0x0000000000000001, "5e-324"},
+		{0x8000000000000001, "-5e-324"},
+		{0x7fefffffffffffff, "1.7976931348623157e+308"},
+		{0xffefffffffffffff, "-1.7976931348623157e+308"},
+		{0x4340000000000000, "9007199254740992"},
+		{0xc340000000000000, "-9007199254740992"},
+		{0x4430000000000000, "295147905179352830000"},
+		{0x44b52d02c7e14af5, "9.999999999999997e+22"},
+		{0x44b52d02c7e14af6, "1e+23"},
+		{0x3eb0c6f7a0b5ed8c, "9.999999999999997e-7"},
+		{0x3eb0c6f7a0b5ed8d, "0.000001"},
+		{0x41b3de4355555553, "333333333.3333332"},
+	}
+	for _, tt := range tests {
+		if got := string(appendESNumber(nil, math.Float64frombits(tt.bits))); got != tt.want {
+			t.Errorf("%016x: got %s, want %s", tt.bits, got, tt.want)
+		}
+	}
+}
+
+func TestMarshalCanonical(t *testing.T) {
+	type item struct {
+		Name  string  `json:"name"`
+		Price float64 `json:"price"`
+		Tags  []string
+	}
+	v := map[string]any{"z": item{"a<b", 1.50, nil}, "a": []int{3, 1}}
+	want := `{"a":[3,1],"z":{"Tags":null,"name":"a<b","price":1.5}}`
+	got, err := MarshalCanonical(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Errorf("MarshalCanonical = %s, want %s", got, want)
+	}
+
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	enc.SetCanonical(true)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != want {
+		t.Errorf("Encoder = %s, want %s", buf.String(), want)
+	}
+}
@@ -99,12 +99,14 @@ func Unmarshal(data []byte, v any) error {
 	// before discovering a JSON syntax error.
 	var d decodeState
 	err := checkValid(data, &d.scan)
+	if err == nil {
+		d.init(data)
+		err = d.unmarshal(v)
+	}
 	if err != nil {
-		return err
+		locateError(err, data, false, func(off int) (int, int) { return lineColumn(data, off) })
 	}
-
-	d.init(data)
-	return d.unmarshal(v)
+	return err
 }
 
 // Unmarshaler is the interface implemented by types
@@ -121,12 +123,18 @@ type Unmarshaler interface {
 
 // An UnmarshalTypeError describes a JSON value that was
 // not appropriate for a value of a specific Go type.
+//
+// Line, Column and Pointer locate the value as for SyntaxError.
 type UnmarshalTypeError struct {
 	Value  string       // description of JSON value - "bool", "array", "number -5"
 	Type   reflect.Type // type of Go value it could not be assigned to
 	Offset int64        // error occurred after reading Offset bytes
 	Struct string       // name of the struct type containing the field
 	Field  string       // the full path from root node to the field
+
+	Line    int
+	Column  int
+	Pointer string // JSON Pointer of the value, such as /items/3/price
 }
 
 func (e *UnmarshalTypeError) Error() string {
@@ -173,6 +181,7 @@ func (d *decodeState) unmarshal(v any) error {
 		return &InvalidUnmarshalError{reflect.TypeOf(v)}
 	}
 
+	d.funcs = activeRegistry(d.registry)
 	d.scan.reset()
 	d.scanWhile(scanSkipSpace)
 	// We decode rv not rv.Elem because the Unmarshaler interface
@@ -215,7 +224,10 @@ type decodeState struct {
 	errorContext          *errorContext
 	savedError            error
 	useNumber             bool
+	useOrderedObjects     bool
 	disallowUnknownFields bool
+	registry              *Registry // set on the Decoder
+	funcs                 *Registry // active during unmarshal
 }
 
 // readIndex returns the position of the last byte read.
@@ -419,10 +431,11 @@ func (d *decodeState) valueQuoted() any {
 
 // indirect walks down v allocating pointers as needed,
 // until it gets to a non-pointer.
-// If it encounters an Unmarshaler, indirect stops and returns that.
+// If it encounters an Unmarshaler, or a type with a decoder registered
+// in funcs, indirect stops and returns that.
 // If decodingNull is true, indirect stops at the first settable pointer so it
 // can be set to nil.
-func indirect(v reflect.Value, decodingNull bool) (Unmarshaler, encoding.TextUnmarshaler, reflect.Value) {
+func indirect(v reflect.Value, decodingNull bool, funcs *Registry) (Unmarshaler, encoding.TextUnmarshaler, reflect.Value) {
 	// Issue #24153 indicates that it is generally not a guaranteed property
 	// that you may round-trip a reflect.Value by calling Value.Addr().Elem()
 	// and expect the value to still be settable for values derived from
@@ -474,6 +487,11 @@ func indirect(v reflect.Value, decodingNull bool) (Unmarshaler, encoding.TextUnm
 		if v.IsNil() {
 			v.Set(reflect.New(v.Type().Elem()))
 		}
+		if funcs != nil && v.CanInterface() {
+			if fn := funcs.decoder(v.Type().Elem()); fn != nil {
+				return registryUnmarshaler{fn, v}, nil, reflect.Value{}
+			}
+		}
 		if v.Type().NumMethod() > 0 && v.CanInterface() {
 			if u, ok := v.Interface().(Unmarshaler); ok {
 				return u, nil, reflect.Value{}
@@ -499,7 +517,7 @@ func indirect(v reflect.Value, decodingNull bool) (Unmarshaler, encoding.TextUnm
 // The first byte of the array ('[') has been read already.
 func (d *decodeState) array(v reflect.Value) error {
 	// Check for unmarshaler.
-	u, ut, pv := indirect(v, false)
+	u, ut, pv := indirect(v, false, d.funcs)
 	if u != nil {
 		start := d.readIndex()
 		d.skip()
@@ -605,7 +623,7 @@ var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem(
 // The first byte ('{') of the object has been read already.
 func (d *decodeState) object(v reflect.Value) error {
 	// Check for unmarshaler.
-	u, ut, pv := indirect(v, false)
+	u, ut, pv := indirect(v, false, d.funcs)
 	if u != nil {
 		start := d.readIndex()
 		d.skip()
@@ -621,6 +639,10 @@ func (d *decodeState) object(v reflect.Value) error {
 
 	// Decoding into nil interface? Switch to non-reflect code.
 	if v.Kind() == reflect.Interface && v.NumMethod() == 0 {
+		if d.useOrderedObjects {
+			v.Set(reflect.ValueOf(d.orderedObjectInterface()))
+			return nil
+		}
 		oi := d.objectInterface()
 		v.Set(reflect.ValueOf(oi))
 		return nil
@@ -866,7 +888,7 @@ func (d *decodeState) literalStore(item []byte, v reflect.Value, fromQuoted bool
 		return nil
 	}
 	isNull := item[0] == 'n' // null
-	u, ut, pv := indirect(v, isNull)
+	u, ut, pv := indirect(v, isNull, d.funcs)
 	if u != nil {
 		return u.UnmarshalJSON(item)
 	}
@@ -1045,7 +1067,11 @@ func (d *decodeState) valueInterface() (val any) {
 		val = d.arrayInterface()
 		d.scanNext()
 	case scanBeginObject:
-		val = d.objectInterface()
+		if d.useOrderedObjects {
+			val = d.orderedObjectInterface()
+		} else {
+			val = d.objectInterface()
+		}
 		d.scanNext()
 	case scanBeginLiteral:
 		val = d.literalInterface()
@@ -1082,6 +1108,20 @@ func (d *decodeState) arrayInterface() []any {
 // objectInterface is like object but returns map[string]interface{}.
 func (d *decodeState) objectInterface() map[string]any {
 	m := make(map[string]any)
+	d.objectMembers(func(key string, val any) { m[key] = val })
+	return m
+}
+
+// orderedObjectInterface is like object but returns an *OrderedMap.
+func (d *decodeState) orderedObjectInterface() *OrderedMap {
+	m := NewOrderedMap()
+	d.objectMembers(m.Set)
+	return m
+}
+
+// objectMembers consumes the members of an object, calling set with each
+// key and value in order.
+func (d *decodeState) objectMembers(set func(key string, val any)) {
 	for {
 		// Read opening " of string key or closing }.
 		d.scanWhile(scanSkipSpace)
@@ -1112,7 +1152,7 @@ func (d *decodeState) objectInterface() map[string]any {
 		d.scanWhile(scanSkipSpace)
 
 		// Read value.
-		m[key] = d.valueInterface()
+		set(key, d.valueInterface())
 
 		// Next token must be , or }.
 		if d.opcode == scanSkipSpace {
@@ -1125,7 +1165,6 @@ func (d *decodeState) objectInterface() map[string]any {
 			panic(phasePanicMsg)
 		}
 	}
-	return m
 }
 
 // literalInterface consumes and returns a literal from d.data[d.off-1:] and
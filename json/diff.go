@@ -0,0 +1,226 @@
+package json
+
+import (
+	"strconv"
+	"strings"
+)
+
+// DiffKind is the kind of a Change.
+type DiffKind string
+
+const (
+	DiffAdded   DiffKind = "added"
+	DiffRemoved DiffKind = "removed"
+	DiffChanged DiffKind = "changed"
+)
+
+// A Change is a difference between two documents.
+type Change struct {
+	Path string // location, such as $.items[id=3].price
+	Kind DiffKind
+	Old  any // nil when added
+	New  any // nil when removed
+}
+
+// A Diff is the list of changes between two documents, in document order.
+type Diff []Change
+
+// DiffOptions are the options of Compare.
+type DiffOptions struct {
+	// ArrayKey is the member identifying the objects of arrays: when set,
+	// arrays whose elements are all objects with this member are compared
+	// by key rather than by index. Elements are then located as [key=value].
+	ArrayKey string
+
+	// ArrayKeys sets the key member per array, overriding ArrayKey. Arrays
+	// are located as in Change.Path, with [*] for any element, such as
+	// $.orders[*].lines. An empty key compares the array by index.
+	ArrayKeys map[string]string
+}
+
+// Compare returns the structural differences from one document to another.
+// Documents are trees as for Patch. Objects are compared member by member,
+// and arrays element by element, by index or by key (see DiffOptions).
+// opts may be nil.
+func Compare(from, to any, opts *DiffOptions) (Diff, error) {
+	a, err := toTree(from)
+	if err != nil {
+		return nil, err
+	}
+	b, err := toTree(to)
+	if err != nil {
+		return nil, err
+	}
+	if opts == nil {
+		opts = &DiffOptions{}
+	}
+	d := Diff{}
+	d.compare(opts, "$", "$", a, b)
+	return d, nil
+}
+
+// CompareRaw is like Compare with encoded documents. The order of object
+// members is preserved.
+func CompareRaw(from, to RawMessage, opts *DiffOptions) (Diff, error) {
+	a, err := decodeTree(from)
+	if err != nil {
+		return nil, err
+	}
+	b, err := decodeTree(to)
+	if err != nil {
+		return nil, err
+	}
+	return Compare(a, b, opts)
+}
+
+// compare appends the changes from a to b at path. pattern is path with
+// array elements replaced by [*], to look up DiffOptions.ArrayKeys.
+func (d *Diff) compare(opts *DiffOptions, path, pattern string, a, b any) {
+	if treeEqual(a, b) {
+		return
+	}
+
+	aKeys, aObj := treeKeys(a)
+	bKeys, bObj := treeKeys(b)
+	if aObj && bObj {
+		for _, key := range aKeys {
+			av, _ := treeMember(a, key)
+			if bv, ok := treeMember(b, key); ok {
+				d.compare(opts, path+pathMember(key), pattern+pathMember(key), av, bv)
+			} else {
+				*d = append(*d, Change{Path: path + pathMember(key), Kind: DiffRemoved, Old: av})
+			}
+		}
+		for _, key := range bKeys {
+			if _, ok := treeMember(a, key); !ok {
+				bv, _ := treeMember(b, key)
+				*d = append(*d, Change{Path: path + pathMember(key), Kind: DiffAdded, New: bv})
+			}
+		}
+		return
+	}
+
+	aArr, aIsArr := a.([]any)
+	bArr, bIsArr := b.([]any)
+	if aIsArr && bIsArr {
+		key, ok := opts.ArrayKeys[pattern]
+		if !ok {
+			key = opts.ArrayKey
+		}
+		if key != "" && keyedArray(aArr, key) && keyedArray(bArr, key) {
+			d.compareKeyed(opts, path, pattern, key, aArr, bArr)
+			return
+		}
+
+		for i := 0; i < len(aArr) || i < len(bArr); i++ {
+			elemPath := path + "[" + strconv.Itoa(i) + "]"
+			switch {
+			case i >= len(bArr):
+				*d = append(*d, Change{Path: elemPath, Kind: DiffRemoved, Old: aArr[i]})
+			case i >= len(aArr):
+				*d = append(*d, Change{Path: elemPath, Kind: DiffAdded, New: bArr[i]})
+			default:
+				d.compare(opts, elemPath, pattern+"[*]", aArr[i], bArr[i])
+			}
+		}
+		return
+	}
+
+	*d = append(*d, Change{Path: path, Kind: DiffChanged, Old: a, New: b})
+}
+
+// compareKeyed compares arrays of objects matched by the member key.
+// Elements removed are listed first, then changed and added ones in the
+// order of b.
+func (d *Diff) compareKeyed(opts *DiffOptions, path, pattern, key string, a, b []any) {
+	elemPath := func(elem any) string {
+		val, _ := treeMember(elem, key)
+		lit, _ := Marshal(val)
+		return path + "[" + key + "=" + string(lit) + "]"
+	}
+	index := func(arr []any) map[string]any {
+		m := make(map[string]any, len(arr))
+		for _, elem := range arr {
+			m[elemPath(elem)] = elem
+		}
+		return m
+	}
+
+	bIndex := index(b)
+	for _, elem := range a {
+		if _, ok := bIndex[elemPath(elem)]; !ok {
+			*d = append(*d, Change{Path: elemPath(elem), Kind: DiffRemoved, Old: elem})
+		}
+	}
+	aIndex := index(a)
+	for _, elem := range b {
+		p := elemPath(elem)
+		if old, ok := aIndex[p]; ok {
+			d.compare(opts, p, pattern+"[*]", old, elem)
+		} else {
+			*d = append(*d, Change{Path: p, Kind: DiffAdded, New: elem})
+		}
+	}
+}
+
+// keyedArray reports whether all the elements of arr are objects with
+// the member key, with distinct values.
+func keyedArray(arr []any, key string) bool {
+	seen := map[string]bool{}
+	for _, elem := range arr {
+		val, ok := treeMember(elem, key)
+		if !ok {
+			return false
+		}
+		lit, err := Marshal(val)
+		if err != nil || seen[string(lit)] {
+			return false
+		}
+		seen[string(lit)] = true
+	}
+	return true
+}
+
+// Table returns the changes as a header and rows, with values as compact
+// JSON, ready to be rendered with g.PrettyTable(d.Table()).
+func (d Diff) Table() (header []string, rows [][]any) {
+	header = []string{"path", "change", "old", "new"}
+	rows = make([][]any, len(d))
+	for i, c := range d {
+		rows[i] = []any{c.Path, string(c.Kind), diffValue(c.Old, c.Kind == DiffAdded), diffValue(c.New, c.Kind == DiffRemoved)}
+	}
+	return header, rows
+}
+
+// String returns the changes, one per line, such as:
+//
+//	~ $.price: 1.5 -> 2
+//	+ $.tags[2]: "new"
+//	- $.legacy: true
+func (d Diff) String() string {
+	var sb strings.Builder
+	for _, c := range d {
+		switch c.Kind {
+		case DiffAdded:
+			sb.WriteString("+ " + c.Path + ": " + diffValue(c.New, false))
+		case DiffRemoved:
+			sb.WriteString("- " + c.Path + ": " + diffValue(c.Old, false))
+		default:
+			sb.WriteString("~ " + c.Path + ": " + diffValue(c.Old, false) + " -> " + diffValue(c.New, false))
+		}
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+// diffValue returns v as compact JSON, or an empty string if absent.
+func diffValue(v any, absent bool) string {
+	if absent {
+		return ""
+	}
+	b, err := Marshal(v)
+	if err != nil {
+		return err.Error()
+	}
+	return string(b)
+}
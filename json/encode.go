@@ -158,7 +158,7 @@ import (
 func Marshal(v any) ([]byte, error) {
 	e := newEncodeState()
 
-	err := e.marshal(v, encOpts{escapeHTML: true})
+	err := e.marshal(v, encOpts{escapeHTML: true, registry: activeRegistry(nil)})
 	if err != nil {
 		return nil, err
 	}
@@ -365,6 +365,14 @@ type encOpts struct {
 	quoted bool
 	// escapeHTML causes '<', '>', and '&' to be escaped in JSON strings.
 	escapeHTML bool
+	// registry holds the functions registered for specific types, if any.
+	registry *Registry
+	// floatFormat formats floats instead of the default format, if set.
+	floatFormat func(f float64, bits int) string
+	// nilSliceAsEmpty causes nil slices to be encoded as empty.
+	nilSliceAsEmpty bool
+	// nilMapAsEmpty causes nil maps to be encoded as empty.
+	nilMapAsEmpty bool
 }
 
 type encoderFunc func(e *encodeState, v reflect.Value, opts encOpts)
@@ -401,7 +409,7 @@ func typeEncoder(t reflect.Type) encoderFunc {
 	}
 
 	// Compute the real encoder and replace the indirect func with it.
-	f = newTypeEncoder(t, true)
+	f = registryEncoder(t, newTypeEncoder(t, true))
 	wg.Done()
 	encoderCache.Store(t, f)
 	return f
@@ -415,6 +423,10 @@ var (
 // newTypeEncoder constructs an encoderFunc for a type.
 // The returned encoder only checks CanAddr when allowAddr is true.
 func newTypeEncoder(t reflect.Type, allowAddr bool) encoderFunc {
+	if t == orderedMapType {
+		return orderedMapEncoder
+	}
+
 	// If we have a non-pointer value whose type implements
 	// Marshaler with a value receiver, then we're better off taking
 	// the address of the value - otherwise we end up with an
@@ -583,6 +595,21 @@ func (bits floatEncoder) encode(e *encodeState, v reflect.Value, opts encOpts) {
 	// See golang.org/issue/6384 and golang.org/issue/14135.
 	// Like fmt %g, but the exponent cutoffs are different
 	// and exponents themselves are not padded to two digits.
+	if opts.floatFormat != nil {
+		s := opts.floatFormat(f, int(bits))
+		if !isValidNumber(s) {
+			e.error(fmt.Errorf("json: invalid number literal %q from float format", s))
+		}
+		if opts.quoted {
+			e.WriteByte('"')
+		}
+		e.WriteString(s)
+		if opts.quoted {
+			e.WriteByte('"')
+		}
+		return
+	}
+
 	b := e.scratch[:0]
 	abs := math.Abs(f)
 	fmt := byte('f')
@@ -778,7 +805,11 @@ type mapEncoder struct {
 
 func (me mapEncoder) encode(e *encodeState, v reflect.Value, opts encOpts) {
 	if v.IsNil() {
-		e.WriteString("null")
+		if opts.nilMapAsEmpty {
+			e.WriteString("{}")
+		} else {
+			e.WriteString("null")
+		}
 		return
 	}
 	if e.ptrLevel++; e.ptrLevel > startDetectingCyclesAfter {
@@ -831,9 +862,13 @@ func newMapEncoder(t reflect.Type) encoderFunc {
 	return me.encode
 }
 
-func encodeByteSlice(e *encodeState, v reflect.Value, _ encOpts) {
+func encodeByteSlice(e *encodeState, v reflect.Value, opts encOpts) {
 	if v.IsNil() {
-		e.WriteString("null")
+		if opts.nilSliceAsEmpty {
+			e.WriteString(`""`)
+		} else {
+			e.WriteString("null")
+		}
 		return
 	}
 	s := v.Bytes()
@@ -868,7 +903,11 @@ type sliceEncoder struct {
 
 func (se sliceEncoder) encode(e *encodeState, v reflect.Value, opts encOpts) {
 	if v.IsNil() {
-		e.WriteString("null")
+		if opts.nilSliceAsEmpty {
+			e.WriteString("[]")
+		} else {
+			e.WriteString("null")
+		}
 		return
 	}
 	if e.ptrLevel++; e.ptrLevel > startDetectingCyclesAfter {
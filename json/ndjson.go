@@ -0,0 +1,302 @@
+package json
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"strconv"
+	"sync"
+)
+
+// A LineError describes an invalid line of a newline-delimited JSON stream.
+type LineError struct {
+	Line int    // 1-based line number
+	Data []byte // content of the line, without the newline
+	Err  error  // *SyntaxError with the offset in the line, or a decoding error
+}
+
+func (e *LineError) Error() string {
+	return "json: line " + strconv.Itoa(e.Line) + ": " + e.Err.Error()
+}
+
+func (e *LineError) Unwrap() error { return e.Err }
+
+// An NDJSONReader reads newline-delimited JSON (one value per line).
+// Unlike a Decoder, an invalid line does not prevent reading the following
+// lines. Blank lines are ignored.
+type NDJSONReader struct {
+	// SkipInvalid causes invalid lines to be skipped instead of being
+	// returned as a *LineError.
+	SkipInvalid bool
+
+	// OnInvalid, if set, is called with each invalid line which is skipped.
+	OnInvalid func(err *LineError)
+
+	r         *bufio.Reader
+	line      int
+	buf       []byte
+	scan      scanner
+	useNumber bool
+}
+
+// NewNDJSONReader returns a new NDJSONReader that reads from r.
+func NewNDJSONReader(r io.Reader) *NDJSONReader {
+	return &NDJSONReader{r: bufio.NewReaderSize(r, 64*1024)}
+}
+
+// UseNumber causes the reader to decode numbers into an interface{} as a
+// Number instead of as a float64.
+func (nr *NDJSONReader) UseNumber() { nr.useNumber = true }
+
+// Line returns the number of the last line read.
+func (nr *NDJSONReader) Line() int { return nr.line }
+
+// readLine returns the next non-blank line. The result is only valid
+// until the next call.
+func (nr *NDJSONReader) readLine() ([]byte, error) {
+	for {
+		line, err := nr.r.ReadSlice('\n')
+		if err == bufio.ErrBufferFull {
+			nr.buf = append(nr.buf[:0], line...)
+			for err == bufio.ErrBufferFull {
+				line, err = nr.r.ReadSlice('\n')
+				nr.buf = append(nr.buf, line...)
+			}
+			line = nr.buf
+		}
+		if err != nil && (err != io.EOF || len(line) == 0) {
+			return nil, err
+		}
+		nr.line++
+
+		line = bytes.TrimRight(line, "\r\n")
+		if !nonSpace(line) {
+			if err == io.EOF {
+				return nil, err
+			}
+			continue
+		}
+		return line, nil
+	}
+}
+
+// Next returns the next valid line as a RawMessage, along with its line
+// number. An invalid line is returned as a *LineError, unless SkipInvalid
+// is set; reading can continue after it. At the end of the stream, Next
+// returns io.EOF.
+func (nr *NDJSONReader) Next() (line int, raw RawMessage, err error) {
+	for {
+		data, err := nr.readLine()
+		if err != nil {
+			return nr.line, nil, err
+		}
+		nr.scan.bytes = 0 // offsets are relative to the line
+		if err := checkValid(data, &nr.scan); err != nil {
+			if lerr := nr.invalid(data, err); lerr != nil {
+				return nr.line, nil, lerr
+			}
+			continue
+		}
+		return nr.line, append(RawMessage{}, data...), nil
+	}
+}
+
+// Decode reads the next valid line and stores it in the value pointed to
+// by v, returning its line number. Errors are handled as in Next.
+func (nr *NDJSONReader) Decode(v any) (line int, err error) {
+	for {
+		line, raw, err := nr.Next()
+		if err != nil {
+			return line, err
+		}
+		if err = nr.unmarshal(raw, v); err != nil {
+			if lerr := nr.invalid(raw, err); lerr != nil {
+				return line, lerr
+			}
+			continue
+		}
+		return line, nil
+	}
+}
+
+func (nr *NDJSONReader) unmarshal(data []byte, v any) error {
+	var d decodeState
+	d.useNumber = nr.useNumber
+	d.init(data)
+	return d.unmarshal(v)
+}
+
+// invalid handles an invalid line, returning the error to report, if any.
+func (nr *NDJSONReader) invalid(data []byte, err error) error {
+	lerr := &LineError{Line: nr.line, Data: append([]byte{}, data...), Err: err}
+	if !nr.SkipInvalid {
+		return lerr
+	}
+	if nr.OnInvalid != nil {
+		nr.OnInvalid(lerr)
+	}
+	return nil
+}
+
+// An NDJSONResult is a line decoded by DecodeNDJSON.
+type NDJSONResult[T any] struct {
+	Line  int
+	Value T
+	Err   error
+}
+
+// DecodeNDJSON decodes the lines of nr into values of type T using workers
+// goroutines, sending the results on the returned channel in input order.
+// Invalid lines are sent with a *LineError, unless nr.SkipInvalid is set.
+// A read error is sent last. The channel is closed at the end of the stream
+// or when ctx is done.
+func DecodeNDJSON[T any](ctx context.Context, nr *NDJSONReader, workers int) <-chan NDJSONResult[T] {
+	if workers < 1 {
+		workers = 1
+	}
+
+	type job struct {
+		line int
+		data RawMessage
+		out  chan NDJSONResult[T]
+	}
+
+	jobs := make(chan job, workers)
+	order := make(chan chan NDJSONResult[T], workers*2)
+	results := make(chan NDJSONResult[T], workers)
+	var mu sync.Mutex // guards nr and its OnInvalid callback
+
+	// workers
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				var res NDJSONResult[T]
+				res.Line = j.line
+				if err := nr.unmarshal(j.data, &res.Value); err != nil {
+					res.Err = &LineError{Line: j.line, Data: j.data, Err: err}
+				}
+				j.out <- res
+			}
+		}()
+	}
+
+	// reader, dispatching lines in order
+	go func() {
+		defer close(order)
+		defer close(jobs)
+		send := func(out chan NDJSONResult[T]) bool {
+			select {
+			case order <- out:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+		for {
+			mu.Lock()
+			line, raw, err := nr.Next()
+			mu.Unlock()
+			if err == io.EOF {
+				return
+			}
+
+			out := make(chan NDJSONResult[T], 1)
+			if err != nil {
+				out <- NDJSONResult[T]{Line: line, Err: err}
+				if !send(out) {
+					return
+				}
+				if _, ok := err.(*LineError); ok {
+					continue
+				}
+				return // read error
+			}
+
+			if !send(out) {
+				return
+			}
+			select {
+			case jobs <- job{line: line, data: raw, out: out}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	// collector, forwarding results in order
+	go func() {
+		defer close(results)
+		for out := range order {
+			var res NDJSONResult[T]
+			select {
+			case res = <-out:
+			case <-ctx.Done():
+				return
+			}
+			if res.Err != nil && nr.SkipInvalid {
+				if lerr, ok := res.Err.(*LineError); ok {
+					mu.Lock()
+					if nr.OnInvalid != nil {
+						nr.OnInvalid(lerr)
+					}
+					mu.Unlock()
+					continue
+				}
+			}
+			select {
+			case results <- res:
+			case <-ctx.Done():
+				return
+			}
+		}
+		wg.Wait()
+	}()
+
+	return results
+}
+
+// An NDJSONWriter writes values as newline-delimited JSON.
+type NDJSONWriter struct {
+	w     io.Writer
+	enc   *Encoder
+	lines int
+	bytes int
+}
+
+// NewNDJSONWriter returns a new NDJSONWriter that writes to w.
+func NewNDJSONWriter(w io.Writer) *NDJSONWriter {
+	return &NDJSONWriter{w: w, enc: NewEncoder(w)}
+}
+
+// SetEscapeHTML specifies whether problematic HTML characters
+// should be escaped inside JSON quoted strings (see Encoder.SetEscapeHTML).
+func (nw *NDJSONWriter) SetEscapeHTML(on bool) {
+	nw.enc.SetEscapeHTML(on)
+}
+
+// Write writes the JSON encoding of v followed by a newline,
+// returning the number of bytes written.
+func (nw *NDJSONWriter) Write(v any) (int, error) {
+	bw, err := nw.enc.EncodeN(v)
+	nw.bytes += bw
+	if err != nil {
+		return bw, err
+	}
+	n, err := nw.w.Write([]byte{'\n'})
+	nw.bytes += n
+	if err != nil {
+		return bw + n, err
+	}
+	nw.lines++
+	return bw + n, nil
+}
+
+// Lines returns the number of lines written.
+func (nw *NDJSONWriter) Lines() int { return nw.lines }
+
+// Bytes returns the number of bytes written.
+func (nw *NDJSONWriter) Bytes() int { return nw.bytes }
@@ -0,0 +1,181 @@
+package json
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+const ndjsonTestDoc = "{\"id\": 1}\n" +
+	"\n" +
+	"{\"id\": 2}\r\n" +
+	"{\"id\": 3,}\n" +
+	"{\"id\": \"four\"}\n" +
+	"{\"id\": 5}"
+
+type ndjsonRecord struct {
+	ID int `json:"id"`
+}
+
+func TestNDJSONReader(t *testing.T) {
+	nr := NewNDJSONReader(strings.NewReader(ndjsonTestDoc))
+
+	var lines []int
+	var ids []int
+	var lerrs []*LineError
+	for {
+		var rec ndjsonRecord
+		line, err := nr.Decode(&rec)
+		if err == io.EOF {
+			break
+		}
+		var lerr *LineError
+		if errors.As(err, &lerr) {
+			lerrs = append(lerrs, lerr)
+			continue
+		} else if err != nil {
+			t.Fatalf("Decode: %v", err)
+		}
+		lines = append(lines, line)
+		ids = append(ids, rec.ID)
+	}
+
+	if want := []int{1, 3, 6}; !equalInts(lines, want) {
+		t.Errorf("lines = %v, want %v", lines, want)
+	}
+	if want := []int{1, 2, 5}; !equalInts(ids, want) {
+		t.Errorf("ids = %v, want %v", ids, want)
+	}
+	if len(lerrs) != 2 {
+		t.Fatalf("got %d line errors, want 2", len(lerrs))
+	}
+	if lerrs[0].Line != 4 || string(lerrs[0].Data) != `{"id": 3,}` {
+		t.Errorf("line error = %d %q", lerrs[0].Line, lerrs[0].Data)
+	}
+	var serr *SyntaxError
+	if !errors.As(lerrs[0], &serr) || serr.Offset != 10 {
+		t.Errorf("syntax error = %v, want offset 10", lerrs[0].Err)
+	}
+	var terr *UnmarshalTypeError
+	if lerrs[1].Line != 5 || !errors.As(lerrs[1], &terr) {
+		t.Errorf("line error = %d %v, want type error on line 5", lerrs[1].Line, lerrs[1].Err)
+	}
+}
+
+func TestNDJSONReaderSkipInvalid(t *testing.T) {
+	nr := NewNDJSONReader(strings.NewReader(ndjsonTestDoc))
+	nr.SkipInvalid = true
+	var skipped []int
+	nr.OnInvalid = func(err *LineError) { skipped = append(skipped, err.Line) }
+
+	var raws []string
+	for {
+		_, raw, err := nr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("Next: %v", err)
+		}
+		raws = append(raws, string(raw))
+	}
+	if len(raws) != 4 || raws[2] != `{"id": "four"}` {
+		t.Errorf("raws = %q", raws)
+	}
+	if want := []int{4}; !equalInts(skipped, want) {
+		t.Errorf("skipped = %v, want %v", skipped, want)
+	}
+}
+
+func TestNDJSONReaderLongLine(t *testing.T) {
+	long := `{"s": "` + strings.Repeat("x", 200000) + `"}`
+	nr := NewNDJSONReader(strings.NewReader(long + "\n" + `{"s": "y"}` + "\n"))
+	for i, want := range []int{200000, 1} {
+		var v struct{ S string }
+		line, err := nr.Decode(&v)
+		if err != nil {
+			t.Fatalf("Decode: %v", err)
+		}
+		if line != i+1 || len(v.S) != want {
+			t.Errorf("line %d: len = %d, want %d", line, len(v.S), want)
+		}
+	}
+	if _, err := nr.Decode(new(any)); err != io.EOF {
+		t.Errorf("err = %v, want io.EOF", err)
+	}
+}
+
+func TestDecodeNDJSON(t *testing.T) {
+	var buf bytes.Buffer
+	for i := 1; i <= 500; i++ {
+		if i == 100 {
+			buf.WriteString("not json\n")
+		}
+		buf.WriteString(`{"id": ` + itoa(i) + "}\n")
+	}
+
+	nr := NewNDJSONReader(&buf)
+	next := 1
+	var errs int
+	for res := range DecodeNDJSON[ndjsonRecord](context.Background(), nr, 4) {
+		if res.Err != nil {
+			errs++
+			if res.Line != 100 {
+				t.Errorf("error on line %d, want 100", res.Line)
+			}
+			continue
+		}
+		if res.Value.ID != next {
+			t.Fatalf("got id %d, want %d (out of order)", res.Value.ID, next)
+		}
+		next++
+	}
+	if next != 501 || errs != 1 {
+		t.Errorf("got %d values and %d errors", next-1, errs)
+	}
+}
+
+func TestNDJSONWriter(t *testing.T) {
+	var buf bytes.Buffer
+	nw := NewNDJSONWriter(&buf)
+	n1, err := nw.Write(map[string]int{"a": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	n2, _ := nw.Write([]string{"<b>"})
+	want := "{\"a\":1}\n[\"\\u003cb\\u003e\"]\n"
+	if buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+	if nw.Lines() != 2 || nw.Bytes() != len(want) || n1+n2 != len(want) {
+		t.Errorf("lines = %d, bytes = %d (%d+%d), want 2, %d", nw.Lines(), nw.Bytes(), n1, n2, len(want))
+	}
+	if _, err := nw.Write(make(chan int)); err == nil {
+		t.Error("expected error for unsupported type")
+	}
+	if nw.Lines() != 2 {
+		t.Errorf("lines = %d after error, want 2", nw.Lines())
+	}
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func itoa(i int) string {
+	var b []byte
+	for ; i > 0; i /= 10 {
+		b = append([]byte{byte('0' + i%10)}, b...)
+	}
+	return string(b)
+}
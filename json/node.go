@@ -0,0 +1,285 @@
+package json
+
+import (
+	"errors"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+type nodeKind int
+
+const (
+	nullNode nodeKind = iota
+	boolNode
+	numberNode
+	stringNode
+	arrayNode
+	objectNode
+)
+
+// A jsonNode is a parsed JSON value which keeps the order of object
+// members and the input offset of each value.
+type jsonNode struct {
+	kind   nodeKind
+	offset int64
+
+	bool  bool
+	str   string   // value of a string, literal of a number
+	num   *big.Rat // value of a number
+	elems []*jsonNode
+	keys  []string
+	vals  []*jsonNode
+}
+
+// maxNodeExponent bounds the exponent of numbers, as exact values of
+// numbers such as 1e1000000000 cannot reasonably be computed.
+const maxNodeExponent = 10000
+
+// parseNode parses data, whose first byte is at input offset base.
+func parseNode(data []byte, base int64) (*jsonNode, error) {
+	var scan scanner
+	if err := checkValid(data, &scan); err != nil {
+		return nil, err
+	}
+	p := nodeParser{data: data, base: base}
+	n := p.value()
+	return n, p.err
+}
+
+type nodeParser struct {
+	data []byte
+	pos  int
+	base int64
+	err  error
+}
+
+func (p *nodeParser) skipSpace() {
+	for p.pos < len(p.data) && isSpace(p.data[p.pos]) {
+		p.pos++
+	}
+}
+
+// value parses the value at p.pos, which must be valid JSON.
+func (p *nodeParser) value() *jsonNode {
+	p.skipSpace()
+	n := &jsonNode{offset: p.base + int64(p.pos)}
+	switch c := p.data[p.pos]; c {
+	case '{':
+		n.kind = objectNode
+		p.pos++
+		for {
+			p.skipSpace()
+			if p.data[p.pos] == '}' {
+				p.pos++
+				break
+			}
+			if p.data[p.pos] == ',' {
+				p.pos++
+				p.skipSpace()
+			}
+			n.keys = append(n.keys, p.string())
+			p.skipSpace()
+			p.pos++ // ':'
+			n.vals = append(n.vals, p.value())
+		}
+	case '[':
+		n.kind = arrayNode
+		p.pos++
+		for {
+			p.skipSpace()
+			if p.data[p.pos] == ']' {
+				p.pos++
+				break
+			}
+			if p.data[p.pos] == ',' {
+				p.pos++
+			}
+			n.elems = append(n.elems, p.value())
+		}
+	case '"':
+		n.kind = stringNode
+		n.str = p.string()
+	case 't':
+		n.kind = boolNode
+		n.bool = true
+		p.pos += len("true")
+	case 'f':
+		n.kind = boolNode
+		p.pos += len("false")
+	case 'n':
+		n.kind = nullNode
+		p.pos += len("null")
+	default:
+		n.kind = numberNode
+		start := p.pos
+		for p.pos < len(p.data) && strings.IndexByte("+-.eE0123456789", p.data[p.pos]) >= 0 {
+			p.pos++
+		}
+		n.str = string(p.data[start:p.pos])
+		if i := strings.IndexAny(n.str, "eE"); i >= 0 {
+			if exp, err := strconv.Atoi(strings.TrimPrefix(n.str[i+1:], "+")); err != nil || exp > maxNodeExponent || exp < -maxNodeExponent {
+				if p.err == nil {
+					p.err = errors.New("json: number exponent out of range: " + n.str)
+				}
+				n.num = new(big.Rat)
+				break
+			}
+		}
+		n.num, _ = new(big.Rat).SetString(n.str)
+	}
+	return n
+}
+
+// string parses the quoted string at p.pos.
+func (p *nodeParser) string() string {
+	start := p.pos
+	p.pos++
+	for p.data[p.pos] != '"' {
+		if p.data[p.pos] == '\\' {
+			p.pos++
+		}
+		p.pos++
+	}
+	p.pos++
+	s, _ := unquote(p.data[start:p.pos])
+	return s
+}
+
+// member returns the value of the object member key, nil if absent.
+// With duplicate keys, the last one wins, as with Unmarshal.
+func (n *jsonNode) member(key string) *jsonNode {
+	for i := len(n.keys) - 1; i >= 0; i-- {
+		if n.keys[i] == key {
+			return n.vals[i]
+		}
+	}
+	return nil
+}
+
+// pointer returns the value at the JSON Pointer ptr, nil if absent.
+func (n *jsonNode) pointer(ptr string) *jsonNode {
+	if ptr == "" {
+		return n
+	}
+	if ptr[0] != '/' {
+		return nil
+	}
+	for _, token := range strings.Split(ptr[1:], "/") {
+		token = unescapePointer(token)
+		switch n.kind {
+		case objectNode:
+			n = n.member(token)
+		case arrayNode:
+			i, err := strconv.Atoi(token)
+			if err != nil || i < 0 || i >= len(n.elems) {
+				return nil
+			}
+			n = n.elems[i]
+		default:
+			return nil
+		}
+		if n == nil {
+			return nil
+		}
+	}
+	return n
+}
+
+// typeName returns the JSON Schema type name of n.
+func (n *jsonNode) typeName() string {
+	switch n.kind {
+	case boolNode:
+		return "boolean"
+	case numberNode:
+		if n.num.IsInt() {
+			return "integer"
+		}
+		return "number"
+	case stringNode:
+		return "string"
+	case arrayNode:
+		return "array"
+	case objectNode:
+		return "object"
+	}
+	return "null"
+}
+
+// hasType reports whether n is of the JSON Schema type t.
+func (n *jsonNode) hasType(t string) bool {
+	if t == "number" {
+		return n.kind == numberNode
+	}
+	return n.typeName() == t
+}
+
+// nodeEqual reports whether a and b are equal JSON values. Numbers are
+// equal if their values are, and objects regardless of member order.
+func nodeEqual(a, b *jsonNode) bool {
+	if a.kind != b.kind {
+		return false
+	}
+	switch a.kind {
+	case boolNode:
+		return a.bool == b.bool
+	case numberNode:
+		return a.num.Cmp(b.num) == 0
+	case stringNode:
+		return a.str == b.str
+	case arrayNode:
+		if len(a.elems) != len(b.elems) {
+			return false
+		}
+		for i := range a.elems {
+			if !nodeEqual(a.elems[i], b.elems[i]) {
+				return false
+			}
+		}
+	case objectNode:
+		if len(a.keys) != len(b.keys) {
+			return false
+		}
+		for i, key := range a.keys {
+			bv := b.member(key)
+			if bv == nil || !nodeEqual(a.vals[i], bv) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// encode writes n in compact form.
+func (n *jsonNode) encode(e *encodeState) {
+	switch n.kind {
+	case nullNode:
+		e.WriteString("null")
+	case boolNode:
+		e.WriteString(strconv.FormatBool(n.bool))
+	case numberNode:
+		e.WriteString(n.str)
+	case stringNode:
+		e.string(n.str, true)
+	case arrayNode:
+		e.WriteByte('[')
+		for i, elem := range n.elems {
+			if i > 0 {
+				e.WriteByte(',')
+			}
+			elem.encode(e)
+		}
+		e.WriteByte(']')
+	case objectNode:
+		e.WriteByte('{')
+		for i, key := range n.keys {
+			if i > 0 {
+				e.WriteByte(',')
+			}
+			e.string(key, true)
+			e.WriteByte(':')
+			n.vals[i].encode(e)
+		}
+		e.WriteByte('}')
+	}
+}
@@ -0,0 +1,105 @@
+package json
+
+import "reflect"
+
+// An OrderedMap is a JSON object which preserves the order of its keys.
+// Keys are kept in insertion order; setting an existing key keeps its
+// position. The zero value is an empty map ready to use.
+//
+// An OrderedMap marshals with its keys in order, and is what a Decoder
+// produces for objects decoded into an interface{} when UseOrderedObjects
+// is set.
+type OrderedMap struct {
+	keys   []string
+	values map[string]any
+}
+
+// NewOrderedMap returns an empty OrderedMap.
+func NewOrderedMap() *OrderedMap {
+	return &OrderedMap{values: map[string]any{}}
+}
+
+// Get returns the value of key, and whether it is present.
+func (om *OrderedMap) Get(key string) (any, bool) {
+	val, ok := om.values[key]
+	return val, ok
+}
+
+// Set sets the value of key. A new key is added last.
+func (om *OrderedMap) Set(key string, val any) {
+	if om.values == nil {
+		om.values = map[string]any{}
+	}
+	if _, ok := om.values[key]; !ok {
+		om.keys = append(om.keys, key)
+	}
+	om.values[key] = val
+}
+
+// Delete removes key, returning whether it was present.
+func (om *OrderedMap) Delete(key string) bool {
+	if _, ok := om.values[key]; !ok {
+		return false
+	}
+	delete(om.values, key)
+	for i, k := range om.keys {
+		if k == key {
+			om.keys = append(om.keys[:i], om.keys[i+1:]...)
+			break
+		}
+	}
+	return true
+}
+
+// Keys returns the keys in order. The returned slice must not be modified.
+func (om *OrderedMap) Keys() []string {
+	return om.keys
+}
+
+// Len returns the number of keys.
+func (om *OrderedMap) Len() int {
+	return len(om.keys)
+}
+
+// MarshalJSON implements the Marshaler interface, writing the keys in order.
+func (om OrderedMap) MarshalJSON() ([]byte, error) {
+	return Marshal(om)
+}
+
+var orderedMapType = reflect.TypeOf(OrderedMap{})
+
+// orderedMapEncoder encodes an OrderedMap directly, rather than through
+// MarshalJSON, so that the values are encoded with the same options.
+func orderedMapEncoder(e *encodeState, v reflect.Value, opts encOpts) {
+	om := v.Interface().(OrderedMap)
+	e.WriteByte('{')
+	for i, key := range om.keys {
+		if i > 0 {
+			e.WriteByte(',')
+		}
+		e.string(key, opts.escapeHTML)
+		e.WriteByte(':')
+		e.reflectValue(reflect.ValueOf(om.values[key]), opts)
+	}
+	e.WriteByte('}')
+}
+
+// UnmarshalJSON implements the Unmarshaler interface. Nested objects
+// are decoded as *OrderedMap as well. Decoding null leaves om unchanged.
+func (om *OrderedMap) UnmarshalJSON(data []byte) error {
+	var d decodeState
+	d.useOrderedObjects = true
+	d.init(data)
+	var v any
+	if err := d.unmarshal(&v); err != nil {
+		return err
+	}
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case *OrderedMap:
+		*om = *v
+		return nil
+	}
+	return &UnmarshalTypeError{Value: "non-object", Type: reflect.TypeOf(om), Offset: 0}
+}
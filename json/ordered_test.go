@@ -0,0 +1,106 @@
+package json
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestOrderedMap(t *testing.T) {
+	var om OrderedMap
+	om.Set("b", 1)
+	om.Set("a", 2)
+	om.Set("c", 3)
+	om.Set("b", 4)
+	if got, want := om.Keys(), []string{"b", "a", "c"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Keys = %v, want %v", got, want)
+	}
+	if v, ok := om.Get("b"); !ok || v != 4 {
+		t.Errorf("Get(b) = %v, %v", v, ok)
+	}
+	if !om.Delete("a") || om.Delete("a") {
+		t.Error("Delete(a) should succeed once")
+	}
+	if _, ok := om.Get("a"); ok || om.Len() != 2 {
+		t.Errorf("a still present after Delete, Len = %d", om.Len())
+	}
+
+	b, err := Marshal(struct {
+		M OrderedMap  `json:"m"`
+		P *OrderedMap `json:"p"`
+	}{M: om})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(b), `{"m":{"b":4,"c":3},"p":null}`; got != want {
+		t.Errorf("Marshal = %s, want %s", got, want)
+	}
+}
+
+func TestUseOrderedObjects(t *testing.T) {
+	const input = `{"z": 1, "y": {"k2": [{"q": 1, "p": 2}], "k1": null}, "x": "<s>"}`
+	dec := NewDecoder(strings.NewReader(input))
+	dec.UseOrderedObjects()
+
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+	om, ok := v.(*OrderedMap)
+	if !ok {
+		t.Fatalf("decoded %T, want *OrderedMap", v)
+	}
+	if got, want := om.Keys(), []string{"z", "y", "x"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Keys = %v, want %v", got, want)
+	}
+	y, _ := om.Get("y")
+	if got, want := y.(*OrderedMap).Keys(), []string{"k2", "k1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("nested Keys = %v, want %v", got, want)
+	}
+	k2, _ := y.(*OrderedMap).Get("k2")
+	if _, ok := k2.([]any)[0].(*OrderedMap); !ok {
+		t.Errorf("object in array decoded as %T", k2.([]any)[0])
+	}
+
+	b, err := Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(b), `{"z":1,"y":{"k2":[{"q":1,"p":2}],"k1":null},"x":"\u003cs\u003e"}`; got != want {
+		t.Errorf("round trip = %s, want %s", got, want)
+	}
+
+	// without the option, objects decode as maps
+	var m any
+	if err := Unmarshal([]byte(input), &m); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := m.(map[string]any); !ok {
+		t.Errorf("decoded %T, want map[string]interface{}", m)
+	}
+}
+
+func TestOrderedMapUnmarshal(t *testing.T) {
+	var s struct {
+		M *OrderedMap
+		N OrderedMap
+	}
+	if err := Unmarshal([]byte(`{"M": {"b": {"d": 1, "c": 2}, "a": 1}, "N": {"y": 1, "x": 2}}`), &s); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := s.M.Keys(), []string{"b", "a"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Keys = %v, want %v", got, want)
+	}
+	b, _ := s.M.Get("b")
+	if got, want := b.(*OrderedMap).Keys(), []string{"d", "c"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("nested Keys = %v, want %v", got, want)
+	}
+	if got, want := s.N.Keys(), []string{"y", "x"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Keys = %v, want %v", got, want)
+	}
+
+	var om OrderedMap
+	if err := Unmarshal([]byte(`[1]`), &om); err == nil {
+		t.Error("expected error unmarshaling an array")
+	}
+}
@@ -0,0 +1,660 @@
+package json
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// A PatchOp is an operation of a JSON Patch (RFC 6902).
+type PatchOp struct {
+	Op    string `json:"op"` // add, remove, replace, move, copy or test
+	Path  string `json:"path"`
+	From  string `json:"from,omitempty"` // for move and copy
+	Value any    `json:"value,omitempty"`
+}
+
+// MarshalJSON implements the Marshaler interface. The value is written
+// for the add, replace and test operations, even when it is null.
+func (op PatchOp) MarshalJSON() ([]byte, error) {
+	m := NewOrderedMap()
+	m.Set("op", op.Op)
+	if op.From != "" || op.Op == "move" || op.Op == "copy" {
+		m.Set("from", op.From)
+	}
+	m.Set("path", op.Path)
+	switch op.Op {
+	case "add", "replace", "test":
+		m.Set("value", op.Value)
+	}
+	return Marshal(m)
+}
+
+// A Patch is a JSON Patch document (RFC 6902), a list of operations.
+//
+// Documents are interface{} trees as produced by Unmarshal: nil, bool,
+// float64 or Number, string, []interface{}, map[string]interface{} and
+// *OrderedMap. Other values are converted to trees through their JSON
+// encoding.
+type Patch []PatchOp
+
+// DecodePatch parses a JSON Patch document.
+func DecodePatch(data []byte) (Patch, error) {
+	dec := NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var p Patch
+	if err := dec.Decode(&p); err != nil {
+		return nil, err
+	}
+	for i, op := range p {
+		switch op.Op {
+		case "add", "remove", "replace", "move", "copy", "test":
+		default:
+			return nil, errors.New("json: invalid patch operation " + strconv.Itoa(i) + ": unknown op " + strconv.Quote(op.Op))
+		}
+	}
+	return p, nil
+}
+
+// Apply applies the patch to a copy of doc and returns it, or an error
+// if one of the operations fails. doc itself is not modified.
+func (p Patch) Apply(doc any) (any, error) {
+	doc, err := toTree(doc)
+	if err != nil {
+		return nil, err
+	}
+	for i, op := range p {
+		if doc, err = applyOp(doc, op); err != nil {
+			return nil, errors.New("json: patch operation " + strconv.Itoa(i) + " (" + op.Op + " " + op.Path + "): " + err.Error())
+		}
+	}
+	return doc, nil
+}
+
+// ApplyRaw is like Apply with an encoded document. The order of object
+// members and the literals of numbers are preserved.
+func (p Patch) ApplyRaw(doc RawMessage) (RawMessage, error) {
+	tree, err := decodeTree(doc)
+	if err != nil {
+		return nil, err
+	}
+	if tree, err = p.Apply(tree); err != nil {
+		return nil, err
+	}
+	return Marshal(tree)
+}
+
+func applyOp(doc any, op PatchOp) (any, error) {
+	path, err := parsePointer(op.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch op.Op {
+	case "add", "replace":
+		val, err := toTree(op.Value)
+		if err != nil {
+			return nil, err
+		}
+		if op.Op == "add" {
+			return treeAdd(doc, path, val)
+		}
+		return treeReplace(doc, path, val)
+	case "remove":
+		return treeRemove(doc, path)
+	case "move", "copy":
+		from, err := parsePointer(op.From)
+		if err != nil {
+			return nil, err
+		}
+		val, err := treeGet(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		if op.Op == "copy" {
+			val, _ = toTree(val)
+		} else {
+			if strings.HasPrefix(op.Path, op.From+"/") {
+				return nil, errors.New("cannot move a value into one of its children")
+			}
+			if doc, err = treeRemove(doc, from); err != nil {
+				return nil, err
+			}
+		}
+		return treeAdd(doc, path, val)
+	case "test":
+		val, err := treeGet(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		want, err := toTree(op.Value)
+		if err != nil {
+			return nil, err
+		}
+		if !treeEqual(val, want) {
+			return nil, errors.New("test failed")
+		}
+		return doc, nil
+	}
+	return nil, errors.New("unknown op " + strconv.Quote(op.Op))
+}
+
+// parsePointer splits a JSON Pointer into its unescaped reference tokens.
+func parsePointer(ptr string) ([]string, error) {
+	if ptr == "" {
+		return nil, nil
+	}
+	if ptr[0] != '/' {
+		return nil, errors.New("invalid JSON Pointer " + strconv.Quote(ptr))
+	}
+	tokens := strings.Split(ptr[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = unescapePointer(token)
+	}
+	return tokens, nil
+}
+
+// arrayIndex parses the token of an array index, less than or equal to n.
+func arrayIndex(token string, n int) (int, error) {
+	i, err := strconv.Atoi(token)
+	if err != nil || i < 0 || (len(token) > 1 && token[0] == '0') {
+		return 0, errors.New("invalid array index " + strconv.Quote(token))
+	}
+	if i > n {
+		return 0, errors.New("array index " + token + " out of range")
+	}
+	return i, nil
+}
+
+// treeGet returns the value at path.
+func treeGet(doc any, path []string) (any, error) {
+	for _, token := range path {
+		switch node := doc.(type) {
+		case map[string]any:
+			val, ok := node[token]
+			if !ok {
+				return nil, errors.New("member " + strconv.Quote(token) + " not found")
+			}
+			doc = val
+		case *OrderedMap:
+			val, ok := node.Get(token)
+			if !ok {
+				return nil, errors.New("member " + strconv.Quote(token) + " not found")
+			}
+			doc = val
+		case []any:
+			i, err := arrayIndex(token, len(node)-1)
+			if err != nil {
+				return nil, err
+			}
+			doc = node[i]
+		default:
+			return nil, errors.New("cannot index a scalar value with " + strconv.Quote(token))
+		}
+	}
+	return doc, nil
+}
+
+// treeModify applies fn to the container holding the last token of path,
+// returning the new document. fn returns the new container, which differs
+// from the original for arrays.
+func treeModify(doc any, path []string, fn func(container any, token string) (any, error)) (any, error) {
+	if len(path) == 1 {
+		return fn(doc, path[0])
+	}
+	child, err := treeGet(doc, path[:1])
+	if err != nil {
+		return nil, err
+	}
+	if child, err = treeModify(child, path[1:], fn); err != nil {
+		return nil, err
+	}
+	switch node := doc.(type) {
+	case map[string]any:
+		node[path[0]] = child
+	case *OrderedMap:
+		node.Set(path[0], child)
+	case []any:
+		i, _ := arrayIndex(path[0], len(node)-1)
+		node[i] = child
+	}
+	return doc, nil
+}
+
+func treeAdd(doc any, path []string, val any) (any, error) {
+	if len(path) == 0 {
+		return val, nil
+	}
+	return treeModify(doc, path, func(container any, token string) (any, error) {
+		switch node := container.(type) {
+		case map[string]any:
+			node[token] = val
+		case *OrderedMap:
+			node.Set(token, val)
+		case []any:
+			if token == "-" {
+				return append(node, val), nil
+			}
+			i, err := arrayIndex(token, len(node))
+			if err != nil {
+				return nil, err
+			}
+			node = append(node, nil)
+			copy(node[i+1:], node[i:])
+			node[i] = val
+			return node, nil
+		default:
+			return nil, errors.New("cannot add a member to a scalar value")
+		}
+		return container, nil
+	})
+}
+
+func treeRemove(doc any, path []string) (any, error) {
+	if len(path) == 0 {
+		return nil, errors.New("cannot remove the root value")
+	}
+	return treeModify(doc, path, func(container any, token string) (any, error) {
+		if _, err := treeGet(container, []string{token}); err != nil {
+			return nil, err
+		}
+		switch node := container.(type) {
+		case map[string]any:
+			delete(node, token)
+		case *OrderedMap:
+			node.Delete(token)
+		case []any:
+			i, _ := arrayIndex(token, len(node)-1)
+			return append(node[:i], node[i+1:]...), nil
+		}
+		return container, nil
+	})
+}
+
+func treeReplace(doc any, path []string, val any) (any, error) {
+	if len(path) == 0 {
+		return val, nil
+	}
+	return treeModify(doc, path, func(container any, token string) (any, error) {
+		if _, err := treeGet(container, []string{token}); err != nil {
+			return nil, err
+		}
+		switch node := container.(type) {
+		case map[string]any:
+			node[token] = val
+		case *OrderedMap:
+			node.Set(token, val)
+		case []any:
+			i, _ := arrayIndex(token, len(node)-1)
+			node[i] = val
+		}
+		return container, nil
+	})
+}
+
+// CreatePatch returns a patch transforming from into to. Arrays are
+// compared by index.
+func CreatePatch(from, to any) (Patch, error) {
+	a, err := toTree(from)
+	if err != nil {
+		return nil, err
+	}
+	b, err := toTree(to)
+	if err != nil {
+		return nil, err
+	}
+	p := Patch{}
+	diffPatch(&p, "", a, b)
+	return p, nil
+}
+
+// CreatePatchRaw is like CreatePatch with encoded documents.
+func CreatePatchRaw(from, to RawMessage) (Patch, error) {
+	a, err := decodeTree(from)
+	if err != nil {
+		return nil, err
+	}
+	b, err := decodeTree(to)
+	if err != nil {
+		return nil, err
+	}
+	p := Patch{}
+	diffPatch(&p, "", a, b)
+	return p, nil
+}
+
+func diffPatch(p *Patch, path string, a, b any) {
+	if treeEqual(a, b) {
+		return
+	}
+
+	aKeys, aObj := treeKeys(a)
+	bKeys, bObj := treeKeys(b)
+	if aObj && bObj {
+		for _, key := range aKeys {
+			if _, ok := treeMember(b, key); !ok {
+				*p = append(*p, PatchOp{Op: "remove", Path: path + "/" + escapePointer(key)})
+			}
+		}
+		for _, key := range bKeys {
+			bv, _ := treeMember(b, key)
+			if av, ok := treeMember(a, key); ok {
+				diffPatch(p, path+"/"+escapePointer(key), av, bv)
+			} else {
+				*p = append(*p, PatchOp{Op: "add", Path: path + "/" + escapePointer(key), Value: bv})
+			}
+		}
+		return
+	}
+
+	aArr, aIsArr := a.([]any)
+	bArr, bIsArr := b.([]any)
+	if aIsArr && bIsArr {
+		common := len(aArr)
+		if len(bArr) < common {
+			common = len(bArr)
+		}
+		for i := 0; i < common; i++ {
+			diffPatch(p, path+"/"+strconv.Itoa(i), aArr[i], bArr[i])
+		}
+		for i := len(aArr) - 1; i >= common; i-- {
+			*p = append(*p, PatchOp{Op: "remove", Path: path + "/" + strconv.Itoa(i)})
+		}
+		for i := common; i < len(bArr); i++ {
+			*p = append(*p, PatchOp{Op: "add", Path: path + "/" + strconv.Itoa(i), Value: bArr[i]})
+		}
+		return
+	}
+
+	*p = append(*p, PatchOp{Op: "replace", Path: path, Value: b})
+}
+
+// MergePatch applies a JSON Merge Patch (RFC 7396) to a copy of doc and
+// returns it.
+func MergePatch(doc, patch any) (any, error) {
+	d, err := toTree(doc)
+	if err != nil {
+		return nil, err
+	}
+	m, err := toTree(patch)
+	if err != nil {
+		return nil, err
+	}
+	return mergePatch(d, m), nil
+}
+
+// MergePatchRaw is like MergePatch with encoded documents. The order of
+// object members and the literals of numbers are preserved.
+func MergePatchRaw(doc, patch RawMessage) (RawMessage, error) {
+	d, err := decodeTree(doc)
+	if err != nil {
+		return nil, err
+	}
+	m, err := decodeTree(patch)
+	if err != nil {
+		return nil, err
+	}
+	return Marshal(mergePatch(d, m))
+}
+
+func mergePatch(target, patch any) any {
+	keys, ok := treeKeys(patch)
+	if !ok {
+		return patch
+	}
+	if _, ok := treeKeys(target); !ok {
+		if _, ordered := patch.(*OrderedMap); ordered {
+			target = NewOrderedMap()
+		} else {
+			target = map[string]any{}
+		}
+	}
+	for _, key := range keys {
+		val, _ := treeMember(patch, key)
+		if val == nil {
+			treeDelete(target, key)
+			continue
+		}
+		cur, _ := treeMember(target, key)
+		treeSet(target, key, mergePatch(cur, val))
+	}
+	return target
+}
+
+// CreateMergePatch returns a merge patch transforming from into to.
+// As merge patches cannot set null values, members set to null in to
+// are removed instead.
+func CreateMergePatch(from, to any) (any, error) {
+	a, err := toTree(from)
+	if err != nil {
+		return nil, err
+	}
+	b, err := toTree(to)
+	if err != nil {
+		return nil, err
+	}
+	return createMergePatch(a, b), nil
+}
+
+// CreateMergePatchRaw is like CreateMergePatch with encoded documents.
+func CreateMergePatchRaw(from, to RawMessage) (RawMessage, error) {
+	a, err := decodeTree(from)
+	if err != nil {
+		return nil, err
+	}
+	b, err := decodeTree(to)
+	if err != nil {
+		return nil, err
+	}
+	return Marshal(createMergePatch(a, b))
+}
+
+func createMergePatch(a, b any) any {
+	aKeys, aObj := treeKeys(a)
+	bKeys, bObj := treeKeys(b)
+	if !aObj || !bObj {
+		return b
+	}
+
+	patch := NewOrderedMap()
+	for _, key := range aKeys {
+		if _, ok := treeMember(b, key); !ok {
+			patch.Set(key, nil)
+		}
+	}
+	for _, key := range bKeys {
+		bv, _ := treeMember(b, key)
+		av, ok := treeMember(a, key)
+		switch {
+		case !ok:
+			patch.Set(key, bv)
+		case !treeEqual(av, bv):
+			patch.Set(key, createMergePatch(av, bv))
+		}
+	}
+	return patch
+}
+
+// decodeTree decodes data into a tree, keeping the order of members and
+// the literals of numbers.
+func decodeTree(data []byte) (any, error) {
+	dec := NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	dec.UseOrderedObjects()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// toTree returns a deep copy of v as an interface{} tree. Values other
+// than those produced by Unmarshal are converted through their encoding.
+func toTree(v any) (any, error) {
+	switch v := v.(type) {
+	case nil, bool, float64, Number, string:
+		return v, nil
+	case []any:
+		out := make([]any, len(v))
+		for i, elem := range v {
+			var err error
+			if out[i], err = toTree(elem); err != nil {
+				return nil, err
+			}
+		}
+		return out, nil
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for key, val := range v {
+			var err error
+			if out[key], err = toTree(val); err != nil {
+				return nil, err
+			}
+		}
+		return out, nil
+	case *OrderedMap:
+		out := NewOrderedMap()
+		for _, key := range v.Keys() {
+			val, _ := v.Get(key)
+			val, err := toTree(val)
+			if err != nil {
+				return nil, err
+			}
+			out.Set(key, val)
+		}
+		return out, nil
+	case RawMessage:
+		return decodeTree(v)
+	}
+	data, err := Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return decodeTree(data)
+}
+
+// treeKeys returns the keys of an object, in order (sorted for a map),
+// and whether v is an object.
+func treeKeys(v any) ([]string, bool) {
+	switch v := v.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		return keys, true
+	case *OrderedMap:
+		return v.Keys(), true
+	}
+	return nil, false
+}
+
+func treeMember(v any, key string) (any, bool) {
+	switch v := v.(type) {
+	case map[string]any:
+		val, ok := v[key]
+		return val, ok
+	case *OrderedMap:
+		return v.Get(key)
+	}
+	return nil, false
+}
+
+func treeSet(v any, key string, val any) {
+	switch v := v.(type) {
+	case map[string]any:
+		v[key] = val
+	case *OrderedMap:
+		v.Set(key, val)
+	}
+}
+
+func treeDelete(v any, key string) {
+	switch v := v.(type) {
+	case map[string]any:
+		delete(v, key)
+	case *OrderedMap:
+		v.Delete(key)
+	}
+}
+
+// treeEqual reports whether two trees are equal. Numbers are equal if
+// their values are, and objects regardless of member order.
+func treeEqual(a, b any) bool {
+	if _, ok := a.(float64); ok {
+		return treeNumberEqual(a, b)
+	}
+	if _, ok := a.(Number); ok {
+		return treeNumberEqual(a, b)
+	}
+
+	aKeys, aObj := treeKeys(a)
+	bKeys, bObj := treeKeys(b)
+	if aObj || bObj {
+		if !aObj || !bObj || len(aKeys) != len(bKeys) {
+			return false
+		}
+		for _, key := range aKeys {
+			av, _ := treeMember(a, key)
+			bv, ok := treeMember(b, key)
+			if !ok || !treeEqual(av, bv) {
+				return false
+			}
+		}
+		return true
+	}
+
+	switch a := a.(type) {
+	case []any:
+		b, ok := b.([]any)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if !treeEqual(a[i], b[i]) {
+				return false
+			}
+		}
+		return true
+	case nil, bool, string:
+		return a == b
+	}
+	return false
+}
+
+// treeNumberEqual reports whether a and b are equal numbers. A float64 is
+// compared as a float64, while two Numbers are compared exactly.
+func treeNumberEqual(a, b any) bool {
+	na, aNum := a.(Number)
+	nb, bNum := b.(Number)
+	if aNum && bNum {
+		if na == nb {
+			return true
+		}
+		fa, _, errA := big.ParseFloat(string(na), 10, 512, big.ToNearestEven)
+		fb, _, errB := big.ParseFloat(string(nb), 10, 512, big.ToNearestEven)
+		return errA == nil && errB == nil && fa.Cmp(fb) == 0
+	}
+
+	fa, ok := treeFloat(a)
+	if !ok {
+		return false
+	}
+	fb, ok := treeFloat(b)
+	return ok && fa == fb
+}
+
+func treeFloat(v any) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case Number:
+		f, err := v.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
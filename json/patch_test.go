@@ -0,0 +1,187 @@
+package json
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPatchApply(t *testing.T) {
+	tests := []struct {
+		doc, patch, want string
+		err              bool
+	}{
+		// from RFC 6902, appendix A
+		{doc: `{"foo": "bar"}`, patch: `[{"op": "add", "path": "/baz", "value": "qux"}]`, want: `{"foo":"bar","baz":"qux"}`},
+		{doc: `{"foo": ["bar", "baz"]}`, patch: `[{"op": "add", "path": "/foo/1", "value": "qux"}]`, want: `{"foo":["bar","qux","baz"]}`},
+		{doc: `{"baz": "qux", "foo": "bar"}`, patch: `[{"op": "remove", "path": "/baz"}]`, want: `{"foo":"bar"}`},
+		{doc: `{"foo": ["bar", "qux", "baz"]}`, patch: `[{"op": "remove", "path": "/foo/1"}]`, want: `{"foo":["bar","baz"]}`},
+		{doc: `{"baz": "qux", "foo": "bar"}`, patch: `[{"op": "replace", "path": "/baz", "value": "boo"}]`, want: `{"baz":"boo","foo":"bar"}`},
+		{doc: `{"foo": {"bar": "baz", "waldo": "fred"}, "qux": {"corge": "grault"}}`, patch: `[{"op": "move", "from": "/foo/waldo", "path": "/qux/thud"}]`, want: `{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`},
+		{doc: `{"foo": ["all", "grass", "cows", "eat"]}`, patch: `[{"op": "move", "from": "/foo/1", "path": "/foo/3"}]`, want: `{"foo":["all","cows","eat","grass"]}`},
+		{doc: `{"baz": "qux", "foo": ["a", 2, "c"]}`, patch: `[{"op": "test", "path": "/baz", "value": "qux"}, {"op": "test", "path": "/foo/1", "value": 2.0}]`, want: `{"baz":"qux","foo":["a",2,"c"]}`},
+		{doc: `{"baz": "qux"}`, patch: `[{"op": "test", "path": "/baz", "value": "bar"}]`, err: true},
+		{doc: `{"foo": "bar"}`, patch: `[{"op": "add", "path": "/child", "value": {"grandchild": {}}}]`, want: `{"foo":"bar","child":{"grandchild":{}}}`},
+		{doc: `{"foo": "bar"}`, patch: `[{"op": "add", "path": "/baz/bat", "value": "qux"}]`, err: true},
+		{doc: `{"/": 9, "~1": 10}`, patch: `[{"op": "test", "path": "/~01", "value": 10}]`, want: `{"/":9,"~1":10}`},
+		{doc: `{"foo": ["bar"]}`, patch: `[{"op": "add", "path": "/foo/-", "value": ["abc", "def"]}]`, want: `{"foo":["bar",["abc","def"]]}`},
+		{doc: `{"foo": null}`, patch: `[{"op": "copy", "from": "/foo", "path": "/bar"}, {"op": "test", "path": "/bar", "value": null}]`, want: `{"foo":null,"bar":null}`},
+		{doc: `{"foo": ["bar"]}`, patch: `[{"op": "add", "path": "/foo/2", "value": 1}]`, err: true},
+		{doc: `{"foo": ["bar"]}`, patch: `[{"op": "remove", "path": "/foo/01"}]`, err: true},
+		{doc: `{"foo": {"a": 1}}`, patch: `[{"op": "move", "from": "/foo", "path": "/foo/b"}]`, err: true},
+		{doc: `{"foo": 1}`, patch: `[{"op": "replace", "path": "", "value": [1]}]`, want: `[1]`},
+	}
+
+	for _, tt := range tests {
+		p, err := DecodePatch([]byte(tt.patch))
+		if err != nil {
+			t.Errorf("DecodePatch(%s): %v", tt.patch, err)
+			continue
+		}
+		got, err := p.ApplyRaw(RawMessage(tt.doc))
+		switch {
+		case tt.err && err == nil:
+			t.Errorf("%s on %s: expected error, got %s", tt.patch, tt.doc, got)
+		case !tt.err && err != nil:
+			t.Errorf("%s on %s: %v", tt.patch, tt.doc, err)
+		case !tt.err && string(got) != tt.want:
+			t.Errorf("%s on %s:\ngot  %s\nwant %s", tt.patch, tt.doc, got, tt.want)
+		}
+	}
+
+	if _, err := DecodePatch([]byte(`[{"op": "nope", "path": ""}]`)); err == nil {
+		t.Error("expected error for unknown op")
+	}
+}
+
+func TestPatchApplyTree(t *testing.T) {
+	doc := map[string]any{"a": []any{1.0, 2.0}, "b": map[string]any{"c": "d"}}
+	p := Patch{
+		{Op: "add", Path: "/a/0", Value: 0},
+		{Op: "remove", Path: "/b/c"},
+		{Op: "add", Path: "/e", Value: struct{ X int }{1}},
+	}
+	got, err := p.Apply(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := Marshal(got)
+	if want := `{"a":[0,1,2],"b":{},"e":{"X":1}}`; string(b) != want {
+		t.Errorf("got %s, want %s", b, want)
+	}
+	if len(doc["a"].([]any)) != 2 || doc["b"].(map[string]any)["c"] != "d" {
+		t.Errorf("doc was modified: %v", doc)
+	}
+
+	b, _ = Marshal(Patch{{Op: "replace", Path: "/x", Value: nil}, {Op: "move", From: "/a", Path: "/b"}})
+	if want := `[{"op":"replace","path":"/x","value":null},{"op":"move","from":"/a","path":"/b"}]`; string(b) != want {
+		t.Errorf("Marshal = %s, want %s", b, want)
+	}
+}
+
+func TestCreatePatch(t *testing.T) {
+	from := `{"a": 1, "b": {"c": [1, 2, 3], "d": "x"}, "e": [1], "f/g": true}`
+	to := `{"a": 1.0, "b": {"c": [1, 5], "d": "y", "n": null}, "e": [1, 2, 3], "h": {}}`
+	p, err := CreatePatchRaw(RawMessage(from), RawMessage(to))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := Marshal(p)
+	want := `[{"op":"remove","path":"/f~1g"},` +
+		`{"op":"replace","path":"/b/c/1","value":5},{"op":"remove","path":"/b/c/2"},` +
+		`{"op":"replace","path":"/b/d","value":"y"},{"op":"add","path":"/b/n","value":null},` +
+		`{"op":"add","path":"/e/1","value":2},{"op":"add","path":"/e/2","value":3},` +
+		`{"op":"add","path":"/h","value":{}}]`
+	if string(b) != want {
+		t.Errorf("CreatePatch =\n%s\nwant\n%s", b, want)
+	}
+
+	got, err := p.ApplyRaw(RawMessage(from))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d, _ := CompareRaw(got, RawMessage(to), nil); len(d) != 0 {
+		t.Errorf("patched document differs:\n%s", d)
+	}
+}
+
+func TestMergePatch(t *testing.T) {
+	// from RFC 7396, appendix A
+	tests := []struct{ doc, patch, want string }{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"a":"foo"}`, `"bar"`, `"bar"`},
+		{`{"e":null}`, `{"a":1}`, `{"e":null,"a":1}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	}
+	for _, tt := range tests {
+		got, err := MergePatchRaw(RawMessage(tt.doc), RawMessage(tt.patch))
+		if err != nil {
+			t.Errorf("MergePatch(%s, %s): %v", tt.doc, tt.patch, err)
+		} else if string(got) != tt.want {
+			t.Errorf("MergePatch(%s, %s) = %s, want %s", tt.doc, tt.patch, got, tt.want)
+		}
+	}
+
+	from := `{"a": 1, "b": {"c": 2, "d": 3}, "e": [1]}`
+	to := `{"a": 1, "b": {"c": 4}, "e": [1, 2], "f": "new"}`
+	patch, err := CreateMergePatchRaw(RawMessage(from), RawMessage(to))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"b":{"d":null,"c":4},"e":[1,2],"f":"new"}`; string(patch) != want {
+		t.Errorf("CreateMergePatch = %s, want %s", patch, want)
+	}
+	got, _ := MergePatchRaw(RawMessage(from), patch)
+	if d, _ := CompareRaw(got, RawMessage(to), nil); len(d) != 0 {
+		t.Errorf("patched document differs:\n%s", d)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	from := `{"name": "svc", "port": 80, "env": ["a", "b"], "items": [{"id": 1, "qty": 2}, {"id": 2, "qty": 1}], "old": true}`
+	to := `{"name": "svc", "port": 8080, "env": ["a"], "items": [{"id": 2, "qty": 5}, {"id": 3, "qty": 1}], "new": {"x": 1}}`
+
+	d, err := CompareRaw(RawMessage(from), RawMessage(to), &DiffOptions{ArrayKey: "id"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "~ $.port: 80 -> 8080\n" +
+		"- $.env[1]: \"b\"\n" +
+		"- $.items[id=1]: {\"id\":1,\"qty\":2}\n" +
+		"~ $.items[id=2].qty: 1 -> 5\n" +
+		"+ $.items[id=3]: {\"id\":3,\"qty\":1}\n" +
+		"- $.old: true\n" +
+		"+ $.new: {\"x\":1}\n"
+	if got := d.String(); got != want {
+		t.Errorf("Compare =\n%s\nwant\n%s", got, want)
+	}
+
+	// by index, when the key is disabled for the array
+	d, _ = CompareRaw(RawMessage(from), RawMessage(to), &DiffOptions{ArrayKey: "id", ArrayKeys: map[string]string{"$.items": ""}})
+	if got := d.String(); !strings.Contains(got, "~ $.items[0].id: 1 -> 2\n") {
+		t.Errorf("Compare by index =\n%s", got)
+	}
+
+	header, rows := d.Table()
+	if len(header) != 4 || len(rows) != len(d) {
+		t.Fatalf("Table = %v, %d rows", header, len(rows))
+	}
+	if rows[0][0] != "$.port" || rows[0][2] != "80" || rows[0][3] != "8080" {
+		t.Errorf("row = %v", rows[0])
+	}
+	for _, row := range rows {
+		if row[1] == "removed" && row[3] != "" {
+			t.Errorf("removed row has a new value: %v", row)
+		}
+	}
+}
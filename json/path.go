@@ -0,0 +1,472 @@
+package json
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// A Path is a compiled path query, a subset of JSONPath:
+//
+//	$               the root value
+//	.name ['name']  child member
+//	.* [*]          all children (members or elements)
+//	[n]             array element n (0-based)
+//	[start:end]     array elements from start (inclusive) to end (exclusive),
+//	                either bound may be omitted
+//	..name ..* ..[n]  recursive descent
+//
+// Negative indexes and filter expressions are not supported, as they
+// cannot be evaluated on a stream.
+type Path struct {
+	expr  string
+	steps []pathStep
+}
+
+type pathSelector int
+
+const (
+	selectName pathSelector = iota
+	selectWildcard
+	selectIndex
+	selectSlice
+)
+
+type pathStep struct {
+	selector  pathSelector
+	recursive bool
+	name      string
+	start     int
+	end       int // -1 for no end
+}
+
+// matches reports whether the step selects the child with key (for an
+// object member) or index (for an array element, key is then ignored).
+func (s pathStep) matches(isIndex bool, key string, index int) bool {
+	switch s.selector {
+	case selectWildcard:
+		return true
+	case selectName:
+		return !isIndex && key == s.name
+	case selectIndex:
+		return isIndex && index == s.start
+	case selectSlice:
+		return isIndex && index >= s.start && (s.end < 0 || index < s.end)
+	}
+	return false
+}
+
+// CompilePath parses a path query expression.
+func CompilePath(expr string) (*Path, error) {
+	p := &Path{expr: expr}
+	s := strings.TrimSpace(expr)
+	if !strings.HasPrefix(s, "$") {
+		return nil, errors.New("json: path must start with $: " + expr)
+	}
+	s = s[1:]
+
+	for s != "" {
+		var step pathStep
+		switch {
+		case strings.HasPrefix(s, ".."):
+			step.recursive = true
+			s = s[2:]
+			if strings.HasPrefix(s, "[") {
+				break
+			}
+			fallthrough
+		case strings.HasPrefix(s, "."):
+			if !step.recursive {
+				s = s[1:]
+			}
+			n := 0
+			for n < len(s) && s[n] != '.' && s[n] != '[' {
+				n++
+			}
+			if n == 0 {
+				return nil, errors.New("json: empty member name in path: " + expr)
+			}
+			if s[:n] == "*" {
+				step.selector = selectWildcard
+			} else {
+				step.selector = selectName
+				step.name = s[:n]
+			}
+			s = s[n:]
+			p.steps = append(p.steps, step)
+			continue
+		case strings.HasPrefix(s, "["):
+		default:
+			return nil, errors.New("json: invalid path at " + strconv.Quote(s) + ": " + expr)
+		}
+
+		// bracket selector
+		end := strings.IndexByte(s, ']')
+		if len(s) > 1 && (s[1] == '\'' || s[1] == '"') {
+			end = strings.IndexByte(s[2:], s[1])
+			if end < 0 || !strings.HasPrefix(s[2+end+1:], "]") {
+				return nil, errors.New("json: unterminated quoted name in path: " + expr)
+			}
+			step.selector = selectName
+			step.name = s[2 : 2+end]
+			s = s[2+end+2:]
+			p.steps = append(p.steps, step)
+			continue
+		}
+		if end < 0 {
+			return nil, errors.New("json: unterminated bracket in path: " + expr)
+		}
+
+		inner := strings.TrimSpace(s[1:end])
+		s = s[end+1:]
+		switch {
+		case inner == "*":
+			step.selector = selectWildcard
+		case strings.Contains(inner, ":"):
+			step.selector = selectSlice
+			startStr, endStr, _ := strings.Cut(inner, ":")
+			var err error
+			if step.start, err = parsePathIndex(startStr, 0); err != nil {
+				return nil, errors.New("json: invalid slice start in path: " + expr)
+			}
+			if step.end, err = parsePathIndex(endStr, -1); err != nil {
+				return nil, errors.New("json: invalid slice end in path: " + expr)
+			}
+		default:
+			step.selector = selectIndex
+			n, err := strconv.Atoi(inner)
+			if err != nil || n < 0 {
+				return nil, errors.New("json: invalid index in path (must be a non-negative integer): " + expr)
+			}
+			step.start = n
+		}
+		p.steps = append(p.steps, step)
+	}
+
+	return p, nil
+}
+
+// MustCompilePath is like CompilePath but panics if the expression
+// cannot be parsed.
+func MustCompilePath(expr string) *Path {
+	p, err := CompilePath(expr)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+func parsePathIndex(s string, def int) (int, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err == nil && n < 0 {
+		err = errors.New("negative index")
+	}
+	return n, err
+}
+
+// String returns the source expression of the path.
+func (p *Path) String() string { return p.expr }
+
+// advance returns the states active for a child, given the states active
+// for its parent, and whether the child is a match.
+func (p *Path) advance(states []int, isIndex bool, key string, index int) (next []int, matched bool) {
+	final := len(p.steps)
+	for _, s := range states {
+		if s >= final {
+			continue
+		}
+		step := p.steps[s]
+		if step.recursive {
+			next = appendState(next, s)
+		}
+		if step.matches(isIndex, key, index) {
+			if s+1 == final {
+				matched = true
+			} else {
+				next = appendState(next, s+1)
+			}
+		}
+	}
+	return
+}
+
+func appendState(states []int, s int) []int {
+	for _, st := range states {
+		if st == s {
+			return states
+		}
+	}
+	return append(states, s)
+}
+
+// A Query yields the values of a stream matching a Path, one at a time.
+// Subtrees which cannot contain a match are skipped without being decoded
+// or fully buffered.
+type Query struct {
+	dec  *Decoder
+	path *Path
+
+	rootStates []int
+	nested     bool // whether the root value was already yielded
+	started    bool
+	done       bool
+
+	stack    []queryFrame
+	pending  []queryMatch
+	location string
+}
+
+type queryFrame struct {
+	states  []int
+	isArray bool
+	index   int // index of the next element
+	loc     string
+}
+
+type queryMatch struct {
+	raw RawMessage
+	loc string
+}
+
+// Query returns a Query yielding the values matching the path expression,
+// from the values remaining in the stream.
+func (dec *Decoder) Query(expr string) (*Query, error) {
+	p, err := CompilePath(expr)
+	if err != nil {
+		return nil, err
+	}
+	return dec.QueryPath(p), nil
+}
+
+// QueryPath is like Query with a compiled Path.
+func (dec *Decoder) QueryPath(p *Path) *Query {
+	return &Query{dec: dec, path: p, rootStates: []int{0}}
+}
+
+// Location returns the location of the last value returned by Next,
+// such as $.data.items[3].id.
+func (q *Query) Location() string {
+	return q.location
+}
+
+// Decode stores the next matching value in the value pointed to by v.
+// At the end of the stream, Decode returns io.EOF.
+func (q *Query) Decode(v any) error {
+	raw, err := q.Next()
+	if err != nil {
+		return err
+	}
+	return Unmarshal(raw, v)
+}
+
+// Next returns the next matching value. At the end of the stream,
+// Next returns nil, io.EOF.
+func (q *Query) Next() (RawMessage, error) {
+	dec := q.dec
+	for {
+		if len(q.pending) > 0 {
+			m := q.pending[0]
+			q.pending = q.pending[1:]
+			q.location = m.loc
+			return m.raw, nil
+		}
+		if q.done {
+			return nil, io.EOF
+		}
+
+		if len(q.stack) == 0 {
+			if q.started && q.nested {
+				q.done = true
+				continue
+			}
+			if q.started {
+				// another top-level value follows in the stream?
+				if _, err := dec.peek(); err == io.EOF {
+					q.done = true
+					continue
+				} else if err != nil {
+					return nil, err
+				}
+			}
+			q.started = true
+			states, matched := q.rootStates, len(q.path.steps) == 0
+			if matched {
+				states = nil
+			}
+			if err := q.enterValue(states, matched, "$"); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		top := &q.stack[len(q.stack)-1]
+		if !dec.More() {
+			if _, err := dec.Token(); err != nil {
+				return nil, err
+			}
+			q.stack = q.stack[:len(q.stack)-1]
+			continue
+		}
+
+		var states []int
+		var matched bool
+		var loc string
+		if top.isArray {
+			states, matched = q.path.advance(top.states, true, "", top.index)
+			loc = top.loc + "[" + strconv.Itoa(top.index) + "]"
+			top.index++
+		} else {
+			tok, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			key, ok := tok.(string)
+			if !ok {
+				return nil, &SyntaxError{msg: "expected object key", Offset: dec.InputOffset()}
+			}
+			states, matched = q.path.advance(top.states, false, key, 0)
+			loc = top.loc + pathMember(key)
+		}
+
+		if err := q.enterValue(states, matched, loc); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// enterValue handles the next value of the stream: a match is read and
+// queued (along with its nested matches), a container which may hold
+// matches is entered, and anything else is skipped.
+func (q *Query) enterValue(states []int, matched bool, loc string) error {
+	dec := q.dec
+	if matched {
+		var raw RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return err
+		}
+		q.pending = append(q.pending, queryMatch{raw: raw, loc: loc})
+		if len(states) > 0 && len(raw) > 0 && (raw[0] == '{' || raw[0] == '[') {
+			return q.nestedMatches(raw, states, loc)
+		}
+		return nil
+	}
+
+	if len(states) == 0 {
+		return dec.skipValue()
+	}
+
+	if err := dec.tokenPrepareForDecode(); err != nil {
+		return err
+	}
+	c, err := dec.peek()
+	if err != nil {
+		return err
+	}
+	if c != '{' && c != '[' {
+		return dec.skipValue()
+	}
+	if _, err := dec.Token(); err != nil {
+		return err
+	}
+	q.stack = append(q.stack, queryFrame{states: states, isArray: c == '[', loc: loc})
+	return nil
+}
+
+// nestedMatches queues the matches found inside an already matched value.
+func (q *Query) nestedMatches(raw RawMessage, states []int, loc string) error {
+	nq := &Query{dec: NewDecoder(bytes.NewReader(raw)), path: q.path, rootStates: states, nested: true}
+	nq.started = true
+	if _, err := nq.dec.Token(); err != nil {
+		return err
+	}
+	nq.stack = append(nq.stack, queryFrame{states: states, isArray: raw[0] == '[', loc: loc})
+	for {
+		m, err := nq.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		q.pending = append(q.pending, queryMatch{raw: m, loc: nq.location})
+	}
+}
+
+// pathMember returns the location suffix for an object member.
+func pathMember(key string) string {
+	simple := key != ""
+	for i, c := range key {
+		if !(c == '_' || c == '$' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || (i > 0 && '0' <= c && c <= '9')) {
+			simple = false
+			break
+		}
+	}
+	if simple {
+		return "." + key
+	}
+	return "[" + strconv.Quote(key) + "]"
+}
+
+// skipValue consumes the next value without decoding it. Unlike Decode,
+// the bytes scanned are discarded as the value is read, so that skipping
+// a large value does not grow the buffer.
+func (dec *Decoder) skipValue() error {
+	if dec.err != nil {
+		return dec.err
+	}
+	if err := dec.tokenPrepareForDecode(); err != nil {
+		return err
+	}
+	if !dec.tokenValueAllowed() {
+		return &SyntaxError{msg: "not at beginning of value", Offset: dec.InputOffset()}
+	}
+
+	dec.scan.reset()
+	scanp := dec.scanp
+	var err error
+Input:
+	for {
+		for ; scanp < len(dec.buf); scanp++ {
+			c := dec.buf[scanp]
+			dec.scan.bytes++
+			switch dec.scan.step(&dec.scan, c) {
+			case scanEnd:
+				dec.scan.bytes--
+				break Input
+			case scanEndObject, scanEndArray:
+				if stateEndValue(&dec.scan, ' ') == scanEnd {
+					scanp++
+					break Input
+				}
+			case scanError:
+				dec.err = dec.scan.err
+				return dec.scan.err
+			}
+		}
+
+		if err != nil {
+			if err == io.EOF {
+				if dec.scan.step(&dec.scan, ' ') == scanEnd {
+					break Input
+				}
+				err = io.ErrUnexpectedEOF
+			}
+			dec.err = err
+			return err
+		}
+
+		// discard what was scanned before reading more
+		dec.scanp = scanp
+		err = dec.refill()
+		scanp = dec.scanp
+	}
+
+	dec.scanp = scanp
+	dec.tokenValueEnd()
+	return nil
+}
@@ -0,0 +1,137 @@
+package json
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const pathTestDoc = `{
+	"meta": {"id": "skip-me", "tags": ["a", "b"]},
+	"data": {
+		"items": [
+			{"id": 1, "name": "one", "children": [{"id": 11}]},
+			{"id": 2, "name": "two"},
+			{"id": 3, "name": "three", "odd key": true}
+		],
+		"total": 3
+	}
+}
+{"data": {"items": [{"id": 4}]}}`
+
+func TestQuery(t *testing.T) {
+	tests := []struct {
+		path string
+		want []string
+		locs []string
+	}{
+		{path: "$.data.items[*].id", want: []string{"1", "2", "3", "4"}, locs: []string{"$.data.items[0].id", "$.data.items[1].id", "$.data.items[2].id", "$.data.items[0].id"}},
+		{path: "$['data']['total']", want: []string{"3"}},
+		{path: "$.data.items[1].name", want: []string{`"two"`}},
+		{path: "$.data.items[1:].id", want: []string{"2", "3"}},
+		{path: "$.data.items[:1].name", want: []string{`"one"`}},
+		{path: "$.data.items[2]['odd key']", want: []string{"true"}, locs: []string{`$.data.items[2]["odd key"]`}},
+		{path: "$..id", want: []string{`"skip-me"`, "1", "11", "2", "3", "4"}},
+		{path: "$.meta.tags[*]", want: []string{`"a"`, `"b"`}},
+		{path: "$.meta.*", want: []string{`"skip-me"`, `["a", "b"]`}},
+		{path: "$..[0].id", want: []string{"1", "11", "4"}},
+		{path: "$.nothing", want: nil},
+	}
+
+	for _, tt := range tests {
+		q, err := NewDecoder(strings.NewReader(pathTestDoc)).Query(tt.path)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.path, err)
+		}
+		var got, locs []string
+		for {
+			raw, err := q.Next()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatalf("%s: %v", tt.path, err)
+			}
+			got = append(got, string(raw))
+			locs = append(locs, q.Location())
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s:\nhave %q\nwant %q", tt.path, got, tt.want)
+		}
+		if tt.locs != nil && !reflect.DeepEqual(locs, tt.locs) {
+			t.Errorf("%s locations:\nhave %q\nwant %q", tt.path, locs, tt.locs)
+		}
+	}
+}
+
+func TestQueryNestedRecursive(t *testing.T) {
+	q, _ := NewDecoder(strings.NewReader(`{"a": {"a": {"a": 1}}}`)).Query("$..a")
+	var got, locs []string
+	for {
+		raw, err := q.Next()
+		if err != nil {
+			break
+		}
+		got = append(got, string(raw))
+		locs = append(locs, q.Location())
+	}
+	want := []string{`{"a": {"a": 1}}`, `{"a": 1}`, "1"}
+	if !reflect.DeepEqual(got, want) || !reflect.DeepEqual(locs, []string{"$.a", "$.a.a", "$.a.a.a"}) {
+		t.Errorf("have %q %q", got, locs)
+	}
+}
+
+func TestQueryDecode(t *testing.T) {
+	type item struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	}
+	q, _ := NewDecoder(strings.NewReader(pathTestDoc)).Query("$.data.items[*]")
+	var items []item
+	for {
+		var it item
+		if err := q.Decode(&it); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		items = append(items, it)
+	}
+	if len(items) != 4 || items[2].Name != "three" || items[3].ID != 4 {
+		t.Errorf("unexpected items: %+v", items)
+	}
+}
+
+func TestQuerySkipBounded(t *testing.T) {
+	// a large unmatched subtree must not be buffered whole
+	var sb strings.Builder
+	sb.WriteString(`{"skip": [`)
+	for i := 0; i < 100000; i++ {
+		if i > 0 {
+			sb.WriteString(",")
+		}
+		sb.WriteString(`{"x": "0123456789"}`)
+	}
+	sb.WriteString(`], "keep": 42}`)
+
+	dec := NewDecoder(strings.NewReader(sb.String()))
+	q, _ := dec.Query("$.keep")
+	raw, err := q.Next()
+	if err != nil || string(raw) != "42" {
+		t.Fatalf("have %q, %v", raw, err)
+	}
+	if c := cap(dec.buf); c > 64*1024 {
+		t.Errorf("buffer grew to %d bytes", c)
+	}
+	if _, err := q.Next(); err != io.EOF {
+		t.Errorf("expected EOF, have %v", err)
+	}
+}
+
+func TestCompilePathErrors(t *testing.T) {
+	for _, expr := range []string{"data.items", "$.items[-1]", "$[", "$['a", "$.", "$.a[x]", "$.a[1:-2]"} {
+		if _, err := CompilePath(expr); err == nil {
+			t.Errorf("%s: expected an error", expr)
+		}
+	}
+}
@@ -0,0 +1,241 @@
+package json
+
+import (
+	"bytes"
+	"strconv"
+)
+
+// A DuplicateKeyError describes an object key which appears more than
+// once, as reported when Decoder.DisallowDuplicateKeys is set. Positions
+// are those of the opening quotes of the keys.
+type DuplicateKeyError struct {
+	Key     string
+	Pointer string // JSON Pointer of the member, relative to the decoded value
+
+	Offset int64 // offset of the duplicate key in the input
+	Line   int
+	Column int
+
+	FirstOffset int64 // offset of the first occurrence of the key
+	FirstLine   int
+	FirstColumn int
+}
+
+func (e *DuplicateKeyError) Error() string {
+	return "json: duplicate key " + strconv.Quote(e.Key) + " at line " + strconv.Itoa(e.Line) + ", column " +
+		strconv.Itoa(e.Column) + " (first at line " + strconv.Itoa(e.FirstLine) + ", column " + strconv.Itoa(e.FirstColumn) + ")"
+}
+
+// DisallowDuplicateKeys causes the Decoder to return a DuplicateKeyError
+// when an object of the input contains a key more than once, rather than
+// keeping the last value.
+func (dec *Decoder) DisallowDuplicateKeys() { dec.disallowDuplicateKeys = true }
+
+// lineColumn returns the line and column, starting at 1, of the byte at
+// off in data. Columns count bytes.
+func lineColumn(data []byte, off int) (line, col int) {
+	if off > len(data) {
+		off = len(data)
+	} else if off < 0 {
+		off = 0
+	}
+	line = 1 + bytes.Count(data[:off], []byte{'\n'})
+	col = off - bytes.LastIndexByte(data[:off], '\n')
+	return line, col
+}
+
+// errorByte returns the offset of the byte where an error occurred after
+// reading offset bytes.
+func errorByte(offset int64) int64 {
+	if offset > 0 {
+		return offset - 1
+	}
+	return 0
+}
+
+// locateError sets the position and pointer of a SyntaxError or an
+// UnmarshalTypeError whose Offset is relative to value, unless already
+// set. pos returns the line and column of an offset in value.
+func locateError(err error, value []byte, relaxed bool, pos func(off int) (line, col int)) {
+	switch err := err.(type) {
+	case *SyntaxError:
+		if err.Line == 0 {
+			off := int(errorByte(err.Offset))
+			err.Line, err.Column = pos(off)
+			err.Pointer = pointerAt(value, off+1, relaxed)
+		}
+	case *UnmarshalTypeError:
+		if err.Line == 0 {
+			off := int(errorByte(err.Offset))
+			err.Line, err.Column = pos(off)
+			err.Pointer = pointerAt(value, off+1, relaxed)
+		}
+	}
+}
+
+// pointerAt returns the JSON Pointer of the member or element parsed at
+// data[end-1], or of the object before the colon of a key. A syntax
+// error ends the walk, so that the pointer is that of the erroneous value.
+func pointerAt(data []byte, end int, relaxed bool) string {
+	w := pathWalker{data: data}
+	w.scan.relaxed = relaxed
+	w.scan.reset()
+	if end > len(data) {
+		end = len(data)
+	}
+	for i := 0; i < end; i++ {
+		if w.step(i) == scanError {
+			break
+		}
+	}
+	return w.lastPointer()
+}
+
+// A pathWalker follows the path of the values in the input, byte by
+// byte, to locate errors. Only the stack of frames is kept while walking,
+// pointers are built when requested.
+type pathWalker struct {
+	data   []byte
+	scan   scanner
+	frames []pathFrame
+
+	// last records the top frame when the last member or element started,
+	// with depth the number of frames then. Frames below the top do not
+	// change until the next one starts or a value ends, which updates last.
+	last  pathFrame
+	depth int
+
+	// keys records the offsets of the keys of each object if set, to
+	// detect duplicates.
+	keys bool
+	dup  *DuplicateKeyError
+}
+
+type pathFrame struct {
+	object   bool
+	inKey    bool // before the colon of a member
+	keyStart int
+	keyEnd   int
+	index    int
+	offsets  map[string]int
+}
+
+// step passes the byte at i to the scanner, and updates the path.
+func (w *pathWalker) step(i int) int {
+	op := w.scan.step(&w.scan, w.data[i])
+	var top *pathFrame
+	if n := len(w.frames); n > 0 {
+		top = &w.frames[n-1]
+	}
+	switch op {
+	case scanBeginObject, scanBeginArray:
+		w.started()
+		f := pathFrame{object: op == scanBeginObject, inKey: true}
+		if f.object && w.keys {
+			f.offsets = map[string]int{}
+		}
+		w.frames = append(w.frames, f)
+		w.started() // the first member or element
+	case scanBeginLiteral:
+		if top != nil && top.object && top.inKey {
+			top.keyStart = i
+		} else {
+			w.started()
+		}
+	case scanObjectKey:
+		top.keyEnd = i
+		top.inKey = false
+		w.started()
+		if top.offsets != nil {
+			key := w.key(top)
+			if first, ok := top.offsets[key]; ok && w.dup == nil {
+				w.dup = &DuplicateKeyError{
+					Key:         key,
+					Pointer:     w.pointer(w.frames),
+					Offset:      int64(top.keyStart),
+					FirstOffset: int64(first),
+				}
+			}
+			top.offsets[key] = top.keyStart
+		}
+	case scanObjectValue:
+		top.inKey = true
+		w.started()
+	case scanArrayValue:
+		top.index++
+		w.started()
+	case scanEndObject, scanEndArray:
+		w.frames = w.frames[:len(w.frames)-1]
+		w.started()
+	}
+	return op
+}
+
+// started records the current position as that of the last member or
+// element, from its key or slot.
+func (w *pathWalker) started() {
+	w.depth = len(w.frames)
+	if w.depth > 0 {
+		w.last = w.frames[w.depth-1]
+	}
+}
+
+// lastPointer returns the pointer of the last member or element started.
+func (w *pathWalker) lastPointer() string {
+	if w.depth == 0 {
+		return ""
+	}
+	frames := append(w.frames[:w.depth-1:w.depth-1], w.last)
+	return w.pointer(frames)
+}
+
+// pointer returns the pointer of the position described by frames.
+func (w *pathWalker) pointer(frames []pathFrame) string {
+	var b []byte
+	for i := range frames {
+		f := &frames[i]
+		switch {
+		case !f.object:
+			b = append(b, '/')
+			b = strconv.AppendInt(b, int64(f.index), 10)
+		case f.inKey:
+			return string(b)
+		default:
+			b = append(b, '/')
+			b = append(b, escapePointer(w.key(f))...)
+		}
+	}
+	return string(b)
+}
+
+// key returns the key of the current member of the object frame f.
+func (w *pathWalker) key(f *pathFrame) string {
+	return literalKey(bytes.TrimRight(w.data[f.keyStart:f.keyEnd], " \t\r\n"))
+}
+
+// literalKey returns the key of a member from its literal, which is
+// unquoted in relaxed mode if not between double quotes.
+func literalKey(item []byte) string {
+	if key, ok := unquote(item); ok {
+		return key
+	}
+	if s, err := Standardize(item); err == nil {
+		if key, ok := unquote(s); ok {
+			return key
+		}
+	}
+	return string(item)
+}
+
+// duplicateKey returns the first duplicate key of value, with offsets
+// relative to value, or nil.
+func duplicateKey(value []byte) *DuplicateKeyError {
+	w := pathWalker{data: value, keys: true}
+	w.scan.reset()
+	for i := range value {
+		if w.step(i) == scanError || w.dup != nil {
+			break
+		}
+	}
+	return w.dup
+}
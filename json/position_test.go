@@ -0,0 +1,129 @@
+package json
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestErrorPosition(t *testing.T) {
+	type item struct {
+		Price float64 `json:"price"`
+	}
+	type config struct {
+		Name  string `json:"name"`
+		Items []item `json:"items"`
+	}
+
+	tests := []struct {
+		in           string
+		line, column int
+		pointer      string
+		syntax       bool
+	}{
+		{in: "{\n  \"name\": \"x\",\n  \"items\": [{\"price\": 1}, {\"price\": \"2\"}]\n}", line: 3, column: 39, pointer: "/items/1/price"},
+		{in: "{\n  \"name\": 7\n}", line: 2, column: 11, pointer: "/name"},
+		{in: "{\"items\": {}}", line: 1, column: 11, pointer: "/items"},
+		{in: "{\n  \"name\": \"x\",\n  \"items\": [\n    {\"price\": 1x}\n  ]\n}", line: 4, column: 16, pointer: "/items/0/price", syntax: true},
+		{in: "{\n  \"name\": \"x\"\n  \"items\": []\n}", line: 3, column: 3, pointer: "/name", syntax: true},
+		{in: "[1, 2", line: 1, column: 5, pointer: "/1", syntax: true},
+		{in: `{"a": 1, "b": x}`, line: 1, column: 15, pointer: "/b", syntax: true},
+		{in: `[1, 2, x]`, line: 1, column: 8, pointer: "/2", syntax: true},
+		{in: `{"a": [1, {"b": x}]}`, line: 1, column: 17, pointer: "/a/1/b", syntax: true},
+		{in: `[x]`, line: 1, column: 2, pointer: "/0", syntax: true},
+	}
+	for _, tt := range tests {
+		var v config
+		err := Unmarshal([]byte(tt.in), &v)
+		var line, column int
+		var pointer string
+		var serr *SyntaxError
+		var terr *UnmarshalTypeError
+		switch {
+		case errors.As(err, &serr) && tt.syntax:
+			line, column, pointer = serr.Line, serr.Column, serr.Pointer
+		case errors.As(err, &terr) && !tt.syntax:
+			line, column, pointer = terr.Line, terr.Column, terr.Pointer
+		default:
+			t.Errorf("Unmarshal(%q): unexpected error %v", tt.in, err)
+			continue
+		}
+		if line != tt.line || column != tt.column || pointer != tt.pointer {
+			t.Errorf("Unmarshal(%q): got %d:%d %s, want %d:%d %s", tt.in, line, column, pointer, tt.line, tt.column, tt.pointer)
+		}
+	}
+}
+
+func TestDecoderErrorPosition(t *testing.T) {
+	in := strings.Repeat("{\"a\": [1, 2]}\n", 100) + "{\"a\": [1, \"b\"]}\n{\"a\": [1 2]}"
+	dec := NewDecoder(iotest.OneByteReader(strings.NewReader(in)))
+	var v struct{ A []int }
+	for i := 0; i < 100; i++ {
+		if err := dec.Decode(&v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var terr *UnmarshalTypeError
+	if err := dec.Decode(&v); !errors.As(err, &terr) {
+		t.Fatalf("got %v, want an UnmarshalTypeError", err)
+	}
+	if terr.Line != 101 || terr.Column != 13 || terr.Pointer != "/a/1" {
+		t.Errorf("type error at %d:%d %s, want 101:13 /a/1", terr.Line, terr.Column, terr.Pointer)
+	}
+
+	var serr *SyntaxError
+	if err := dec.Decode(&v); !errors.As(err, &serr) {
+		t.Fatalf("got %v, want a SyntaxError", err)
+	}
+	if serr.Line != 102 || serr.Column != 10 || serr.Pointer != "/a/0" {
+		t.Errorf("syntax error at %d:%d %s, want 102:10 /a/0", serr.Line, serr.Column, serr.Pointer)
+	}
+}
+
+func TestDisallowDuplicateKeys(t *testing.T) {
+	in := "{\"a\": 1}\n{\n  \"a\": {\"b\": 1, \"c\": [{\"d\": 1,\n  \"d\": 2}]}\n}\n{\"a\": 1, \"a\": 2}"
+	dec := NewDecoder(strings.NewReader(in))
+	dec.DisallowDuplicateKeys()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+
+	err := dec.Decode(&v)
+	var derr *DuplicateKeyError
+	if !errors.As(err, &derr) {
+		t.Fatalf("got %v, want a DuplicateKeyError", err)
+	}
+	want := DuplicateKeyError{
+		Key: "d", Pointer: "/a/c/0/d",
+		Offset: 44, Line: 4, Column: 3,
+		FirstOffset: 34, FirstLine: 3, FirstColumn: 24,
+	}
+	if *derr != want {
+		t.Errorf("got %+v\nwant %+v", *derr, want)
+	}
+	if got := err.Error(); got != `json: duplicate key "d" at line 4, column 3 (first at line 3, column 24)` {
+		t.Errorf("Error() = %s", got)
+	}
+
+	// the decoder is still usable
+	if err := dec.Decode(&v); !errors.As(err, &derr) || derr.Pointer != "/a" {
+		t.Errorf("got %v", err)
+	}
+
+	dec = NewDecoder(strings.NewReader(`{a: 1, 'a': 2}`))
+	dec.UseRelaxed()
+	dec.DisallowDuplicateKeys()
+	if err := dec.Decode(&v); !errors.As(err, &derr) || derr.Key != "a" {
+		t.Errorf("relaxed: got %v", err)
+	}
+
+	// keys are unique per object
+	dec = NewDecoder(strings.NewReader(`{"a": {"a": 1}, "b": [{"a": 1}, {"a": 2}]}`))
+	dec.DisallowDuplicateKeys()
+	if err := dec.Decode(&v); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,182 @@
+package json
+
+import (
+	"reflect"
+	"sync"
+	"sync/atomic"
+)
+
+// A Registry holds marshal and unmarshal functions for specific types.
+// A registered function takes priority over the MarshalJSON, MarshalText,
+// UnmarshalJSON and UnmarshalText methods of its type, and over the
+// default encoding. Functions are registered with RegisterEncoder and
+// RegisterDecoder, and a Registry is safe for concurrent use.
+//
+// A registry is set with Encoder.SetRegistry or Decoder.SetRegistry;
+// DefaultRegistry applies to all marshaling and unmarshaling, after the
+// registry of the Encoder or Decoder if any.
+type Registry struct {
+	mu  sync.RWMutex
+	enc map[reflect.Type]registeredEncoder
+	dec map[reflect.Type]registeredDecoder
+	n   atomic.Int32
+}
+
+// registeredEncoder returns the JSON encoding of v
+type registeredEncoder func(v reflect.Value) ([]byte, error)
+
+// registeredDecoder decodes data into v, a pointer to the registered type
+type registeredDecoder func(data []byte, v reflect.Value) error
+
+// DefaultRegistry is the registry used by all marshaling and unmarshaling.
+var DefaultRegistry = NewRegistry()
+
+// NewRegistry returns an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		enc: map[reflect.Type]registeredEncoder{},
+		dec: map[reflect.Type]registeredDecoder{},
+	}
+}
+
+// RegisterEncoder registers fn to marshal the values of type T in r.
+// fn must return valid JSON. A nil fn removes the registration.
+func RegisterEncoder[T any](r *Registry, fn func(v T) ([]byte, error)) {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if fn == nil {
+		delete(r.enc, t)
+	} else {
+		r.enc[t] = func(v reflect.Value) ([]byte, error) {
+			x, _ := v.Interface().(T)
+			return fn(x)
+		}
+	}
+	r.n.Store(int32(len(r.enc) + len(r.dec)))
+}
+
+// RegisterDecoder registers fn to unmarshal the values of type T in r.
+// As with UnmarshalJSON, fn is called with the literal null, and must copy
+// data if it keeps it. A nil fn removes the registration.
+func RegisterDecoder[T any](r *Registry, fn func(data []byte, v *T) error) {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if fn == nil {
+		delete(r.dec, t)
+	} else {
+		r.dec[t] = func(data []byte, v reflect.Value) error {
+			return fn(data, v.Interface().(*T))
+		}
+	}
+	r.n.Store(int32(len(r.enc) + len(r.dec)))
+}
+
+// activeRegistry returns the registry to use given the one set on an
+// Encoder or Decoder, or nil if no function is registered.
+func activeRegistry(r *Registry) *Registry {
+	if r != nil {
+		return r
+	}
+	if DefaultRegistry.n.Load() > 0 {
+		return DefaultRegistry
+	}
+	return nil
+}
+
+// encoder returns the function registered to marshal t, if any.
+func (r *Registry) encoder(t reflect.Type) registeredEncoder {
+	if r.n.Load() > 0 {
+		r.mu.RLock()
+		fn := r.enc[t]
+		r.mu.RUnlock()
+		if fn != nil {
+			return fn
+		}
+	}
+	if r != DefaultRegistry {
+		return DefaultRegistry.encoder(t)
+	}
+	return nil
+}
+
+// decoder returns the function registered to unmarshal t, if any.
+func (r *Registry) decoder(t reflect.Type) registeredDecoder {
+	if r.n.Load() > 0 {
+		r.mu.RLock()
+		fn := r.dec[t]
+		r.mu.RUnlock()
+		if fn != nil {
+			return fn
+		}
+	}
+	if r != DefaultRegistry {
+		return DefaultRegistry.decoder(t)
+	}
+	return nil
+}
+
+// registryEncoder wraps the encoder of t to use the function registered
+// for t (or for the element type of a pointer), if any.
+func registryEncoder(t reflect.Type, enc encoderFunc) encoderFunc {
+	return func(e *encodeState, v reflect.Value, opts encOpts) {
+		if opts.registry != nil {
+			fn := opts.registry.encoder(t)
+			if fn == nil && t.Kind() == reflect.Pointer {
+				// the methods of *T would otherwise take precedence
+				if fn = opts.registry.encoder(t.Elem()); fn != nil {
+					if v.IsNil() {
+						e.WriteString("null")
+						return
+					}
+					v = v.Elem()
+				}
+			}
+			if fn != nil {
+				b, err := fn(v)
+				if err == nil {
+					// copy JSON into buffer, checking validity.
+					err = compact(&e.Buffer, b, opts.escapeHTML)
+				}
+				if err != nil {
+					e.error(&MarshalerError{v.Type(), err, "registered encoder"})
+				}
+				return
+			}
+		}
+		enc(e, v, opts)
+	}
+}
+
+// registryUnmarshaler adapts a registered decoder to the Unmarshaler
+// interface.
+type registryUnmarshaler struct {
+	fn registeredDecoder
+	v  reflect.Value
+}
+
+func (u registryUnmarshaler) UnmarshalJSON(data []byte) error {
+	return u.fn(data, u.v)
+}
+
+// SetRegistry sets the registry of the functions used to marshal
+// specific types, before DefaultRegistry.
+func (enc *Encoder) SetRegistry(r *Registry) { enc.registry = r }
+
+// SetFloatFormat sets the function formatting floats, which must return
+// a valid JSON number. bits is 32 or 64. A nil fn restores the default
+// format. NaN and infinite values are still rejected.
+func (enc *Encoder) SetFloatFormat(fn func(f float64, bits int) string) { enc.floatFormat = fn }
+
+// SetNilSliceAsEmpty specifies whether nil slices are encoded as [] (or
+// "" for byte slices) instead of null.
+func (enc *Encoder) SetNilSliceAsEmpty(on bool) { enc.nilSliceAsEmpty = on }
+
+// SetNilMapAsEmpty specifies whether nil maps are encoded as {} instead
+// of null.
+func (enc *Encoder) SetNilMapAsEmpty(on bool) { enc.nilMapAsEmpty = on }
+
+// SetRegistry sets the registry of the functions used to unmarshal
+// specific types, before DefaultRegistry.
+func (dec *Decoder) SetRegistry(r *Registry) { dec.d.registry = r }
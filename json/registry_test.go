@@ -0,0 +1,155 @@
+package json
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+type registryValue struct {
+	When  time.Time  `json:"when"`
+	Opt   *time.Time `json:"opt"`
+	Price float64    `json:"price"`
+	Tags  []string   `json:"tags"`
+	Meta  map[string]int
+	Any   any
+}
+
+func unixEncoder(t time.Time) ([]byte, error) {
+	return []byte(strconv.FormatInt(t.Unix(), 10)), nil
+}
+
+func unixDecoder(data []byte, t *time.Time) error {
+	if string(data) == "null" {
+		return nil
+	}
+	sec, err := strconv.ParseInt(string(data), 10, 64)
+	if err != nil {
+		return err
+	}
+	*t = time.Unix(sec, 0).UTC()
+	return nil
+}
+
+func TestEncoderRegistry(t *testing.T) {
+	when := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	v := registryValue{When: when, Opt: &when, Price: 1.5, Any: when}
+
+	r := NewRegistry()
+	RegisterEncoder(r, unixEncoder)
+
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	enc.SetRegistry(r)
+	if err := enc.Encode(v); err != nil {
+		t.Fatal(err)
+	}
+	want := `{"when":1704164645,"opt":1704164645,"price":1.5,"tags":null,"Meta":null,"Any":1704164645}`
+	if got := buf.String(); got != want {
+		t.Errorf("Encode =\n%s\nwant\n%s", got, want)
+	}
+
+	// other encoders and Marshal are not affected
+	b, _ := Marshal(v)
+	if !strings.Contains(string(b), `"when":"2024-01-02T03:04:05Z"`) {
+		t.Errorf("Marshal = %s", b)
+	}
+
+	RegisterEncoder(r, func(t time.Time) ([]byte, error) { return []byte("{bad"), nil })
+	buf.Reset()
+	var merr *MarshalerError
+	if err := enc.Encode(v); !errors.As(err, &merr) {
+		t.Errorf("got %v, want *MarshalerError", err)
+	}
+}
+
+func TestDecoderRegistry(t *testing.T) {
+	r := NewRegistry()
+	RegisterDecoder(r, unixDecoder)
+
+	dec := NewDecoder(strings.NewReader(`{"when": 1704164645, "opt": 60, "Any": 1}`))
+	dec.SetRegistry(r)
+	var v registryValue
+	if err := dec.Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC); !v.When.Equal(want) {
+		t.Errorf("When = %v, want %v", v.When, want)
+	}
+	if v.Opt == nil || v.Opt.Unix() != 60 {
+		t.Errorf("Opt = %v", v.Opt)
+	}
+	if v.Any != 1.0 {
+		t.Errorf("Any = %v", v.Any)
+	}
+
+	// time.Time.UnmarshalJSON is used without the registry
+	if err := Unmarshal([]byte(`{"when": 1704164645}`), &v); err == nil {
+		t.Error("expected error without registry")
+	}
+}
+
+func TestDefaultRegistry(t *testing.T) {
+	type celsius float64
+	RegisterEncoder(DefaultRegistry, func(c celsius) ([]byte, error) {
+		return []byte(`"` + strconv.FormatFloat(float64(c), 'f', 1, 64) + `C"`), nil
+	})
+	RegisterDecoder(DefaultRegistry, func(data []byte, c *celsius) error {
+		f, err := strconv.ParseFloat(strings.TrimSuffix(strings.Trim(string(data), `"`), "C"), 64)
+		*c = celsius(f)
+		return err
+	})
+	defer RegisterEncoder[celsius](DefaultRegistry, nil)
+	defer RegisterDecoder[celsius](DefaultRegistry, nil)
+
+	b, err := Marshal(map[string]celsius{"t": 21.55})
+	if err != nil || string(b) != `{"t":"21.6C"}` {
+		t.Errorf("Marshal = %s, %v", b, err)
+	}
+	var m map[string]celsius
+	if err := Unmarshal([]byte(`{"t": "-3.5C"}`), &m); err != nil || m["t"] != -3.5 {
+		t.Errorf("Unmarshal = %v, %v", m, err)
+	}
+
+	// a registry set on an encoder falls back to the default one
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	enc.SetRegistry(NewRegistry())
+	enc.Encode([]celsius{1})
+	if buf.String() != `["1.0C"]` {
+		t.Errorf("Encode = %s", buf.String())
+	}
+}
+
+func TestEncoderOptions(t *testing.T) {
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	enc.SetNilSliceAsEmpty(true)
+	enc.SetNilMapAsEmpty(true)
+	enc.SetFloatFormat(func(f float64, bits int) string { return strconv.FormatFloat(f, 'f', 2, bits) })
+
+	var om OrderedMap
+	om.Set("nested", []int(nil))
+	v := struct {
+		S  []string
+		B  []byte
+		M  map[string]any
+		F  float32
+		Q  float64 `json:",string"`
+		OM OrderedMap
+	}{F: 1.5, Q: 2, OM: om}
+	if err := enc.Encode(v); err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"S":[],"B":"","M":{},"F":1.50,"Q":"2.00","OM":{"nested":[]}}`; buf.String() != want {
+		t.Errorf("Encode =\n%s\nwant\n%s", buf.String(), want)
+	}
+
+	enc.SetFloatFormat(func(f float64, bits int) string { return "1,5" })
+	if err := enc.Encode(1.5); err == nil {
+		t.Error("expected error for invalid float format")
+	}
+}
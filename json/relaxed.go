@@ -0,0 +1,352 @@
+package json
+
+import (
+	"math/big"
+)
+
+// UseRelaxed causes the Decoder to accept a subset of JSON5, as found in
+// human-edited configuration files:
+//
+//   - // line comments and /* block comments */;
+//   - a trailing comma after the last element of an array or object;
+//   - strings between single quotes, in which \' is an escaped quote;
+//   - object keys which are identifiers, such as {name: "x"}: ASCII
+//     letters, digits, _ and $, not starting with a digit;
+//   - hexadecimal integers such as 0xFF or -0x1f.
+//
+// Each value is converted to standard JSON before decoding, so offsets
+// in errors other than syntax errors are relative to the converted value.
+// The Token API does not support relaxed input.
+func (dec *Decoder) UseRelaxed() { dec.scan.relaxed = true }
+
+// UnmarshalRelaxed is like Unmarshal, accepting the JSON5 subset
+// described at Decoder.UseRelaxed.
+func UnmarshalRelaxed(data []byte, v any) error {
+	var d decodeState
+	d.scan.relaxed = true
+	if err := checkValid(data, &d.scan); err != nil {
+		return err
+	}
+	d.init(standardize(data))
+	return d.unmarshal(v)
+}
+
+// Standardize converts data, in the JSON5 subset described at
+// Decoder.UseRelaxed, to standard JSON. Comments and trailing commas are
+// replaced by spaces, so that lines are preserved.
+func Standardize(data []byte) ([]byte, error) {
+	scan := newScanner()
+	defer freeScanner(scan)
+	scan.relaxed = true
+	err := checkValid(data, scan)
+	scan.relaxed = false
+	if err != nil {
+		return nil, err
+	}
+	return standardize(data), nil
+}
+
+// beginComment handles the '/' starting a comment, resuming in state
+// resume after the comment.
+func (s *scanner) beginComment(resume func(*scanner, byte) int) int {
+	s.step = stateBeginComment
+	s.resume = resume
+	return scanSkipSpace
+}
+
+// endComment ends the current comment, at the end of input.
+func (s *scanner) endComment() {
+	if s.comment == '/' {
+		s.comment = 0
+		s.step = s.resume
+		return
+	}
+	s.step = stateError
+	s.err = &SyntaxError{msg: "unexpected end of JSON input in comment", Offset: s.bytes}
+}
+
+// stringState returns the state inside the current string literal.
+func (s *scanner) stringState() func(*scanner, byte) int {
+	if s.quote == '\'' {
+		return stateInSingleString
+	}
+	return stateInString
+}
+
+// stateRelaxedValue is the state at the beginning of a value, in relaxed
+// mode, for bytes which do not begin a standard JSON value.
+func stateRelaxedValue(s *scanner, c byte) int {
+	switch c {
+	case '/':
+		return s.beginComment(stateBeginValue)
+	case '\'':
+		s.step = stateInSingleString
+		s.quote = c
+		return scanBeginLiteral
+	case ']':
+		// after a trailing comma
+		if n := len(s.parseState); n > 0 && s.parseState[n-1] == parseArrayValue {
+			return stateEndValue(s, c)
+		}
+	}
+	return s.error(c, "looking for beginning of value")
+}
+
+// stateRelaxedKey is the state at the beginning of an object key after
+// a comma, in relaxed mode, for bytes other than '"'.
+func stateRelaxedKey(s *scanner, c byte) int {
+	switch {
+	case c == '/':
+		return s.beginComment(stateBeginString)
+	case c == '\'':
+		s.step = stateInSingleString
+		s.quote = c
+		return scanBeginLiteral
+	case c == '}':
+		// after a trailing comma
+		s.parseState[len(s.parseState)-1] = parseObjectValue
+		return stateEndValue(s, c)
+	case isIdentStart(c):
+		s.step = stateInIdent
+		return scanBeginLiteral
+	}
+	return s.error(c, "looking for beginning of object key string")
+}
+
+// stateInIdent is the state after reading the first bytes of an object
+// key identifier.
+func stateInIdent(s *scanner, c byte) int {
+	if isIdentStart(c) || '0' <= c && c <= '9' {
+		return scanContinue
+	}
+	return stateEndValue(s, c)
+}
+
+func isIdentStart(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || c == '_' || c == '$'
+}
+
+// stateInSingleString is the state after reading `'`.
+func stateInSingleString(s *scanner, c byte) int {
+	if c == '\'' {
+		s.step = stateEndValue
+		s.quote = 0
+		return scanContinue
+	}
+	if c == '\\' {
+		s.step = stateInSingleStringEsc
+		return scanContinue
+	}
+	if c < 0x20 {
+		return s.error(c, "in string literal")
+	}
+	return scanContinue
+}
+
+// stateInSingleStringEsc is the state after reading `'\` during a single
+// quoted string.
+func stateInSingleStringEsc(s *scanner, c byte) int {
+	if c == '\'' {
+		s.step = stateInSingleString
+		return scanContinue
+	}
+	return stateInStringEsc(s, c)
+}
+
+// stateRelaxed0 is the state after reading `0` during a number, in
+// relaxed mode.
+func stateRelaxed0(s *scanner, c byte) int {
+	if c == 'x' || c == 'X' {
+		s.step = stateHex
+		return scanContinue
+	}
+	return state0(s, c)
+}
+
+// stateHex is the state after reading `0x` during a number.
+func stateHex(s *scanner, c byte) int {
+	if isHex(c) {
+		s.step = stateHex0
+		return scanContinue
+	}
+	return s.error(c, "in hexadecimal numeric literal")
+}
+
+// stateHex0 is the state after reading `0x` and at least one digit during
+// a number, such as after reading `0x1f`.
+func stateHex0(s *scanner, c byte) int {
+	if isHex(c) {
+		return scanContinue
+	}
+	return stateEndValue(s, c)
+}
+
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+// stateBeginComment is the state after reading `/` outside of a literal.
+func stateBeginComment(s *scanner, c byte) int {
+	if c == '/' || c == '*' {
+		s.step = stateInComment
+		s.comment = c
+		return scanSkipSpace
+	}
+	return s.error(c, "looking for beginning of comment")
+}
+
+// stateInComment is the state inside a comment.
+func stateInComment(s *scanner, c byte) int {
+	switch {
+	case s.comment == '/' && c == '\n':
+		s.comment = 0
+		s.step = s.resume
+	case s.comment == '*' && c == '*':
+		s.step = stateInCommentStar
+	}
+	return scanSkipSpace
+}
+
+// stateInCommentStar is the state after reading `*` in a block comment.
+func stateInCommentStar(s *scanner, c byte) int {
+	switch c {
+	case '/':
+		s.comment = 0
+		s.step = s.resume
+	case '*':
+	default:
+		s.step = stateInComment
+	}
+	return scanSkipSpace
+}
+
+// standardize converts data, valid in relaxed mode, to standard JSON.
+func standardize(data []byte) []byte {
+	out := make([]byte, 0, len(data))
+	for i := 0; i < len(data); {
+		c := data[i]
+		switch {
+		case c == '"':
+			end := stringEnd(data, i)
+			out = append(out, data[i:end]...)
+			i = end
+		case c == '\'':
+			i++
+			out = append(out, '"')
+			for ; data[i] != '\''; i++ {
+				switch data[i] {
+				case '"':
+					out = append(out, '\\', '"')
+				case '\\':
+					i++
+					if data[i] != '\'' {
+						out = append(out, '\\')
+					}
+					out = append(out, data[i])
+				default:
+					out = append(out, data[i])
+				}
+			}
+			out = append(out, '"')
+			i++
+		case c == '/':
+			end := commentEnd(data, i)
+			for ; i < end; i++ {
+				if data[i] == '\n' || data[i] == '\r' {
+					out = append(out, data[i])
+				} else {
+					out = append(out, ' ')
+				}
+			}
+		case c == ',':
+			if next := skipRelaxedSpace(data, i+1); next < len(data) && (data[next] == ']' || data[next] == '}') {
+				out = append(out, ' ')
+			} else {
+				out = append(out, ',')
+			}
+			i++
+		case c == '0' && i+1 < len(data) && (data[i+1] == 'x' || data[i+1] == 'X'):
+			end := i + 2
+			for end < len(data) && isHex(data[end]) {
+				end++
+			}
+			n, _ := new(big.Int).SetString(string(data[i+2:end]), 16)
+			out = n.Append(out, 10)
+			i = end
+		case c == '-' && i+2 < len(data) && data[i+1] == '0' && (data[i+2] == 'x' || data[i+2] == 'X'):
+			out = append(out, c)
+			i++
+		case '0' <= c && c <= '9' || c == '-':
+			// copy the whole number, as it may have an exponent
+			end := i + 1
+			for end < len(data) && ('0' <= data[end] && data[end] <= '9' || data[end] == '.' ||
+				data[end] == 'e' || data[end] == 'E' || data[end] == '+' || data[end] == '-') {
+				end++
+			}
+			out = append(out, data[i:end]...)
+			i = end
+		case isIdentStart(c):
+			end := i + 1
+			for end < len(data) && (isIdentStart(data[end]) || '0' <= data[end] && data[end] <= '9') {
+				end++
+			}
+			if next := skipRelaxedSpace(data, end); next < len(data) && data[next] == ':' {
+				out = append(out, '"')
+				out = append(out, data[i:end]...)
+				out = append(out, '"')
+			} else {
+				// true, false or null
+				out = append(out, data[i:end]...)
+			}
+			i = end
+		default:
+			out = append(out, c)
+			i++
+		}
+	}
+	return out
+}
+
+// stringEnd returns the offset after the double quoted string at i.
+func stringEnd(data []byte, i int) int {
+	for i++; i < len(data); i++ {
+		switch data[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1
+		}
+	}
+	return len(data)
+}
+
+// commentEnd returns the offset after the comment at i.
+func commentEnd(data []byte, i int) int {
+	if i+1 < len(data) && data[i+1] == '/' {
+		for i += 2; i < len(data) && data[i] != '\n'; i++ {
+		}
+		return i
+	}
+	for i += 2; i+1 < len(data); i++ {
+		if data[i] == '*' && data[i+1] == '/' {
+			return i + 2
+		}
+	}
+	return len(data)
+}
+
+// skipRelaxedSpace returns the offset of the first byte at or after i
+// which is neither space nor in a comment.
+func skipRelaxedSpace(data []byte, i int) int {
+	for i < len(data) {
+		switch {
+		case isSpace(data[i]):
+			i++
+		case data[i] == '/':
+			i = commentEnd(data, i)
+		default:
+			return i
+		}
+	}
+	return i
+}
@@ -0,0 +1,102 @@
+package json
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestUnmarshalRelaxed(t *testing.T) {
+	tests := []struct {
+		in   string
+		want any
+	}{
+		{`// config
+		{
+			/* the name */ name: 'svc', // trailing
+			"ports": [80, 0x1F90, -0x10,],
+			$ref_1: 'it\'s "quoted"',
+			nested: {a: null, b: true, c: 1.5e2,},
+		}`, map[string]any{
+			"name":   "svc",
+			"ports":  []any{80.0, 8080.0, -16.0},
+			"$ref_1": `it's "quoted"`,
+			"nested": map[string]any{"a": nil, "b": true, "c": 150.0},
+		}},
+		{`[1, /* , */ 2, // ]
+		]`, []any{1.0, 2.0}},
+		{`{'a/*b*/': "c//d", true: false}`, map[string]any{"a/*b*/": "c//d", "true": false}},
+		{`'é\n'`, "é\n"},
+		{`0 // zero`, 0.0},
+		{`{}/**/`, map[string]any{}},
+	}
+	for _, tt := range tests {
+		var v any
+		if err := UnmarshalRelaxed([]byte(tt.in), &v); err != nil {
+			t.Errorf("UnmarshalRelaxed(%s): %v", tt.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(v, tt.want) {
+			t.Errorf("UnmarshalRelaxed(%s) = %#v, want %#v", tt.in, v, tt.want)
+		}
+		if Valid([]byte(tt.in)) {
+			t.Errorf("Valid(%s) = true in strict mode", tt.in)
+		}
+	}
+
+	for _, in := range []string{
+		`[1,,]`, `[,]`, `{,}`, `{a:1,,}`, `{1a: 2}`, `0x`, `0xg`, `1x2`, `'a`, `/ 1`,
+		`[1 /* 2 ]`, `{a: 1} /* x`, `{"a": 1,]`, `{a: b}`, `'\x41'`,
+	} {
+		var v any
+		if err := UnmarshalRelaxed([]byte(in), &v); err == nil {
+			t.Errorf("UnmarshalRelaxed(%s): expected error, got %v", in, v)
+		}
+	}
+}
+
+func TestStandardize(t *testing.T) {
+	in := "{\n  // c\n  a: 0xff, /* x\n y */ b: [1,],\n}"
+	want := "{\n      \n  \"a\": 255,     \n      \"b\": [1 ] \n}"
+	got, err := Standardize([]byte(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Errorf("Standardize =\n%q\nwant\n%q", got, want)
+	}
+	if !Valid(got) {
+		t.Errorf("Standardize output is not valid JSON")
+	}
+}
+
+func TestDecoderRelaxed(t *testing.T) {
+	in := `// first
+	{a: 1,} /* second */ [2,]
+	'three' 0x4 // end`
+	dec := NewDecoder(strings.NewReader(in))
+	dec.UseRelaxed()
+	var got []any
+	for {
+		var v any
+		err := dec.Decode(&v)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, v)
+	}
+	want := []any{map[string]any{"a": 1.0}, []any{2.0}, "three", 4.0}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Decode = %#v, want %#v", got, want)
+	}
+
+	dec = NewDecoder(strings.NewReader(`{a: 1}`))
+	var v any
+	if err := dec.Decode(&v); err == nil {
+		t.Error("expected error in strict mode")
+	}
+}
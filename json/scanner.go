@@ -42,9 +42,18 @@ func checkValid(data []byte, scan *scanner) error {
 }
 
 // A SyntaxError is a description of a JSON syntax error.
+//
+// Line and Column give the position of the last byte read, starting at 1,
+// with columns counting bytes, and Pointer is the JSON Pointer of the
+// value being read, such as /items/3. They are set by Unmarshal and
+// Decoder.Decode.
 type SyntaxError struct {
 	msg    string // description of error
 	Offset int64  // error occurred after reading Offset bytes
+
+	Line    int
+	Column  int
+	Pointer string
 }
 
 func (e *SyntaxError) Error() string { return e.msg }
@@ -80,6 +89,16 @@ type scanner struct {
 	// total bytes consumed, updated by decoder.Decode (and deliberately
 	// not set to zero by scan.reset)
 	bytes int64
+
+	// relaxed accepts the JSON5 subset described at Decoder.UseRelaxed
+	// (deliberately not reset by scan.reset).
+	relaxed bool
+
+	// In relaxed mode: the quote of the current string, the kind of the
+	// current comment ('/' or '*') and the state to resume after it.
+	quote   byte
+	comment byte
+	resume  func(*scanner, byte) int
 }
 
 var scannerPool = sync.Pool{
@@ -150,6 +169,9 @@ func (s *scanner) reset() {
 	s.parseState = s.parseState[0:0]
 	s.err = nil
 	s.endTop = false
+	s.quote = 0
+	s.comment = 0
+	s.resume = nil
 }
 
 // eof tells the scanner that the end of input has been reached.
@@ -158,6 +180,11 @@ func (s *scanner) eof() int {
 	if s.err != nil {
 		return scanError
 	}
+	if s.comment != 0 {
+		if s.endComment(); s.err != nil {
+			return scanError
+		}
+	}
 	if s.endTop {
 		return scanEnd
 	}
@@ -166,7 +193,7 @@ func (s *scanner) eof() int {
 		return scanEnd
 	}
 	if s.err == nil {
-		s.err = &SyntaxError{"unexpected end of JSON input", s.bytes}
+		s.err = &SyntaxError{msg: "unexpected end of JSON input", Offset: s.bytes}
 	}
 	return scanError
 }
@@ -206,6 +233,9 @@ func stateBeginValueOrEmpty(s *scanner, c byte) int {
 	if c == ']' {
 		return stateEndValue(s, c)
 	}
+	if c == '/' && s.relaxed {
+		return s.beginComment(stateBeginValueOrEmpty)
+	}
 	return stateBeginValue(s, c)
 }
 
@@ -229,6 +259,9 @@ func stateBeginValue(s *scanner, c byte) int {
 		return scanBeginLiteral
 	case '0': // beginning of 0.123
 		s.step = state0
+		if s.relaxed {
+			s.step = stateRelaxed0
+		}
 		return scanBeginLiteral
 	case 't': // beginning of true
 		s.step = stateT
@@ -244,6 +277,9 @@ func stateBeginValue(s *scanner, c byte) int {
 		s.step = state1
 		return scanBeginLiteral
 	}
+	if s.relaxed {
+		return stateRelaxedValue(s, c)
+	}
 	return s.error(c, "looking for beginning of value")
 }
 
@@ -257,6 +293,9 @@ func stateBeginStringOrEmpty(s *scanner, c byte) int {
 		s.parseState[n-1] = parseObjectValue
 		return stateEndValue(s, c)
 	}
+	if c == '/' && s.relaxed {
+		return s.beginComment(stateBeginStringOrEmpty)
+	}
 	return stateBeginString(s, c)
 }
 
@@ -269,6 +308,9 @@ func stateBeginString(s *scanner, c byte) int {
 		s.step = stateInString
 		return scanBeginLiteral
 	}
+	if s.relaxed {
+		return stateRelaxedKey(s, c)
+	}
 	return s.error(c, "looking for beginning of object key string")
 }
 
@@ -286,6 +328,9 @@ func stateEndValue(s *scanner, c byte) int {
 		s.step = stateEndValue
 		return scanSkipSpace
 	}
+	if c == '/' && s.relaxed {
+		return s.beginComment(stateEndValue)
+	}
 	ps := s.parseState[n-1]
 	switch ps {
 	case parseObjectKey:
@@ -325,6 +370,10 @@ func stateEndValue(s *scanner, c byte) int {
 // Only space characters should be seen now.
 func stateEndTop(s *scanner, c byte) int {
 	if !isSpace(c) {
+		if c == '/' && s.relaxed {
+			s.beginComment(stateEndTop)
+			return scanEnd
+		}
 		// Complain about non-space byte on next call.
 		s.error(c, "after top-level value")
 	}
@@ -351,7 +400,7 @@ func stateInString(s *scanner, c byte) int {
 func stateInStringEsc(s *scanner, c byte) int {
 	switch c {
 	case 'b', 'f', 'n', 'r', 't', '\\', '/', '"':
-		s.step = stateInString
+		s.step = s.stringState()
 		return scanContinue
 	case 'u':
 		s.step = stateInStringEscU
@@ -393,7 +442,7 @@ func stateInStringEscU12(s *scanner, c byte) int {
 // stateInStringEscU123 is the state after reading `"\u123` during a quoted string.
 func stateInStringEscU123(s *scanner, c byte) int {
 	if '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F' {
-		s.step = stateInString
+		s.step = s.stringState()
 		return scanContinue
 	}
 	// numbers
@@ -404,6 +453,9 @@ func stateInStringEscU123(s *scanner, c byte) int {
 func stateNeg(s *scanner, c byte) int {
 	if c == '0' {
 		s.step = state0
+		if s.relaxed {
+			s.step = stateRelaxed0
+		}
 		return scanContinue
 	}
 	if '1' <= c && c <= '9' {
@@ -588,7 +640,7 @@ func stateError(s *scanner, c byte) int {
 // error records an error and switches to the error state.
 func (s *scanner) error(c byte, context string) int {
 	s.step = stateError
-	s.err = &SyntaxError{"invalid character " + quoteChar(c) + " " + context, s.bytes}
+	s.err = &SyntaxError{msg: "invalid character " + quoteChar(c) + " " + context, Offset: s.bytes}
 	return scanError
 }
 
@@ -0,0 +1,741 @@
+package json
+
+import (
+	"errors"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// A Schema is a compiled JSON Schema, supporting the core and validation
+// vocabularies of draft 2020-12:
+//
+//	$ref $defs $anchor $id (for local references)
+//	allOf anyOf oneOf not if then else dependentSchemas
+//	prefixItems items contains properties patternProperties
+//	additionalProperties propertyNames
+//	type enum const multipleOf maximum exclusiveMaximum minimum
+//	exclusiveMinimum maxLength minLength pattern maxItems minItems
+//	uniqueItems maxContains minContains maxProperties minProperties
+//	required dependentRequired
+//
+// Other keywords, such as format, are annotations and are not validated.
+// References must be local to the document. Patterns use the regexp
+// package syntax.
+type Schema struct {
+	node *jsonNode // source of the schema
+
+	boolean *bool // for the schemas true and false
+	ref     *Schema
+	refURI  string
+
+	types    []string
+	enum     []*jsonNode
+	constVal *jsonNode
+
+	multipleOf       *big.Rat
+	maximum          *big.Rat
+	exclusiveMaximum *big.Rat
+	minimum          *big.Rat
+	exclusiveMinimum *big.Rat
+
+	maxLength     int // -1 when not set, as for the other limits
+	minLength     int
+	pattern       *regexp.Regexp
+	maxItems      int
+	minItems      int
+	uniqueItems   bool
+	maxContains   int
+	minContains   int
+	maxProperties int
+	minProperties int
+
+	required          []string
+	dependentRequired map[string][]string
+
+	allOf            []*Schema
+	anyOf            []*Schema
+	oneOf            []*Schema
+	not              *Schema
+	ifSchema         *Schema
+	thenSchema       *Schema
+	elseSchema       *Schema
+	dependentSchemas map[string]*Schema
+
+	prefixItems          []*Schema
+	items                *Schema
+	contains             *Schema
+	properties           map[string]*Schema
+	patternProperties    []patternSchema
+	additionalProperties *Schema
+	propertyNames        *Schema
+}
+
+type patternSchema struct {
+	re     *regexp.Regexp
+	schema *Schema
+}
+
+// A ValidationError describes a value which does not match a schema.
+type ValidationError struct {
+	Pointer string // JSON Pointer to the value, empty for the root value
+	Offset  int64  // input offset where the value starts
+	Keyword string // schema keyword which failed, such as "minimum"
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	ptr := e.Pointer
+	if ptr == "" {
+		ptr = "root value"
+	}
+	return "json: " + ptr + " (offset " + strconv.FormatInt(e.Offset, 10) + "): " + e.Message
+}
+
+// A SchemaError is returned when a value does not match a schema.
+// It lists all the errors found, in document order.
+type SchemaError struct {
+	Errors []*ValidationError
+}
+
+func (e *SchemaError) Error() string {
+	msg := e.Errors[0].Error()
+	if n := len(e.Errors) - 1; n > 0 {
+		msg += " (and " + strconv.Itoa(n) + " more errors)"
+	}
+	return msg
+}
+
+// CompileSchema parses a JSON Schema document.
+func CompileSchema(data []byte) (*Schema, error) {
+	root, err := parseNode(data, 0)
+	if err != nil {
+		return nil, err
+	}
+	c := &schemaCompiler{root: root, schemas: map[*jsonNode]*Schema{}, anchors: map[string]*jsonNode{}}
+	c.collectAnchors(root)
+	if id := root.member("$id"); id != nil && id.kind == stringNode {
+		c.id = strings.TrimSuffix(id.str, "#")
+	}
+
+	s := c.compile(root, "")
+	for i := 0; i < len(c.refs) && c.err == nil; i++ {
+		c.resolve(c.refs[i])
+	}
+	if c.err != nil {
+		return nil, c.err
+	}
+	return s, nil
+}
+
+// MustCompileSchema is like CompileSchema but panics if the schema
+// cannot be parsed.
+func MustCompileSchema(data []byte) *Schema {
+	s, err := CompileSchema(data)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// MarshalJSON implements the Marshaler interface, returning the source
+// of the schema.
+func (s *Schema) MarshalJSON() ([]byte, error) {
+	e := newEncodeState()
+	defer encodeStatePool.Put(e)
+	s.node.encode(e)
+	return append([]byte(nil), e.Bytes()...), nil
+}
+
+// Validate reports whether data is a JSON value matching the schema.
+// It returns a *SyntaxError if data is not valid JSON, and a *SchemaError
+// if it does not match.
+func (s *Schema) Validate(data []byte) error {
+	return s.validate(data, 0)
+}
+
+// ValidateValue is like Validate with the JSON encoding of v.
+func (s *Schema) ValidateValue(v any) error {
+	data, err := Marshal(v)
+	if err != nil {
+		return err
+	}
+	return s.validate(data, 0)
+}
+
+// validate validates data, whose first byte is at input offset base.
+func (s *Schema) validate(data []byte, base int64) error {
+	n, err := parseNode(data, base)
+	if err != nil {
+		return err
+	}
+	var v schemaValidator
+	v.validate(s, n, "")
+	if len(v.errs) > 0 {
+		return &SchemaError{Errors: v.errs}
+	}
+	return nil
+}
+
+// SetSchema causes Decode to validate each value against s before storing
+// it. A value which does not match is skipped and a *SchemaError is
+// returned, with offsets relative to the start of the input. Values read
+// with Token are not validated. A nil schema disables validation.
+func (dec *Decoder) SetSchema(s *Schema) { dec.schema = s }
+
+// schemaCompiler compiles the subschemas of a schema document
+type schemaCompiler struct {
+	root    *jsonNode
+	id      string
+	schemas map[*jsonNode]*Schema
+	anchors map[string]*jsonNode
+	refs    []*Schema
+	err     error
+}
+
+func (c *schemaCompiler) fail(ptr, keyword, msg string) {
+	if c.err == nil {
+		c.err = errors.New("json: invalid schema at " + ptr + "/" + keyword + ": " + msg)
+	}
+}
+
+func (c *schemaCompiler) collectAnchors(n *jsonNode) {
+	switch n.kind {
+	case objectNode:
+		if a := n.member("$anchor"); a != nil && a.kind == stringNode {
+			c.anchors[a.str] = n
+		}
+		for _, val := range n.vals {
+			c.collectAnchors(val)
+		}
+	case arrayNode:
+		for _, elem := range n.elems {
+			c.collectAnchors(elem)
+		}
+	}
+}
+
+// resolve resolves the reference of s.
+func (c *schemaCompiler) resolve(s *Schema) {
+	ref := s.refURI
+	if c.id != "" && strings.HasPrefix(ref, c.id) {
+		ref = ref[len(c.id):]
+		if ref == "" {
+			ref = "#"
+		}
+	}
+	if !strings.HasPrefix(ref, "#") {
+		c.err = errors.New("json: unsupported schema reference (must be local): " + s.refURI)
+		return
+	}
+
+	var target *jsonNode
+	if ref == "#" || strings.HasPrefix(ref, "#/") {
+		target = c.root.pointer(ref[1:])
+	} else {
+		target = c.anchors[ref[1:]]
+	}
+	if target == nil {
+		c.err = errors.New("json: schema reference not found: " + s.refURI)
+		return
+	}
+	s.ref = c.compile(target, ref[1:])
+}
+
+// compile compiles the schema n, located at ptr in the document.
+func (c *schemaCompiler) compile(n *jsonNode, ptr string) *Schema {
+	if s, ok := c.schemas[n]; ok {
+		return s
+	}
+	s := &Schema{
+		node: n, maxLength: -1, minLength: -1, maxItems: -1, minItems: -1,
+		maxContains: -1, minContains: -1, maxProperties: -1, minProperties: -1,
+	}
+	c.schemas[n] = s
+
+	switch n.kind {
+	case boolNode:
+		b := n.bool
+		s.boolean = &b
+		return s
+	case objectNode:
+	default:
+		c.fail(ptr, "", "schema must be an object or a boolean")
+		return s
+	}
+
+	for i, key := range n.keys {
+		val := n.vals[i]
+		kptr := ptr + "/" + escapePointer(key)
+		switch key {
+		case "$ref":
+			if val.kind != stringNode {
+				c.fail(ptr, key, "must be a string")
+				continue
+			}
+			s.refURI = val.str
+			c.refs = append(c.refs, s)
+		case "$defs":
+			if val.kind != objectNode {
+				c.fail(ptr, key, "must be an object")
+				continue
+			}
+			for j, name := range val.keys {
+				c.compile(val.vals[j], kptr+"/"+escapePointer(name))
+			}
+		case "type":
+			switch val.kind {
+			case stringNode:
+				s.types = []string{val.str}
+			case arrayNode:
+				for _, elem := range val.elems {
+					if elem.kind != stringNode {
+						c.fail(ptr, key, "must be a string or an array of strings")
+					}
+					s.types = append(s.types, elem.str)
+				}
+			default:
+				c.fail(ptr, key, "must be a string or an array of strings")
+			}
+			for _, t := range s.types {
+				switch t {
+				case "null", "boolean", "object", "array", "number", "string", "integer":
+				default:
+					c.fail(ptr, key, "unknown type "+strconv.Quote(t))
+				}
+			}
+		case "enum":
+			if val.kind != arrayNode {
+				c.fail(ptr, key, "must be an array")
+				continue
+			}
+			s.enum = val.elems
+		case "const":
+			s.constVal = val
+		case "multipleOf", "maximum", "exclusiveMaximum", "minimum", "exclusiveMinimum":
+			if val.kind != numberNode {
+				c.fail(ptr, key, "must be a number")
+				continue
+			}
+			switch key {
+			case "multipleOf":
+				if val.num.Sign() <= 0 {
+					c.fail(ptr, key, "must be greater than 0")
+				}
+				s.multipleOf = val.num
+			case "maximum":
+				s.maximum = val.num
+			case "exclusiveMaximum":
+				s.exclusiveMaximum = val.num
+			case "minimum":
+				s.minimum = val.num
+			case "exclusiveMinimum":
+				s.exclusiveMinimum = val.num
+			}
+		case "maxLength", "minLength", "maxItems", "minItems", "maxContains", "minContains", "maxProperties", "minProperties":
+			if val.kind != numberNode || !val.num.IsInt() || val.num.Sign() < 0 || !val.num.Num().IsInt64() {
+				c.fail(ptr, key, "must be a non-negative integer")
+				continue
+			}
+			limit := int(val.num.Num().Int64())
+			switch key {
+			case "maxLength":
+				s.maxLength = limit
+			case "minLength":
+				s.minLength = limit
+			case "maxItems":
+				s.maxItems = limit
+			case "minItems":
+				s.minItems = limit
+			case "maxContains":
+				s.maxContains = limit
+			case "minContains":
+				s.minContains = limit
+			case "maxProperties":
+				s.maxProperties = limit
+			case "minProperties":
+				s.minProperties = limit
+			}
+		case "pattern":
+			if val.kind != stringNode {
+				c.fail(ptr, key, "must be a string")
+				continue
+			}
+			re, err := regexp.Compile(val.str)
+			if err != nil {
+				c.fail(ptr, key, err.Error())
+				continue
+			}
+			s.pattern = re
+		case "uniqueItems":
+			if val.kind != boolNode {
+				c.fail(ptr, key, "must be a boolean")
+				continue
+			}
+			s.uniqueItems = val.bool
+		case "required":
+			s.required = c.stringList(val, ptr, key)
+		case "dependentRequired":
+			if val.kind != objectNode {
+				c.fail(ptr, key, "must be an object")
+				continue
+			}
+			s.dependentRequired = map[string][]string{}
+			for j, name := range val.keys {
+				s.dependentRequired[name] = c.stringList(val.vals[j], ptr, key)
+			}
+		case "allOf", "anyOf", "oneOf", "prefixItems":
+			if val.kind != arrayNode || len(val.elems) == 0 {
+				c.fail(ptr, key, "must be a non-empty array")
+				continue
+			}
+			list := make([]*Schema, len(val.elems))
+			for j, elem := range val.elems {
+				list[j] = c.compile(elem, kptr+"/"+strconv.Itoa(j))
+			}
+			switch key {
+			case "allOf":
+				s.allOf = list
+			case "anyOf":
+				s.anyOf = list
+			case "oneOf":
+				s.oneOf = list
+			case "prefixItems":
+				s.prefixItems = list
+			}
+		case "not":
+			s.not = c.compile(val, kptr)
+		case "if":
+			s.ifSchema = c.compile(val, kptr)
+		case "then":
+			s.thenSchema = c.compile(val, kptr)
+		case "else":
+			s.elseSchema = c.compile(val, kptr)
+		case "items":
+			s.items = c.compile(val, kptr)
+		case "contains":
+			s.contains = c.compile(val, kptr)
+		case "additionalProperties":
+			s.additionalProperties = c.compile(val, kptr)
+		case "propertyNames":
+			s.propertyNames = c.compile(val, kptr)
+		case "properties", "dependentSchemas":
+			if val.kind != objectNode {
+				c.fail(ptr, key, "must be an object")
+				continue
+			}
+			m := make(map[string]*Schema, len(val.keys))
+			for j, name := range val.keys {
+				m[name] = c.compile(val.vals[j], kptr+"/"+escapePointer(name))
+			}
+			if key == "properties" {
+				s.properties = m
+			} else {
+				s.dependentSchemas = m
+			}
+		case "patternProperties":
+			if val.kind != objectNode {
+				c.fail(ptr, key, "must be an object")
+				continue
+			}
+			for j, expr := range val.keys {
+				re, err := regexp.Compile(expr)
+				if err != nil {
+					c.fail(ptr, key, err.Error())
+					continue
+				}
+				sub := c.compile(val.vals[j], kptr+"/"+escapePointer(expr))
+				s.patternProperties = append(s.patternProperties, patternSchema{re: re, schema: sub})
+			}
+		}
+	}
+	return s
+}
+
+func (c *schemaCompiler) stringList(n *jsonNode, ptr, key string) []string {
+	if n.kind != arrayNode {
+		c.fail(ptr, key, "must be an array of strings")
+		return nil
+	}
+	list := make([]string, 0, len(n.elems))
+	for _, elem := range n.elems {
+		if elem.kind != stringNode {
+			c.fail(ptr, key, "must be an array of strings")
+			continue
+		}
+		list = append(list, elem.str)
+	}
+	return list
+}
+
+// maxSchemaDepth bounds the nesting of schemas applied to a single value,
+// so that a reference cycle such as {"$ref": "#"} fails instead of looping.
+const maxSchemaDepth = 1000
+
+// schemaValidator collects the errors of a validation
+type schemaValidator struct {
+	errs  []*ValidationError
+	depth int
+}
+
+func (v *schemaValidator) fail(n *jsonNode, ptr, keyword, msg string) {
+	v.errs = append(v.errs, &ValidationError{Pointer: ptr, Offset: n.offset, Keyword: keyword, Message: msg})
+}
+
+// valid reports whether n matches s, without recording errors.
+func (v *schemaValidator) valid(s *Schema, n *jsonNode, ptr string) bool {
+	sub := schemaValidator{depth: v.depth}
+	sub.validate(s, n, ptr)
+	return len(sub.errs) == 0
+}
+
+func (v *schemaValidator) validate(s *Schema, n *jsonNode, ptr string) {
+	if s.boolean != nil {
+		if !*s.boolean {
+			v.fail(n, ptr, "false", "no value is allowed")
+		}
+		return
+	}
+
+	v.depth++
+	defer func() { v.depth-- }()
+	if v.depth > maxSchemaDepth {
+		v.fail(n, ptr, "$ref", "schema nesting too deep")
+		return
+	}
+
+	if s.ref != nil {
+		v.validate(s.ref, n, ptr)
+	}
+
+	if len(s.types) > 0 {
+		ok := false
+		for _, t := range s.types {
+			if n.hasType(t) {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			v.fail(n, ptr, "type", "must be of type "+strings.Join(s.types, " or ")+", not "+n.typeName())
+			return
+		}
+	}
+
+	if s.enum != nil {
+		ok := false
+		for _, e := range s.enum {
+			if nodeEqual(n, e) {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			v.fail(n, ptr, "enum", "must be one of the enumerated values")
+		}
+	}
+	if s.constVal != nil && !nodeEqual(n, s.constVal) {
+		v.fail(n, ptr, "const", "must be equal to the constant value")
+	}
+
+	switch n.kind {
+	case numberNode:
+		v.validateNumber(s, n, ptr)
+	case stringNode:
+		v.validateString(s, n, ptr)
+	case arrayNode:
+		v.validateArray(s, n, ptr)
+	case objectNode:
+		v.validateObject(s, n, ptr)
+	}
+
+	for _, sub := range s.allOf {
+		v.validate(sub, n, ptr)
+	}
+	if s.anyOf != nil {
+		ok := false
+		for _, sub := range s.anyOf {
+			if v.valid(sub, n, ptr) {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			v.fail(n, ptr, "anyOf", "must match at least one schema of anyOf")
+		}
+	}
+	if s.oneOf != nil {
+		matches := 0
+		for _, sub := range s.oneOf {
+			if v.valid(sub, n, ptr) {
+				matches++
+			}
+		}
+		if matches != 1 {
+			v.fail(n, ptr, "oneOf", "must match exactly one schema of oneOf, matched "+strconv.Itoa(matches))
+		}
+	}
+	if s.not != nil && v.valid(s.not, n, ptr) {
+		v.fail(n, ptr, "not", "must not match the schema of not")
+	}
+	if s.ifSchema != nil {
+		if v.valid(s.ifSchema, n, ptr) {
+			if s.thenSchema != nil {
+				v.validate(s.thenSchema, n, ptr)
+			}
+		} else if s.elseSchema != nil {
+			v.validate(s.elseSchema, n, ptr)
+		}
+	}
+}
+
+func (v *schemaValidator) validateNumber(s *Schema, n *jsonNode, ptr string) {
+	num := n.num
+	if s.multipleOf != nil && !new(big.Rat).Quo(num, s.multipleOf).IsInt() {
+		v.fail(n, ptr, "multipleOf", "must be a multiple of "+s.multipleOf.RatString())
+	}
+	if s.maximum != nil && num.Cmp(s.maximum) > 0 {
+		v.fail(n, ptr, "maximum", "must be <= "+s.maximum.RatString())
+	}
+	if s.exclusiveMaximum != nil && num.Cmp(s.exclusiveMaximum) >= 0 {
+		v.fail(n, ptr, "exclusiveMaximum", "must be < "+s.exclusiveMaximum.RatString())
+	}
+	if s.minimum != nil && num.Cmp(s.minimum) < 0 {
+		v.fail(n, ptr, "minimum", "must be >= "+s.minimum.RatString())
+	}
+	if s.exclusiveMinimum != nil && num.Cmp(s.exclusiveMinimum) <= 0 {
+		v.fail(n, ptr, "exclusiveMinimum", "must be > "+s.exclusiveMinimum.RatString())
+	}
+}
+
+func (v *schemaValidator) validateString(s *Schema, n *jsonNode, ptr string) {
+	if s.maxLength >= 0 || s.minLength >= 0 {
+		length := utf8.RuneCountInString(n.str)
+		if s.maxLength >= 0 && length > s.maxLength {
+			v.fail(n, ptr, "maxLength", "must be at most "+strconv.Itoa(s.maxLength)+" characters long")
+		}
+		if s.minLength >= 0 && length < s.minLength {
+			v.fail(n, ptr, "minLength", "must be at least "+strconv.Itoa(s.minLength)+" characters long")
+		}
+	}
+	if s.pattern != nil && !s.pattern.MatchString(n.str) {
+		v.fail(n, ptr, "pattern", "must match the pattern "+strconv.Quote(s.pattern.String()))
+	}
+}
+
+func (v *schemaValidator) validateArray(s *Schema, n *jsonNode, ptr string) {
+	if s.maxItems >= 0 && len(n.elems) > s.maxItems {
+		v.fail(n, ptr, "maxItems", "must have at most "+strconv.Itoa(s.maxItems)+" items")
+	}
+	if s.minItems >= 0 && len(n.elems) < s.minItems {
+		v.fail(n, ptr, "minItems", "must have at least "+strconv.Itoa(s.minItems)+" items")
+	}
+	if s.uniqueItems {
+	Unique:
+		for i := 1; i < len(n.elems); i++ {
+			for j := 0; j < i; j++ {
+				if nodeEqual(n.elems[i], n.elems[j]) {
+					v.fail(n, ptr, "uniqueItems", "items "+strconv.Itoa(j)+" and "+strconv.Itoa(i)+" must not be equal")
+					break Unique
+				}
+			}
+		}
+	}
+
+	for i, elem := range n.elems {
+		eptr := ptr + "/" + strconv.Itoa(i)
+		if i < len(s.prefixItems) {
+			v.validate(s.prefixItems[i], elem, eptr)
+		} else if s.items != nil {
+			v.validate(s.items, elem, eptr)
+		}
+	}
+
+	if s.contains != nil {
+		matches := 0
+		for i, elem := range n.elems {
+			if v.valid(s.contains, elem, ptr+"/"+strconv.Itoa(i)) {
+				matches++
+			}
+		}
+		minContains := 1
+		if s.minContains >= 0 {
+			minContains = s.minContains
+		}
+		if matches < minContains {
+			v.fail(n, ptr, "contains", "must contain at least "+strconv.Itoa(minContains)+" matching items")
+		}
+		if s.maxContains >= 0 && matches > s.maxContains {
+			v.fail(n, ptr, "maxContains", "must contain at most "+strconv.Itoa(s.maxContains)+" matching items")
+		}
+	}
+}
+
+func (v *schemaValidator) validateObject(s *Schema, n *jsonNode, ptr string) {
+	if s.maxProperties >= 0 && len(n.keys) > s.maxProperties {
+		v.fail(n, ptr, "maxProperties", "must have at most "+strconv.Itoa(s.maxProperties)+" properties")
+	}
+	if s.minProperties >= 0 && len(n.keys) < s.minProperties {
+		v.fail(n, ptr, "minProperties", "must have at least "+strconv.Itoa(s.minProperties)+" properties")
+	}
+	for _, name := range s.required {
+		if n.member(name) == nil {
+			v.fail(n, ptr, "required", "missing required property "+strconv.Quote(name))
+		}
+	}
+	for i, key := range n.keys {
+		for _, name := range s.dependentRequired[key] {
+			if n.member(name) == nil {
+				v.fail(n, ptr, "dependentRequired", "property "+strconv.Quote(name)+" is required when "+strconv.Quote(key)+" is present")
+			}
+		}
+		if sub := s.dependentSchemas[key]; sub != nil {
+			v.validate(sub, n, ptr)
+		}
+
+		val := n.vals[i]
+		mptr := ptr + "/" + escapePointer(key)
+		if s.propertyNames != nil && !v.valid(s.propertyNames, &jsonNode{kind: stringNode, str: key, offset: val.offset}, mptr) {
+			v.fail(val, mptr, "propertyNames", "property name "+strconv.Quote(key)+" is invalid")
+		}
+
+		matched := false
+		if sub := s.properties[key]; sub != nil {
+			matched = true
+			v.validate(sub, val, mptr)
+		}
+		for _, pp := range s.patternProperties {
+			if pp.re.MatchString(key) {
+				matched = true
+				v.validate(pp.schema, val, mptr)
+			}
+		}
+		if !matched && s.additionalProperties != nil {
+			if b := s.additionalProperties.boolean; b != nil && !*b {
+				v.fail(val, mptr, "additionalProperties", "property "+strconv.Quote(key)+" is not allowed")
+			} else {
+				v.validate(s.additionalProperties, val, mptr)
+			}
+		}
+	}
+}
+
+// escapePointer escapes a JSON Pointer reference token.
+func escapePointer(s string) string {
+	if strings.ContainsAny(s, "~/") {
+		s = strings.ReplaceAll(s, "~", "~0")
+		s = strings.ReplaceAll(s, "/", "~1")
+	}
+	return s
+}
+
+// unescapePointer reverses escapePointer.
+func unescapePointer(s string) string {
+	if strings.Contains(s, "~") {
+		s = strings.ReplaceAll(s, "~1", "/")
+		s = strings.ReplaceAll(s, "~0", "~")
+	}
+	return s
+}
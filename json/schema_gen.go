@@ -0,0 +1,190 @@
+package json
+
+import (
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// SchemaDraft is the JSON Schema dialect of the generated schemas.
+const SchemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+// SchemaFor generates a schema for the type of v (typically a struct),
+// describing the JSON produced by Marshal: fields are named with the same
+// json struct tags, and are required unless tagged omitempty. Pointers,
+// slices and maps may be null. Named struct types are placed in $defs,
+// so that recursive types are supported.
+func SchemaFor(v any) (*Schema, error) {
+	t := reflect.TypeOf(v)
+	if t == nil {
+		return nil, &UnsupportedTypeError{Type: t}
+	}
+	gen := &schemaGenerator{root: t, defs: NewOrderedMap(), names: map[reflect.Type]string{}, used: map[string]bool{}}
+	for gen.root.Kind() == reflect.Pointer {
+		gen.root = gen.root.Elem()
+	}
+
+	doc := NewOrderedMap()
+	doc.Set("$schema", SchemaDraft)
+	var root *OrderedMap
+	if gen.root.Kind() == reflect.Struct {
+		root = gen.structSchema(gen.root)
+	} else {
+		root = gen.typeSchema(t)
+	}
+	for _, key := range root.Keys() {
+		val, _ := root.Get(key)
+		doc.Set(key, val)
+	}
+	if gen.defs.Len() > 0 {
+		doc.Set("$defs", gen.defs)
+	}
+	if gen.err != nil {
+		return nil, gen.err
+	}
+
+	data, err := Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	return CompileSchema(data)
+}
+
+type schemaGenerator struct {
+	root  reflect.Type
+	defs  *OrderedMap
+	names map[reflect.Type]string
+	used  map[string]bool
+	err   error
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// typeSchema returns the schema of the values of type t.
+func (gen *schemaGenerator) typeSchema(t reflect.Type) *OrderedMap {
+	nullable := false
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+		nullable = true
+	}
+
+	s := NewOrderedMap()
+	implements := func(it reflect.Type) bool {
+		return t.Implements(it) || reflect.PointerTo(t).Implements(it)
+	}
+	switch {
+	case t == timeType:
+		s.Set("type", "string")
+		s.Set("format", "date-time")
+	case t == numberType:
+		s.Set("type", "number")
+	case implements(marshalerType):
+		return s // any value
+	case implements(textMarshalerType):
+		s.Set("type", "string")
+	default:
+		switch t.Kind() {
+		case reflect.Bool:
+			s.Set("type", "boolean")
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			s.Set("type", "integer")
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			s.Set("type", "integer")
+			s.Set("minimum", 0)
+		case reflect.Float32, reflect.Float64:
+			s.Set("type", "number")
+		case reflect.String:
+			s.Set("type", "string")
+		case reflect.Interface:
+			return s // any value
+		case reflect.Slice:
+			nullable = true
+			if t.Elem().Kind() == reflect.Uint8 && !reflect.PointerTo(t.Elem()).Implements(marshalerType) && !reflect.PointerTo(t.Elem()).Implements(textMarshalerType) {
+				s.Set("type", "string")
+				s.Set("contentEncoding", "base64")
+			} else {
+				s.Set("type", "array")
+				s.Set("items", gen.typeSchema(t.Elem()))
+			}
+		case reflect.Array:
+			s.Set("type", "array")
+			s.Set("items", gen.typeSchema(t.Elem()))
+			s.Set("minItems", t.Len())
+			s.Set("maxItems", t.Len())
+		case reflect.Map:
+			nullable = true
+			s.Set("type", "object")
+			s.Set("additionalProperties", gen.typeSchema(t.Elem()))
+		case reflect.Struct:
+			if t.Name() == "" {
+				s = gen.structSchema(t) // anonymous structs cannot be recursive
+			} else {
+				s.Set("$ref", gen.structRef(t))
+			}
+		default:
+			if gen.err == nil {
+				gen.err = &UnsupportedTypeError{Type: t}
+			}
+			return s
+		}
+	}
+
+	if nullable {
+		if typ, ok := s.Get("type"); ok {
+			s.Set("type", []string{typ.(string), "null"})
+		} else if ref, ok := s.Get("$ref"); ok {
+			s = NewOrderedMap()
+			s.Set("anyOf", []any{map[string]any{"$ref": ref}, map[string]any{"type": "null"}})
+		}
+	}
+	return s
+}
+
+// structRef returns the reference to the definition of the named struct type t,
+// adding it to $defs if needed.
+func (gen *schemaGenerator) structRef(t reflect.Type) string {
+	if t == gen.root {
+		return "#"
+	}
+	if name, ok := gen.names[t]; ok {
+		return "#/$defs/" + escapePointer(name)
+	}
+
+	name := t.Name()
+	for i := 2; gen.used[name]; i++ {
+		name = t.Name() + strconv.Itoa(i)
+	}
+	gen.used[name] = true
+	gen.names[t] = name
+
+	gen.defs.Set(name, nil) // keeps definitions in order of first use
+	gen.defs.Set(name, gen.structSchema(t))
+	return "#/$defs/" + escapePointer(name)
+}
+
+// structSchema returns the schema of the struct type t.
+func (gen *schemaGenerator) structSchema(t reflect.Type) *OrderedMap {
+	s := NewOrderedMap()
+	s.Set("type", "object")
+
+	props := NewOrderedMap()
+	required := []string{}
+	for _, f := range cachedTypeFields(t).list {
+		var fs *OrderedMap
+		if f.quoted {
+			fs = NewOrderedMap()
+			fs.Set("type", "string")
+		} else {
+			fs = gen.typeSchema(typeByIndex(t, f.index))
+		}
+		props.Set(f.name, fs)
+		if !f.omitEmpty {
+			required = append(required, f.name)
+		}
+	}
+	s.Set("properties", props)
+	if len(required) > 0 {
+		s.Set("required", required)
+	}
+	return s
+}
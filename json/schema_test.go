@@ -0,0 +1,231 @@
+package json
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSchemaValidate(t *testing.T) {
+	tests := []struct {
+		schema  string
+		data    string
+		keyword string // empty when valid
+	}{
+		{`true`, `{"a": 1}`, ""},
+		{`false`, `1`, "false"},
+		{`{"type": "integer"}`, `1.0`, ""},
+		{`{"type": "integer"}`, `1.5`, "type"},
+		{`{"type": ["string", "null"]}`, `null`, ""},
+		{`{"type": "number"}`, `"1"`, "type"},
+		{`{"enum": [1, "a", {"b": [true]}]}`, `{"b": [true]}`, ""},
+		{`{"enum": [1, "a"]}`, `2`, "enum"},
+		{`{"const": 10}`, `1e1`, ""},
+		{`{"const": {"a": 1, "b": 2}}`, `{"b": 2, "a": 1}`, ""},
+		{`{"multipleOf": 0.1}`, `0.3`, ""},
+		{`{"multipleOf": 2}`, `3`, "multipleOf"},
+		{`{"maximum": 3}`, `3`, ""},
+		{`{"exclusiveMaximum": 3}`, `3`, "exclusiveMaximum"},
+		{`{"minimum": 1}`, `0.5`, "minimum"},
+		{`{"exclusiveMinimum": 1}`, `1.5`, ""},
+		{`{"maxLength": 2}`, `"héé"`, "maxLength"},
+		{`{"minLength": 3}`, `"héé"`, ""},
+		{`{"pattern": "^a+$"}`, `"aab"`, "pattern"},
+		{`{"maxItems": 1}`, `[1, 2]`, "maxItems"},
+		{`{"minItems": 1}`, `[]`, "minItems"},
+		{`{"uniqueItems": true}`, `[1, 2, 1.0]`, "uniqueItems"},
+		{`{"prefixItems": [{"type": "string"}], "items": {"type": "integer"}}`, `["a", 1, 2]`, ""},
+		{`{"prefixItems": [{"type": "string"}], "items": false}`, `["a", 1]`, "false"},
+		{`{"contains": {"type": "string"}}`, `[1, 2]`, "contains"},
+		{`{"contains": {"type": "string"}, "minContains": 0}`, `[1, 2]`, ""},
+		{`{"contains": {"type": "string"}, "maxContains": 1}`, `["a", "b"]`, "maxContains"},
+		{`{"maxProperties": 1}`, `{"a": 1, "b": 2}`, "maxProperties"},
+		{`{"minProperties": 1}`, `{}`, "minProperties"},
+		{`{"required": ["a", "b"]}`, `{"a": 1}`, "required"},
+		{`{"dependentRequired": {"a": ["b"]}}`, `{"a": 1}`, "dependentRequired"},
+		{`{"dependentRequired": {"a": ["b"]}}`, `{"c": 1}`, ""},
+		{`{"dependentSchemas": {"a": {"required": ["b"]}}}`, `{"a": 1}`, "required"},
+		{`{"properties": {"a": {"type": "string"}}}`, `{"a": 1}`, "type"},
+		{`{"patternProperties": {"^x-": {"type": "integer"}}, "additionalProperties": false}`, `{"x-a": 1}`, ""},
+		{`{"patternProperties": {"^x-": {"type": "integer"}}, "additionalProperties": false}`, `{"y": 1}`, "additionalProperties"},
+		{`{"additionalProperties": {"type": "boolean"}}`, `{"y": 1}`, "type"},
+		{`{"propertyNames": {"maxLength": 3}}`, `{"long": 1}`, "propertyNames"},
+		{`{"allOf": [{"minimum": 1}, {"maximum": 2}]}`, `3`, "maximum"},
+		{`{"anyOf": [{"type": "string"}, {"type": "null"}]}`, `1`, "anyOf"},
+		{`{"oneOf": [{"type": "integer"}, {"type": "number"}]}`, `1`, "oneOf"},
+		{`{"oneOf": [{"type": "integer"}, {"type": "string"}]}`, `1`, ""},
+		{`{"not": {"type": "null"}}`, `null`, "not"},
+		{`{"if": {"type": "string"}, "then": {"minLength": 1}, "else": {"minimum": 0}}`, `""`, "minLength"},
+		{`{"if": {"type": "string"}, "then": {"minLength": 1}, "else": {"minimum": 0}}`, `-1`, "minimum"},
+		{`{"format": "email"}`, `"not an email"`, ""},
+	}
+
+	for _, tt := range tests {
+		s, err := CompileSchema([]byte(tt.schema))
+		if err != nil {
+			t.Errorf("CompileSchema(%s): %v", tt.schema, err)
+			continue
+		}
+		err = s.Validate([]byte(tt.data))
+		var serr *SchemaError
+		switch {
+		case tt.keyword == "" && err != nil:
+			t.Errorf("%s with %s: unexpected error %v", tt.schema, tt.data, err)
+		case tt.keyword != "" && !errors.As(err, &serr):
+			t.Errorf("%s with %s: got %v, want %s error", tt.schema, tt.data, err, tt.keyword)
+		case tt.keyword != "" && serr.Errors[0].Keyword != tt.keyword:
+			t.Errorf("%s with %s: got %s error, want %s", tt.schema, tt.data, serr.Errors[0].Keyword, tt.keyword)
+		}
+	}
+}
+
+func TestSchemaRefs(t *testing.T) {
+	s, err := CompileSchema([]byte(`{
+		"$id": "https://example.com/tree",
+		"type": "object",
+		"properties": {
+			"value": {"$ref": "#/$defs/positive"},
+			"label": {"$ref": "#label"},
+			"children": {"type": "array", "items": {"$ref": "https://example.com/tree"}}
+		},
+		"$defs": {
+			"positive": {"type": "integer", "exclusiveMinimum": 0},
+			"name~/": {"$anchor": "label", "type": "string"}
+		}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = s.Validate([]byte(`{"value": 1, "label": "a", "children": [{"value": 2, "children": [{"value": 0, "label": 1}]}]}`))
+	var serr *SchemaError
+	if !errors.As(err, &serr) || len(serr.Errors) != 2 {
+		t.Fatalf("got %v, want 2 errors", err)
+	}
+	if got := serr.Errors[0].Pointer; got != "/children/0/children/0/value" {
+		t.Errorf("pointer = %s", got)
+	}
+	if got := serr.Errors[1].Pointer; got != "/children/0/children/0/label" {
+		t.Errorf("pointer = %s", got)
+	}
+
+	for _, bad := range []string{
+		`{"$ref": "#/missing"}`,
+		`{"$ref": "other.json"}`,
+		`{"type": "text"}`,
+		`{"minLength": -1}`,
+		`{"pattern": "("}`,
+		`{"properties": []}`,
+		`[]`,
+	} {
+		if _, err := CompileSchema([]byte(bad)); err == nil {
+			t.Errorf("CompileSchema(%s): expected error", bad)
+		}
+	}
+
+	loop := MustCompileSchema([]byte(`{"$ref": "#"}`))
+	if err := loop.Validate([]byte(`1`)); err == nil {
+		t.Error("expected error for reference cycle")
+	}
+}
+
+func TestDecoderSchema(t *testing.T) {
+	s := MustCompileSchema([]byte(`{
+		"type": "object",
+		"required": ["id"],
+		"properties": {"id": {"type": "integer"}, "tags": {"items": {"type": "string"}}}
+	}`))
+
+	const input = `{"id": 1}
+{"id": 2, "tags": ["a", 3]}
+{"id": 3}`
+	dec := NewDecoder(strings.NewReader(input))
+	dec.SetSchema(s)
+
+	var ids []int
+	var verrs []*ValidationError
+	for {
+		var v struct{ ID int }
+		err := dec.Decode(&v)
+		if err == io.EOF {
+			break
+		}
+		var serr *SchemaError
+		if errors.As(err, &serr) {
+			verrs = append(verrs, serr.Errors...)
+			continue
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, v.ID)
+	}
+
+	if !equalInts(ids, []int{1, 3}) {
+		t.Errorf("ids = %v, want [1 3]", ids)
+	}
+	if len(verrs) != 1 {
+		t.Fatalf("got %d errors, want 1", len(verrs))
+	}
+	want := int64(strings.Index(input, "3]"))
+	if e := verrs[0]; e.Pointer != "/tags/1" || e.Offset != want || e.Keyword != "type" {
+		t.Errorf("got %s %d %s, want /tags/1 %d type", e.Pointer, e.Offset, e.Keyword, want)
+	}
+	if msg := verrs[0].Error(); !strings.Contains(msg, "/tags/1") {
+		t.Errorf("message %q does not contain the pointer", msg)
+	}
+}
+
+type schemaNode struct {
+	Name     string         `json:"name"`
+	Count    uint           `json:"count,omitempty"`
+	Score    float64        `json:"score,string"`
+	Created  time.Time      `json:"created"`
+	Data     []byte         `json:"data,omitempty"`
+	Meta     map[string]any `json:"meta,omitempty"`
+	Parent   *schemaNode    `json:"parent,omitempty"`
+	Children []schemaLeaf   `json:"children"`
+	Pair     [2]int         `json:"pair"`
+	Inline   struct {
+		X bool `json:"x"`
+	} `json:"inline"`
+	Ignored string `json:"-"`
+}
+
+type schemaLeaf struct {
+	ID int `json:"id"`
+}
+
+func TestSchemaFor(t *testing.T) {
+	s, err := SchemaFor(&schemaNode{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := s.MarshalJSON()
+	want := `{"$schema":"https://json-schema.org/draft/2020-12/schema","type":"object","properties":{` +
+		`"name":{"type":"string"},"count":{"type":"integer","minimum":0},"score":{"type":"string"},` +
+		`"created":{"type":"string","format":"date-time"},"data":{"type":["string","null"],"contentEncoding":"base64"},` +
+		`"meta":{"type":["object","null"],"additionalProperties":{}},` +
+		`"parent":{"anyOf":[{"$ref":"#"},{"type":"null"}]},` +
+		`"children":{"type":["array","null"],"items":{"$ref":"#/$defs/schemaLeaf"}},` +
+		`"pair":{"type":"array","items":{"type":"integer"},"minItems":2,"maxItems":2},` +
+		`"inline":{"type":"object","properties":{"x":{"type":"boolean"}},"required":["x"]}},` +
+		`"required":["name","score","created","children","pair","inline"],` +
+		`"$defs":{"schemaLeaf":{"type":"object","properties":{"id":{"type":"integer"}},"required":["id"]}}}`
+	if string(b) != want {
+		t.Errorf("SchemaFor =\n%s\nwant\n%s", b, want)
+	}
+
+	v := schemaNode{Name: "a", Score: 1.5, Children: []schemaLeaf{{ID: 1}}, Parent: &schemaNode{Name: "p"}}
+	if err := s.ValidateValue(v); err != nil {
+		t.Errorf("ValidateValue: %v", err)
+	}
+	if err := s.Validate([]byte(`{"name": "a", "children": [{"id": "x"}]}`)); err == nil {
+		t.Error("expected validation error")
+	}
+
+	if _, err := SchemaFor(struct{ C chan int }{}); err == nil {
+		t.Error("expected error for unsupported type")
+	}
+}
@@ -22,6 +22,14 @@ type Decoder struct {
 
 	tokenState int
 	tokenStack []int
+
+	schema                *Schema
+	disallowDuplicateKeys bool
+
+	// lines is the number of lines before buf, and lineStart the offset
+	// of the last one, to locate errors.
+	lines     int
+	lineStart int64
 }
 
 // NewDecoder returns a new decoder that reads from r.
@@ -36,6 +44,11 @@ func NewDecoder(r io.Reader) *Decoder {
 // Number instead of as a float64.
 func (dec *Decoder) UseNumber() { dec.d.useNumber = true }
 
+// UseOrderedObjects causes the Decoder to unmarshal an object into an
+// interface{} as an *OrderedMap instead of as a map[string]interface{},
+// preserving the order of its keys. This applies to nested objects as well.
+func (dec *Decoder) UseOrderedObjects() { dec.d.useOrderedObjects = true }
+
 // DisallowUnknownFields causes the Decoder to return an error when the destination
 // is a struct and the input contains object keys which do not match any
 // non-ignored, exported fields in the destination.
@@ -62,15 +75,49 @@ func (dec *Decoder) Decode(v interface{}) error {
 	// Read whole value into buffer.
 	n, err := dec.readValue()
 	if err != nil {
+		if serr, ok := err.(*SyntaxError); ok && serr.Line == 0 {
+			off := int(errorByte(serr.Offset) - dec.scanned)
+			serr.Line, serr.Column = dec.position(off)
+			if dec.scanp <= off && off <= len(dec.buf) {
+				serr.Pointer = pointerAt(dec.buf[dec.scanp:], off-dec.scanp+1, dec.scan.relaxed)
+			}
+		}
 		return err
 	}
-	dec.d.init(dec.buf[dec.scanp : dec.scanp+n])
+	valueStart := dec.scanp
+	value := dec.buf[dec.scanp : dec.scanp+n]
+	if dec.scan.relaxed {
+		value = standardize(value)
+	}
+	if dec.disallowDuplicateKeys {
+		if derr := duplicateKey(value); derr != nil {
+			derr.Line, derr.Column = dec.position(valueStart + int(derr.Offset))
+			derr.FirstLine, derr.FirstColumn = dec.position(valueStart + int(derr.FirstOffset))
+			derr.Offset += dec.InputOffset()
+			derr.FirstOffset += dec.InputOffset()
+			dec.scanp += n
+			dec.tokenValueEnd()
+			return derr
+		}
+	}
+	if dec.schema != nil {
+		start := dec.InputOffset()
+		if err := dec.schema.validate(value, start); err != nil {
+			dec.scanp += n
+			dec.tokenValueEnd()
+			return err
+		}
+	}
+	dec.d.init(value)
 	dec.scanp += n
 
 	// Don't save err from unmarshal into dec.err:
 	// the connection is still usable since we read a complete JSON
 	// object from it before the error happened.
 	err = dec.d.unmarshal(v)
+	if err != nil {
+		locateError(err, value, false, func(off int) (int, int) { return dec.position(valueStart + off) })
+	}
 
 	// fixup token streaming state
 	dec.tokenValueEnd()
@@ -78,6 +125,16 @@ func (dec *Decoder) Decode(v interface{}) error {
 	return err
 }
 
+// position returns the line and column, starting at 1, of the byte at
+// off in buf.
+func (dec *Decoder) position(off int) (line, col int) {
+	line, col = lineColumn(dec.buf, off)
+	if line == 1 {
+		col = int(dec.scanned + int64(off) - dec.lineStart + 1)
+	}
+	return dec.lines + line, col
+}
+
 // Buffered returns a reader of the data remaining in the Decoder's
 // buffer. The reader is valid until the next call to Decode.
 func (dec *Decoder) Buffered() io.Reader {
@@ -128,7 +185,7 @@ Input:
 				if dec.scan.step(&dec.scan, ' ') == scanEnd {
 					break Input
 				}
-				if nonSpace(dec.buf) {
+				if nonSpace(dec.buf) && !(dec.scan.relaxed && skipRelaxedSpace(dec.buf, 0) == len(dec.buf)) {
 					err = io.ErrUnexpectedEOF
 				}
 			}
@@ -147,6 +204,10 @@ func (dec *Decoder) refill() error {
 	// Make room to read more into the buffer.
 	// First slide down data already consumed.
 	if dec.scanp > 0 {
+		if i := bytes.LastIndexByte(dec.buf[:dec.scanp], '\n'); i >= 0 {
+			dec.lines += bytes.Count(dec.buf[:dec.scanp], []byte{'\n'})
+			dec.lineStart = dec.scanned + int64(i) + 1
+		}
 		dec.scanned += int64(dec.scanp)
 		n := copy(dec.buf, dec.buf[dec.scanp:])
 		dec.buf = dec.buf[:n]
@@ -183,9 +244,18 @@ type Encoder struct {
 	err        error
 	escapeHTML bool
 
+	registry        *Registry
+	floatFormat     func(f float64, bits int) string
+	nilSliceAsEmpty bool
+	nilMapAsEmpty   bool
+	canonical       bool
+
 	indentBuf    *bytes.Buffer
 	indentPrefix string
 	indentValue  string
+
+	// open arrays and objects of the streaming API
+	stack []streamLevel
 }
 
 // NewEncoder returns a new encoder that writes to w.
@@ -209,11 +279,17 @@ func (enc *Encoder) Encode(v any) error {
 // See the documentation for Marshal for details about the
 // conversion of Go values to JSON.
 func (enc *Encoder) EncodeN(v any) (int, error) {
+	return enc.encode(nil, v, enc.indentPrefix)
+}
+
+// encode writes sep followed by the JSON encoding of v, indented after
+// prefix if indenting.
+func (enc *Encoder) encode(sep []byte, v any, prefix string) (int, error) {
 	if enc.err != nil {
 		return 0, enc.err
 	}
 	e := newEncodeState()
-	err := e.marshal(v, encOpts{escapeHTML: enc.escapeHTML})
+	err := e.marshal(v, enc.opts())
 	if err != nil {
 		return 0, err
 	}
@@ -227,17 +303,25 @@ func (enc *Encoder) EncodeN(v any) (int, error) {
 	// e.WriteByte('\n')
 
 	b := e.Bytes()
-	if enc.indentPrefix != "" || enc.indentValue != "" {
+	if enc.canonical {
+		if b, err = Canonicalize(b); err != nil {
+			encodeStatePool.Put(e)
+			return 0, err
+		}
+	} else if enc.indentPrefix != "" || enc.indentValue != "" {
 		if enc.indentBuf == nil {
 			enc.indentBuf = new(bytes.Buffer)
 		}
 		enc.indentBuf.Reset()
-		err = Indent(enc.indentBuf, b, enc.indentPrefix, enc.indentValue)
+		err = Indent(enc.indentBuf, b, prefix, enc.indentValue)
 		if err != nil {
 			return 0, err
 		}
 		b = enc.indentBuf.Bytes()
 	}
+	if len(sep) > 0 {
+		b = append(sep, b...)
+	}
 	var bw int
 	if bw, err = enc.w.Write(b); err != nil {
 		enc.err = err
@@ -246,6 +330,17 @@ func (enc *Encoder) EncodeN(v any) (int, error) {
 	return bw, err
 }
 
+// opts returns the encoding options of the encoder.
+func (enc *Encoder) opts() encOpts {
+	return encOpts{
+		escapeHTML:      enc.escapeHTML,
+		registry:        activeRegistry(enc.registry),
+		floatFormat:     enc.floatFormat,
+		nilSliceAsEmpty: enc.nilSliceAsEmpty,
+		nilMapAsEmpty:   enc.nilMapAsEmpty,
+	}
+}
+
 // SetIndent instructs the encoder to format each subsequent encoded
 // value as if indented by the package-level function Indent(dst, src, prefix, indent).
 // Calling SetIndent("", "") disables indentation.
@@ -325,7 +420,7 @@ func (dec *Decoder) tokenPrepareForDecode() error {
 			return err
 		}
 		if c != ',' {
-			return &SyntaxError{"expected comma after array element", dec.InputOffset()}
+			return &SyntaxError{msg: "expected comma after array element", Offset: dec.InputOffset()}
 		}
 		dec.scanp++
 		dec.tokenState = tokenArrayValue
@@ -335,7 +430,7 @@ func (dec *Decoder) tokenPrepareForDecode() error {
 			return err
 		}
 		if c != ':' {
-			return &SyntaxError{"expected colon after object key", dec.InputOffset()}
+			return &SyntaxError{msg: "expected colon after object key", Offset: dec.InputOffset()}
 		}
 		dec.scanp++
 		dec.tokenState = tokenObjectValue
@@ -488,7 +583,7 @@ func (dec *Decoder) tokenError(c byte) (Token, error) {
 	case tokenObjectComma:
 		context = " after object key:value pair"
 	}
-	return nil, &SyntaxError{"invalid character " + quoteChar(c) + context, dec.InputOffset()}
+	return nil, &SyntaxError{msg: "invalid character " + quoteChar(c) + context, Offset: dec.InputOffset()}
 }
 
 // More reports whether there is another element in the
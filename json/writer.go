@@ -0,0 +1,193 @@
+package json
+
+import (
+	"errors"
+	"strings"
+)
+
+// A streamLevel is an array or object opened with BeginArray or
+// BeginObject.
+type streamLevel struct {
+	object bool
+	n      int  // number of elements or members written
+	key    bool // a key was written, waiting for its value
+}
+
+// BeginArray starts writing an array, whose elements are then written
+// with Value, BeginArray or BeginObject, up to EndArray. Together with
+// BeginObject, Key and EndObject, it writes arbitrarily large documents
+// value by value, with the separators and the indentation set with
+// SetIndent. Like EncodeN, each method returns the number of bytes
+// written. Nothing is written after the top-level value.
+//
+//	enc.BeginArray()
+//	for rows.Next() {
+//		enc.Value(row)
+//	}
+//	enc.EndArray()
+func (enc *Encoder) BeginArray() (int, error) {
+	return enc.begin('[')
+}
+
+// EndArray ends the array started by the last BeginArray.
+func (enc *Encoder) EndArray() (int, error) {
+	return enc.end(']')
+}
+
+// BeginObject starts writing an object, whose members are then written
+// as a Key followed by its value, up to EndObject. See BeginArray.
+func (enc *Encoder) BeginObject() (int, error) {
+	return enc.begin('{')
+}
+
+// EndObject ends the object started by the last BeginObject.
+func (enc *Encoder) EndObject() (int, error) {
+	return enc.end('}')
+}
+
+// Key writes the key of the next member of the current object, whose
+// value is written next with Value, BeginArray or BeginObject.
+func (enc *Encoder) Key(key string) (int, error) {
+	if enc.err != nil {
+		return 0, enc.err
+	}
+	n := len(enc.stack)
+	if n == 0 || !enc.stack[n-1].object {
+		return 0, errors.New("json: Key called outside of an object")
+	}
+	if enc.stack[n-1].key {
+		return 0, errors.New("json: Key called twice without a value")
+	}
+	b := enc.separator()
+	e := newEncodeState()
+	e.string(key, enc.escapeHTML)
+	b = append(b, e.Bytes()...)
+	encodeStatePool.Put(e)
+	b = append(b, ':')
+	if enc.indenting() {
+		b = append(b, ' ')
+	}
+	enc.stack[n-1].key = true
+	return enc.write(b)
+}
+
+// Value writes the JSON encoding of v as the next element of the current
+// array, the value of the current object member, or as a top-level value
+// like EncodeN.
+func (enc *Encoder) Value(v any) (int, error) {
+	if err := enc.valueAllowed(); err != nil {
+		return 0, err
+	}
+	sep := enc.separator()
+	n, err := enc.encode(sep, v, enc.levelPrefix(len(enc.stack)))
+	if err == nil {
+		enc.valueWritten()
+	}
+	return n, err
+}
+
+// begin starts an array or object.
+func (enc *Encoder) begin(delim byte) (int, error) {
+	if enc.err != nil {
+		return 0, enc.err
+	}
+	if err := enc.valueAllowed(); err != nil {
+		return 0, err
+	}
+	b := append(enc.separator(), delim)
+	enc.valueWritten()
+	enc.stack = append(enc.stack, streamLevel{object: delim == '{'})
+	return enc.write(b)
+}
+
+// end ends the current array or object.
+func (enc *Encoder) end(delim byte) (int, error) {
+	if enc.err != nil {
+		return 0, enc.err
+	}
+	name := "EndArray"
+	if delim == '}' {
+		name = "EndObject"
+	}
+	n := len(enc.stack)
+	if n == 0 || enc.stack[n-1].object != (delim == '}') {
+		return 0, errors.New("json: " + name + " called without a matching begin")
+	}
+	level := enc.stack[n-1]
+	if level.key {
+		return 0, errors.New("json: " + name + " called after Key without a value")
+	}
+	enc.stack = enc.stack[:n-1]
+	var b []byte
+	if level.n > 0 && enc.indenting() {
+		b = append(b, '\n')
+		b = append(b, enc.levelPrefix(n-1)...)
+	}
+	b = append(b, delim)
+	return enc.write(b)
+}
+
+// valueAllowed returns an error if a value cannot be written now.
+func (enc *Encoder) valueAllowed() error {
+	if n := len(enc.stack); n > 0 && enc.stack[n-1].object && !enc.stack[n-1].key {
+		return errors.New("json: value written in an object without a Key")
+	}
+	return nil
+}
+
+// valueWritten records a value written in the current array or object.
+func (enc *Encoder) valueWritten() {
+	if n := len(enc.stack); n > 0 {
+		enc.stack[n-1].key = false
+		if !enc.stack[n-1].object {
+			enc.stack[n-1].n++
+		}
+	}
+}
+
+// separator returns the bytes to write before the next element or key
+// of the current array or object.
+func (enc *Encoder) separator() []byte {
+	n := len(enc.stack)
+	if n == 0 {
+		return nil
+	}
+	level := &enc.stack[n-1]
+	if level.object && level.key {
+		// the value of a member
+		return nil
+	}
+	var b []byte
+	if level.n > 0 {
+		b = append(b, ',')
+	}
+	if level.object {
+		level.n++
+	}
+	if enc.indenting() {
+		b = append(b, '\n')
+		b = append(b, enc.levelPrefix(n)...)
+	}
+	return b
+}
+
+// levelPrefix returns the indentation of the values at depth.
+func (enc *Encoder) levelPrefix(depth int) string {
+	if !enc.indenting() {
+		return enc.indentPrefix
+	}
+	return enc.indentPrefix + strings.Repeat(enc.indentValue, depth)
+}
+
+func (enc *Encoder) indenting() bool {
+	return !enc.canonical && (enc.indentPrefix != "" || enc.indentValue != "")
+}
+
+// write writes b to the stream.
+func (enc *Encoder) write(b []byte) (int, error) {
+	n, err := enc.w.Write(b)
+	if err != nil {
+		enc.err = err
+	}
+	return n, err
+}
@@ -0,0 +1,92 @@
+package json
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestEncoderStream(t *testing.T) {
+	v := map[string]any{
+		"name":  "report",
+		"rows":  []any{map[string]any{"id": 1, "tags": []string{"a", "<b>"}}, []int{}, 3},
+		"empty": map[string]any{},
+	}
+	write := func(enc *Encoder) (total int) {
+		step := func(n int, err error) {
+			if err != nil {
+				t.Fatal(err)
+			}
+			total += n
+		}
+		step(enc.BeginObject())
+		step(enc.Key("empty"))
+		step(enc.BeginObject())
+		step(enc.EndObject())
+		step(enc.Key("name"))
+		step(enc.Value("report"))
+		step(enc.Key("rows"))
+		step(enc.BeginArray())
+		step(enc.Value(map[string]any{"id": 1, "tags": []string{"a", "<b>"}}))
+		step(enc.BeginArray())
+		step(enc.EndArray())
+		step(enc.Value(3))
+		step(enc.EndArray())
+		step(enc.EndObject())
+		return total
+	}
+
+	for _, indent := range []string{"", "\t"} {
+		prefix := ""
+		if indent != "" {
+			prefix = ">"
+		}
+		var buf bytes.Buffer
+		enc := NewEncoder(&buf)
+		enc.SetIndent(prefix, indent)
+		n := write(enc)
+
+		want, _ := Marshal(v)
+		if indent != "" {
+			want, _ = MarshalIndent(v, prefix, indent)
+		}
+		if got := buf.String(); got != string(want) {
+			t.Errorf("indent %q:\ngot  %s\nwant %s", indent, got, want)
+		}
+		if n != buf.Len() {
+			t.Errorf("indent %q: counted %d bytes, wrote %d", indent, n, buf.Len())
+		}
+	}
+}
+
+func TestEncoderStreamErrors(t *testing.T) {
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	if _, err := enc.EndArray(); err == nil {
+		t.Error("EndArray without BeginArray: expected error")
+	}
+	if _, err := enc.Key("a"); err == nil {
+		t.Error("Key outside of an object: expected error")
+	}
+	enc.BeginObject()
+	if _, err := enc.Value(1); err == nil {
+		t.Error("Value without Key: expected error")
+	}
+	enc.Key("a")
+	if _, err := enc.Key("b"); err == nil {
+		t.Error("Key after Key: expected error")
+	}
+	if _, err := enc.EndObject(); err == nil {
+		t.Error("EndObject after Key: expected error")
+	}
+	if _, err := enc.Value(make(chan int)); err == nil {
+		t.Error("unsupported value: expected error")
+	}
+	enc.Value(1)
+	if _, err := enc.EndArray(); err == nil {
+		t.Error("EndArray in an object: expected error")
+	}
+	enc.EndObject()
+	if got := buf.String(); got != `{"a":1}` {
+		t.Errorf("got %s", got)
+	}
+}
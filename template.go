@@ -0,0 +1,460 @@
+package g
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/spf13/cast"
+)
+
+// TemplateSyntax is a set of placeholder syntaxes recognized by templates.
+type TemplateSyntax int
+
+const (
+	// TemplateDollar recognizes `${key}` placeholders, with defaults such
+	// as `${key:-default}` and filters such as `${key|upper}`.
+	TemplateDollar TemplateSyntax = 1 << iota
+	// TemplateBraces recognizes `{key}` placeholders, which are replaced
+	// only if the key is defined, without defaults or filters.
+	TemplateBraces
+)
+
+// TemplateEscape is the language of a template, which determines how
+// values are escaped.
+type TemplateEscape string
+
+const (
+	// TemplateEscapeNone inserts values as is.
+	TemplateEscapeNone TemplateEscape = ""
+	// TemplateEscapeSQL escapes values inserted in SQL strings, quoted
+	// identifiers and comments.
+	TemplateEscapeSQL TemplateEscape = "sql"
+	// TemplateEscapeShell escapes values inserted in shell strings and comments.
+	TemplateEscapeShell TemplateEscape = "shell"
+)
+
+// TemplateOptions configures ParseTemplate.
+type TemplateOptions struct {
+	// Syntax is the set of placeholder syntaxes. Defaults to TemplateDollar.
+	Syntax TemplateSyntax
+	// Strict makes Render fail on undefined variables without default, and
+	// ParseTemplate fail on unknown filters and unterminated placeholders.
+	// Otherwise these placeholders are left as is.
+	Strict bool
+	// Escape escapes values according to the context of each placeholder,
+	// as seen by Tokenize: inside a quoted string or identifier, values are
+	// escaped for the quotes, and inside a comment, they cannot end it.
+	// Outside of quotes, SQL values are written as literals, as by the
+	// quote_sql filter, while shell values are inserted as is, see the
+	// quote_shell filter. The raw filter disables escaping.
+	Escape TemplateEscape
+	// Dialect holds the lexical rules of SQL templates. Defaults to DialectGeneric.
+	Dialect *SQLDialect
+}
+
+// TemplateFilter transforms the value of a placeholder.
+type TemplateFilter func(value any) (any, error)
+
+var (
+	templateFilters = map[string]TemplateFilter{
+		"upper":       templateStringFilter(strings.ToUpper),
+		"lower":       templateStringFilter(strings.ToLower),
+		"trim":        templateStringFilter(strings.TrimSpace),
+		"quote_shell": templateStringFilter(quoteShell),
+		"json": func(value any) (any, error) {
+			return Marshal(value), nil
+		},
+	}
+	templateFiltersMu sync.RWMutex
+)
+
+// RegisterTemplateFilter registers the filter fn under name, for the
+// templates parsed afterwards. It replaces a filter registered under the
+// same name, including the built-in ones: upper, lower, trim, quote_sql,
+// quote_shell and json. quote_sql writes a SQL literal escaped for the
+// dialect of the template, or NULL. The raw filter, which inserts the
+// value without the escaping of TemplateOptions.Escape, cannot be
+// replaced.
+func RegisterTemplateFilter(name string, fn TemplateFilter) {
+	templateFiltersMu.Lock()
+	defer templateFiltersMu.Unlock()
+	templateFilters[name] = fn
+}
+
+// Template is a parsed text with placeholders, see ParseTemplate.
+// It is safe for concurrent use.
+type Template struct {
+	text    string
+	options TemplateOptions
+	parts   []templatePart
+}
+
+// templatePart is a literal text or a placeholder.
+type templatePart struct {
+	text        string // the literal text, or the placeholder as written
+	offset      int
+	placeholder bool
+	braces      bool   // a `{key}` placeholder
+	content     string // the trimmed content between the braces
+	key         string
+	fallback    *string // the default value
+	filters     []TemplateFilter
+	invalid     bool // has an unknown filter, replaced only by a key equal to content
+	raw         bool // has the raw filter, not escaped
+	quoted      bool // has the quote_sql filter
+	literal     bool // written as a SQL literal, outside of quotes
+	escape      func(string) (string, error)
+}
+
+// ParseTemplate parses the placeholders of text, to render it with many
+// sets of variables.
+func ParseTemplate(text string, options *TemplateOptions) (*Template, error) {
+	t := &Template{text: text}
+	if options != nil {
+		t.options = *options
+	}
+	if t.options.Syntax == 0 {
+		t.options.Syntax = TemplateDollar
+	}
+	if t.options.Dialect == nil {
+		t.options.Dialect = DialectGeneric
+	}
+
+	last := 0
+	for i := 0; i < len(text); i++ {
+		dollar := text[i] == '$' && i+1 < len(text) && text[i+1] == '{' && t.options.Syntax&TemplateDollar != 0
+		braces := text[i] == '{' && t.options.Syntax&TemplateBraces != 0
+		if !dollar && !braces {
+			continue
+		}
+
+		open := i
+		if dollar {
+			open++
+		}
+		end := strings.IndexByte(text[open:], '}')
+		if end < 0 {
+			if dollar && t.options.Strict {
+				return nil, Error("unterminated placeholder at %s", t.position(i))
+			}
+			break
+		}
+		end += open + 1
+		if strings.IndexByte(text[open+1:end], '{') >= 0 {
+			continue // such as {{key}}, where the inner placeholder is replaced
+		}
+
+		part, err := t.placeholder(text[i:end], i, !dollar)
+		if err != nil {
+			return nil, err
+		}
+		if last < i {
+			t.parts = append(t.parts, templatePart{text: text[last:i], offset: last})
+		}
+		t.parts = append(t.parts, part)
+		last = end
+		i = end - 1
+	}
+	if last < len(text) {
+		t.parts = append(t.parts, templatePart{text: text[last:], offset: last})
+	}
+
+	if t.options.Escape != TemplateEscapeNone {
+		if err := t.setEscapes(); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// placeholder parses a placeholder, such as `${key:-default|filter}`.
+func (t *Template) placeholder(text string, offset int, braces bool) (part templatePart, err error) {
+	part = templatePart{text: text, offset: offset, placeholder: true, braces: braces}
+	if braces {
+		part.content = strings.TrimSpace(text[1 : len(text)-1])
+		part.key = part.content
+		return part, nil
+	}
+
+	part.content = strings.TrimSpace(text[2 : len(text)-1])
+	fields := strings.Split(part.content, "|")
+	part.key = strings.TrimSpace(fields[0])
+	if key, fallback, ok := strings.Cut(part.key, ":-"); ok {
+		part.key = strings.TrimSpace(key)
+		part.fallback = &fallback
+	}
+
+	for _, name := range fields[1:] {
+		name = strings.TrimSpace(name)
+		switch name {
+		case "raw":
+			part.raw = true
+			continue
+		case "quote_sql":
+			dialect := t.options.Dialect
+			part.quoted = true
+			part.filters = append(part.filters, func(value any) (any, error) {
+				return quoteSQL(value, dialect), nil
+			})
+			continue
+		}
+
+		templateFiltersMu.RLock()
+		filter, ok := templateFilters[name]
+		templateFiltersMu.RUnlock()
+		if !ok {
+			if t.options.Strict {
+				return part, Error("unknown template filter %s at %s", name, t.position(offset))
+			}
+			part.invalid = true
+		}
+		part.filters = append(part.filters, filter)
+	}
+	return part, nil
+}
+
+// setEscapes sets the escape function of the placeholders from the token
+// holding them, in the text where placeholders are masked. SQL
+// placeholders joined to other text outside of quotes, such as `t_${x}`
+// or `${x}${y}`, cannot be escaped and require the raw or quote_sql filter.
+func (t *Template) setEscapes() error {
+	dialect := t.options.Dialect
+	if t.options.Escape == TemplateEscapeShell {
+		dialect = shellDialect
+	}
+
+	masked := []byte(t.text)
+	for _, part := range t.parts {
+		if part.placeholder {
+			for i := part.offset; i < part.offset+len(part.text); i++ {
+				masked[i] = '0'
+			}
+		}
+	}
+
+	body := Tokenize(string(masked), &TokenizeOptions{Dialect: dialect})
+	for i := range t.parts {
+		part := &t.parts[i]
+		if !part.placeholder || part.raw {
+			continue
+		}
+		tok := body.TokenAt(part.offset)
+		switch {
+		case tok.IsComment:
+			part.escape = commentEscape(tok)
+		case t.options.Escape == TemplateEscapeShell && tok.Kind == TokenString:
+			part.escape = shellEscape(tok)
+		case t.options.Escape == TemplateEscapeSQL && quoted(tok):
+			part.escape = dialect.quoteEscape(tok)
+		case tok.Start == part.offset && tok.End == part.offset+len(part.text):
+			// a token of its own, outside of quotes and comments
+			part.literal = t.options.Escape == TemplateEscapeSQL && !part.quoted
+		case t.options.Escape == TemplateEscapeSQL && !part.quoted:
+			return Error("placeholder %s at %s is joined to other text outside of quotes: use the raw or quote_sql filter", part.text, t.position(part.offset))
+		}
+	}
+	return nil
+}
+
+// shellDialect holds the lexical rules of shell scripts, for Tokenize.
+var shellDialect = &SQLDialect{
+	Name:                "shell",
+	LineComments:        []string{"#"},
+	DoubleQuotedStrings: true,
+}
+
+func (t *Template) position(offset int) string {
+	var cursor positionCursor
+	p := cursor.position(t.text, offset)
+	return F("line %d, column %d", p.Line, p.Column)
+}
+
+// Render returns the text with the placeholders replaced by the values of
+// vars. Keys are looked up as is, then as paths of nested maps separated
+// by dots, such as `env.HOST`. Defaults are used for undefined, null or
+// empty values.
+func (t *Template) Render(vars map[string]any) (string, error) {
+	var b strings.Builder
+	b.Grow(len(t.text))
+	for _, part := range t.parts {
+		if !part.placeholder {
+			b.WriteString(part.text)
+			continue
+		}
+
+		// a key equal to the content is replaced as is, as by Rm
+		value, ok := vars[part.content]
+		direct := ok || part.braces || part.invalid
+		if !direct {
+			value, ok = lookupTemplateVar(vars, part.key)
+			if (!ok || value == nil || value == "") && part.fallback != nil {
+				value, ok = *part.fallback, true
+			}
+		}
+		if !ok {
+			if t.options.Strict && !part.braces {
+				return "", Error("undefined template variable %s at %s", part.key, t.position(part.offset))
+			}
+			b.WriteString(part.text)
+			continue
+		}
+
+		if !direct {
+			for _, filter := range part.filters {
+				var err error
+				if value, err = filter(value); err != nil {
+					return "", Error(err, "could not render placeholder %s at %s", part.text, t.position(part.offset))
+				}
+			}
+		}
+
+		s := templateString(value)
+		if part.literal {
+			s = quoteSQL(value, t.options.Dialect)
+		}
+		if part.escape != nil {
+			var err error
+			if s, err = part.escape(s); err != nil {
+				return "", Error(err, "could not render placeholder %s at %s", part.text, t.position(part.offset))
+			}
+		}
+		b.WriteString(s)
+	}
+	return b.String(), nil
+}
+
+// RenderTemplate parses and renders text, see ParseTemplate and Template.Render.
+func RenderTemplate(text string, vars map[string]any, options *TemplateOptions) (string, error) {
+	t, err := ParseTemplate(text, options)
+	if err != nil {
+		return "", err
+	}
+	return t.Render(vars)
+}
+
+// lookupTemplateVar returns the value of the key in vars, following the
+// dots of the key through nested maps.
+func lookupTemplateVar(vars map[string]any, key string) (any, bool) {
+	if value, ok := vars[key]; ok {
+		return value, true
+	}
+
+	var current any = vars
+	for _, name := range strings.Split(key, ".") {
+		rv := reflect.ValueOf(current)
+		if rv.Kind() != reflect.Map || rv.Type().Key().Kind() != reflect.String {
+			return nil, false
+		}
+		item := rv.MapIndex(reflect.ValueOf(name).Convert(rv.Type().Key()))
+		if !item.IsValid() {
+			return nil, false
+		}
+		current = item.Interface()
+	}
+	return current, true
+}
+
+// templateString returns the text of a value: strings and scalars as is,
+// null as empty, and other values as JSON.
+func templateString(value any) string {
+	if value == nil {
+		return ""
+	}
+	s, err := cast.ToStringE(value)
+	if err != nil {
+		return Marshal(value)
+	}
+	return s
+}
+
+func templateStringFilter(fn func(string) string) TemplateFilter {
+	return func(value any) (any, error) {
+		return fn(templateString(value)), nil
+	}
+}
+
+// quoteSQL returns the value as a SQL literal for the dialect: null as
+// NULL, numbers and booleans as is, and other values as strings.
+func quoteSQL(value any, dialect *SQLDialect) string {
+	switch value.(type) {
+	case nil:
+		return "NULL"
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64, bool:
+		return templateString(value)
+	}
+	return dialect.QuoteString(templateString(value))
+}
+
+// escapeString escapes s for a string or identifier closed by quote,
+// doubling the quote, and backslashes if escapes is true.
+func (d *SQLDialect) escapeString(s string, quote byte, escapes bool) string {
+	if escapes {
+		s = strings.ReplaceAll(s, `\`, `\\`)
+	}
+	q := string(quote)
+	return strings.ReplaceAll(s, q, q+q)
+}
+
+// quoteEscape returns the escape function of values inserted in the
+// quoted string or identifier token.
+func (d *SQLDialect) quoteEscape(tok Token) func(string) (string, error) {
+	i := strings.IndexAny(tok.Text, "'\"`[$")
+	if i < 0 {
+		return nil
+	}
+
+	switch quote := tok.Text[i]; quote {
+	case '$':
+		tag := dollarTag(tok.Text[i:])
+		return func(s string) (string, error) {
+			if strings.Contains(s, tag) {
+				return "", Error("value contains the quote %s", tag)
+			}
+			return s, nil
+		}
+	case '[':
+		return func(s string) (string, error) {
+			return d.escapeString(s, ']', false), nil
+		}
+	default:
+		escapes := d.BackslashEscapes && (quote == '\'' || quote == '"' && d.DoubleQuotedStrings) ||
+			d.EscapeStrings && quote == '\'' && strings.EqualFold(tok.Text[:i], "E")
+		return func(s string) (string, error) {
+			return d.escapeString(s, quote, escapes), nil
+		}
+	}
+}
+
+// quoteShell returns s as a single-quoted shell word.
+func quoteShell(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// shellEscape returns the escape function of values inserted in the shell
+// string token.
+func shellEscape(tok Token) func(string) (string, error) {
+	if tok.Text[0] == '\'' {
+		return func(s string) (string, error) {
+			return strings.ReplaceAll(s, "'", `'\''`), nil
+		}
+	}
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "$", `\$`, "`", "\\`")
+	return func(s string) (string, error) {
+		return replacer.Replace(s), nil
+	}
+}
+
+// commentEscape returns the escape function of values inserted in the
+// comment token, which prevents them from ending it.
+func commentEscape(tok Token) func(string) (string, error) {
+	if strings.HasPrefix(tok.Text, "/*") {
+		return func(s string) (string, error) {
+			return strings.ReplaceAll(s, "*/", "* /"), nil
+		}
+	}
+	replacer := strings.NewReplacer("\r\n", " ", "\n", " ", "\r", " ")
+	return func(s string) (string, error) {
+		return replacer.Replace(s), nil
+	}
+}
@@ -0,0 +1,142 @@
+package g
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderTemplate(t *testing.T) {
+	vars := map[string]any{
+		"name":  "O'Brien",
+		"empty": "",
+		"n":     3,
+		"tags":  []string{"a", "b"},
+		"env":   map[string]string{"HOST": "db.local"},
+		"a.b":   "dotted",
+	}
+
+	cases := map[string]string{
+		"Hello ${name}":                     "Hello O'Brien",
+		"Hello ${ name | upper }":           "Hello O'BRIEN",
+		"${missing:-guest} ${empty:-none}":  "guest none",
+		"${missing:-guest|upper}":           "GUEST",
+		"${env.HOST}:${n}":                  "db.local:3",
+		"${a.b} ${tags|json}":               `dotted ["a","b"]`,
+		"${name|quote_sql} ${missing|json}": `'O''Brien' ${missing|json}`,
+		"${name|quote_shell}":               `'O'\''Brien'`,
+		"{name} ${undefined}":               "{name} ${undefined}",
+		"${name|unknown}":                   "${name|unknown}",
+		"${name":                            "${name",
+	}
+	for text, expected := range cases {
+		actual, err := RenderTemplate(text, vars, nil)
+		assert.NoError(t, err, text)
+		assert.Equal(t, expected, actual, text)
+	}
+
+	// strict mode
+	strict := &TemplateOptions{Strict: true}
+	_, err := RenderTemplate("select ${undefined}", vars, strict)
+	if assert.Error(t, err) {
+		assert.True(t, strings.Contains(err.Error(), "undefined template variable undefined at line 1, column 7"), err.Error())
+	}
+	_, err = RenderTemplate("${name|unknown}", vars, strict)
+	assert.Error(t, err)
+	_, err = RenderTemplate("${name", vars, strict)
+	assert.Error(t, err)
+	actual, err := RenderTemplate("${missing:-x}", vars, strict)
+	assert.NoError(t, err)
+	assert.Equal(t, "x", actual)
+
+	// compiled once, rendered many times
+	tpl, err := ParseTemplate("{name}/${n}", &TemplateOptions{Syntax: TemplateDollar | TemplateBraces})
+	assert.NoError(t, err)
+	for _, n := range []int{1, 2} {
+		actual, err := tpl.Render(map[string]any{"name": "x", "n": n})
+		assert.NoError(t, err)
+		assert.Equal(t, F("x/%d", n), actual)
+	}
+
+	// filters
+	RegisterTemplateFilter("reverse", func(value any) (any, error) {
+		runes := []rune(templateString(value))
+		for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+			runes[i], runes[j] = runes[j], runes[i]
+		}
+		return string(runes), nil
+	})
+	actual, err = RenderTemplate("${env.HOST|reverse|upper}", vars, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "LACOL.BD", actual)
+
+	// the legacy helpers replace the exact placeholders only
+	m := map[string]any{"HOME": "/root", "name": "x"}
+	assert.Equal(t, "echo ${HOME:-/tmp} ${ HOME }", Rmd("echo ${HOME:-/tmp} ${ HOME }", m))
+	assert.Equal(t, "/root {name|upper}", Rme("${HOME} {name|upper}", m))
+	assert.Equal(t, "x {missing:-y}", Rm("{name} {missing:-y}", m))
+}
+
+func TestRenderTemplateEscape(t *testing.T) {
+	vars := map[string]any{
+		"v":    `it's "x" \ */ $y` + "\nz",
+		"id":   7,
+		"name": "O'Brien",
+	}
+
+	sql := &TemplateOptions{Escape: TemplateEscapeSQL, Dialect: DialectPostgres}
+	text := `select "${v}", '${v}', E'${v}', ${id} -- ${v}` + "\n" + `/* ${v} */ from t where name = ${name|quote_sql}`
+	actual, err := RenderTemplate(text, vars, sql)
+	assert.NoError(t, err)
+	expected := `select "it's ""x"" \ */ $y` + "\nz" + `", 'it''s "x" \ */ $y` + "\nz" + `', E'it''s "x" \\ */ $y` + "\nz" + `', 7 -- it's "x" \ */ $y z` + "\n" +
+		`/* it's "x" \ * / $y` + "\nz" + ` */ from t where name = 'O''Brien'`
+	assert.Equal(t, expected, actual)
+
+	// tokens of the result are the same as those of the template
+	tokens := func(text string) (kinds []TokenKind) {
+		for _, tok := range Tokenize(text, &TokenizeOptions{Dialect: DialectPostgres}).Tokens {
+			kinds = append(kinds, tok.Kind)
+		}
+		return kinds
+	}
+	masked, err := RenderTemplate(text, map[string]any{"v": "v", "id": 7, "name": "n"}, sql)
+	assert.NoError(t, err)
+	assert.Equal(t, tokens(masked), tokens(actual))
+
+	// values outside of quotes are literals, unless raw
+	literals := map[string]any{"id": "1 or 1=1", "n": 2, "null": nil}
+	actual, err = RenderTemplate(`delete from t where id = ${id} and n = ${n} and x is ${null} or ${id|raw} or ${id|quote_sql}`, literals, sql)
+	assert.NoError(t, err)
+	assert.Equal(t, `delete from t where id = '1 or 1=1' and n = 2 and x is NULL or 1 or 1=1 or '1 or 1=1'`, actual)
+	actual, err = RenderTemplate(`select {id}, '{id}'`, literals, &TemplateOptions{Syntax: TemplateBraces, Escape: TemplateEscapeSQL})
+	assert.NoError(t, err)
+	assert.Equal(t, `select '1 or 1=1', '1 or 1=1'`, actual)
+
+	// placeholders joined to other text outside of quotes are rejected
+	glued := map[string]any{"x": "1 or 1=1; drop table t --"}
+	for _, text := range []string{`select * from tbl_${x}`, `select ${x}a`, `a = ${x}${x}`, `a = $${x}`} {
+		_, err = RenderTemplate(text, glued, sql)
+		assert.ErrorContains(t, err, "joined to other text", text)
+	}
+	actual, err = RenderTemplate(`select * from tbl_${x|raw}`, map[string]any{"x": "v"}, sql)
+	assert.NoError(t, err)
+	assert.Equal(t, `select * from tbl_v`, actual)
+
+	// dollar-quoted strings cannot be escaped
+	actual, err = RenderTemplate(`select $$${v}$$`, map[string]any{"v": "a$b"}, sql)
+	assert.NoError(t, err)
+	assert.Equal(t, "select $$a$b$$", actual)
+	_, err = RenderTemplate(`select $$${v}$$`, map[string]any{"v": "a$$b"}, sql)
+	assert.Error(t, err)
+
+	// backslashes are escaped where the dialect reads them
+	actual, err = RenderTemplate(`select '${v}', `+"`${v}`", map[string]any{"v": "a\\'`"}, &TemplateOptions{Escape: TemplateEscapeSQL, Dialect: DialectMySQL})
+	assert.NoError(t, err)
+	assert.Equal(t, "select 'a\\\\''`', `a\\'```", actual)
+
+	shell := &TemplateOptions{Escape: TemplateEscapeShell}
+	actual, err = RenderTemplate(`echo '${v}' "${v}" ${v|quote_shell} # ${v}`, vars, shell)
+	assert.NoError(t, err)
+	assert.Equal(t, `echo 'it'\''s "x" \ */ $y`+"\nz"+`' "it's \"x\" \\ */ \$y`+"\nz"+`" 'it'\''s "x" \ */ $y`+"\nz"+`' # it's "x" \ */ $y z`, actual)
+}
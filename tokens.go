@@ -10,11 +10,14 @@ import (
 
 // Token represents a group of characters in a tokenized text.
 type Token struct {
-	Text             string   `json:"text"`             // The actual text content of the token
-	Index            int      `json:"index"`            // The index of the token in the token list
-	IsComment        bool     `json:"-"`                // Indicates if the token is a comment
-	ParenthesisLevel int      `json:"parenthesisLevel"` // The nesting level of parentheses at this token
-	Position         Position `json:"position"`         // The starting position of the token in the original text
+	Text             string    `json:"text"`             // The actual text content of the token
+	Kind             TokenKind `json:"kind"`             // The lexical class of the token
+	Index            int       `json:"index"`            // The index of the token in the token list
+	IsComment        bool      `json:"-"`                // Indicates if the token is a comment
+	ParenthesisLevel int       `json:"parenthesisLevel"` // The nesting level of parentheses at this token
+	Position         Position  `json:"position"`         // The starting position of the token in the original text
+	Start            int       `json:"start"`            // The byte offset of the token in the original text
+	End              int       `json:"end"`              // The byte offset following the token in the original text
 
 	previous *Token // Pointer to the previous token
 	next     *Token // Pointer to the next token
@@ -426,6 +429,9 @@ func (ts Tokens) Body() *TokenBody {
 type TokenBody struct {
 	Tokens               Tokens
 	baseParenthesisLevel int
+
+	text    string           // the tokenized text, kept for Retokenize
+	options *TokenizeOptions // the options of Tokenize
 }
 
 // AddToken adds a new token to the TokenBody.
@@ -442,6 +448,19 @@ func (tb *TokenBody) AddToken(t Token) Token {
 	return t
 }
 
+// detectBaseParenthesisLevel sets the parenthesis level of the
+// parentheses opened before the first keyword.
+func (tb *TokenBody) detectBaseParenthesisLevel() {
+	tb.baseParenthesisLevel = 0
+	for _, t := range tb.Tokens {
+		if t.Text == "(" {
+			tb.baseParenthesisLevel = t.ParenthesisLevel
+		} else if !t.IsComment && !t.IsWhitespace() && !t.IsOperand() {
+			return
+		}
+	}
+}
+
 var (
 	nonWordRegex = *regexp.MustCompile(`[^_a-zA-Z0-9]`)
 )
@@ -504,6 +523,63 @@ func isNumeric(s string) bool {
 	return isNumeric
 }
 
+// literalEnd returns the end of the number literal starting text[i:], such
+// as 12, 1.5, .5 or 1e-3, or of the numbered parameter, such as $1. It
+// returns i if there is none, or if a word character follows, as in 0x1F,
+// which is left to the word rules.
+func literalEnd(text string, i int) int {
+	j := i
+	switch c := text[i]; {
+	case c == '$':
+		for j++; j < len(text) && isDigitByte(text[j]); j++ {
+		}
+		if j == i+1 {
+			return i
+		}
+	case isDigitByte(c) || c == '.' && i+1 < len(text) && isDigitByte(text[i+1]):
+		j = numberEnd(text, i)
+		if text[j-1] == '.' && j < len(text) && text[j] == '.' {
+			j-- // range, such as 1..2
+		}
+	default:
+		return i
+	}
+	if j < len(text) && isWordByte(text[j]) {
+		return i
+	}
+	return j
+}
+
+// numberEnd returns the offset following the number starting at i.
+func numberEnd(text string, i int) int {
+	digits := func() {
+		for i < len(text) && '0' <= text[i] && text[i] <= '9' {
+			i++
+		}
+	}
+	digits()
+	if i < len(text) && text[i] == '.' {
+		i++
+		digits()
+	}
+	if i+1 < len(text) && (text[i] == 'e' || text[i] == 'E') {
+		j := i + 1
+		if text[j] == '+' || text[j] == '-' {
+			j++
+		}
+		if j < len(text) && '0' <= text[j] && text[j] <= '9' {
+			i = j
+			digits()
+		}
+	}
+	return i
+}
+
+// isDigitByte checks if a byte is an ASCII digit.
+func isDigitByte(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
 // isWhiteByte checks if a byte is a whitespace character (optimized for single bytes)
 func isWhiteByte(c byte) bool {
 	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
@@ -519,57 +595,93 @@ func isOperandByte(c byte) bool {
 	}
 }
 
-type TokenizeOptions struct{}
+// TokenizeOptions configures Tokenize.
+type TokenizeOptions struct {
+	// Dialect holds the lexical rules of the text. Defaults to DialectGeneric.
+	Dialect *SQLDialect
+}
 
 // TokenizeWithMapIDs map of char index to line-column ID
 func Tokenize(text string, options *TokenizeOptions) (body TokenBody) {
+	body = tokenize(text, options, tokenizeState{}, nil)
+	body.detectBaseParenthesisLevel()
+	return body
+}
+
+// tokenizeState is the state of the tokenizer at a token boundary, outside
+// of strings and comments, from which tokenize can resume.
+type tokenizeState struct {
+	offset           int      // the byte offset to resume from
+	parenthesisLevel int      // the parenthesis level at offset
+	start            Position // the position of the token at offset
+	line, column     int      // the line and column counters after offset-1
+	newLine          bool     // the byte at offset-1 is a line feed
+}
+
+// tokenize tokenizes text from the state. When stop returns true for an
+// added token, tokenization stops after it.
+func tokenize(text string, options *TokenizeOptions, state tokenizeState, stop func(Token) bool) (body TokenBody) {
 	// wordChars := CharsToMap("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ1234567890_")
 
+	dialect := options.dialect()
+
 	var tokenBuilder strings.Builder
 	var char, pChar, nChar byte
-	var inTickQ, inSingleQ, inDoubleQ, escaping bool
-	var inCommentLine, inCommentMulti, newLine bool
+	var quote byte      // closing quote of the current string or identifier
+	var quoteTag string // closing tag of a dollar-quoted string
+	var escapes, escaping, skipEnds bool
+	var inCommentLine, isComment, newLine bool
+	var commentDepth, markerEnd, skip int
 	var i, parenthesisLevel, startLineNumber, startColNumber, lineNumber, colNumber int
+	var offset int
+	var stopped bool
 
 	// Pre-allocate token slice capacity (estimate ~1 token per 4 characters)
-	estimatedTokens := len(text) / 4
+	estimatedTokens := (len(text) - state.offset) / 4
+	if stop != nil && estimatedTokens > 1024 {
+		estimatedTokens = 1024 // stopped early, usually
+	}
 	if estimatedTokens < 10 {
 		estimatedTokens = 10
 	}
 	body = TokenBody{
-		Tokens: make(Tokens, 0, estimatedTokens),
+		Tokens:  make(Tokens, 0, estimatedTokens),
+		text:    text,
+		options: options,
 	}
 
 	// Pre-allocate builder capacity
 	tokenBuilder.Grow(32)
 
-	firstKeyword := ""
+	markerEnd = -1
+	offset = state.offset
+	parenthesisLevel = state.parenthesisLevel
+	startLineNumber, startColNumber = state.start.Line, state.start.Column
+	lineNumber, colNumber, newLine = state.line, state.column, state.newLine
 
 	reset := func() {
 		tokenBuilder.Reset()
+		isComment = false
 		startLineNumber = lineNumber
 		startColNumber = colNumber - 1
 	}
 	addTokenAndReset := func() {
-		if tokenBuilder.Len() == 0 {
+		if tokenBuilder.Len() == 0 || stopped {
 			return
 		}
 		t := Token{
 			Text:             tokenBuilder.String(),
+			Kind:             dialect.tokenKind(tokenBuilder.String(), isComment),
+			IsComment:        isComment,
 			ParenthesisLevel: parenthesisLevel,
 			Position:         Position{startLineNumber, startColNumber},
+			Start:            offset,
+			End:              offset + tokenBuilder.Len(),
 		}
+		offset = t.End
 		body.AddToken(t)
 		reset()
-
-		// detect baseParenthesisLevel
-		if firstKeyword == "" {
-			if !t.IsComment && !t.IsWhitespace() && !t.IsOperand() && t.Text != "(" {
-				firstKeyword = t.Text
-			} else if t.Text == "(" {
-				body.baseParenthesisLevel = parenthesisLevel
-			}
-		}
+		stopped = stop != nil && stop(t)
 	}
 	appendByte := func() {
 		tokenBuilder.WriteByte(char)
@@ -583,7 +695,7 @@ func Tokenize(text string, options *TokenizeOptions) (body TokenBody) {
 		appendByte()
 	}
 
-	for i = range text {
+	for i = state.offset; i < len(text) && !stopped; i++ {
 		char = text[i]
 		// previous
 		if i > 0 {
@@ -599,8 +711,8 @@ func Tokenize(text string, options *TokenizeOptions) (body TokenBody) {
 		}
 
 		// Cache these checks per iteration
-		isInQuote := inSingleQ || inDoubleQ || inTickQ
-		isInComment := inCommentLine || inCommentMulti
+		isInQuote := quote != 0
+		isInComment := inCommentLine || commentDepth > 0
 
 		// line & column numbers
 		if char == '\n' {
@@ -613,24 +725,45 @@ func Tokenize(text string, options *TokenizeOptions) (body TokenBody) {
 			colNumber++
 		}
 
+		// bytes consumed with the previous one, such as doubled quotes
+		if skip > 0 {
+			skip--
+			appendByte()
+			if skip == 0 && skipEnds {
+				skipEnds = false
+				addTokenAndReset()
+			}
+			continue
+		}
+
 		// comments
 		{
-			if !isInQuote && !isInComment && char == '-' && nChar == '-' {
+			if !isInQuote && !isInComment && dialect.lineComment(text, i) {
 				addResetAndAppend()
 				inCommentLine = true
+				isComment = true
 				continue
 			} else if inCommentLine && newLine {
 				appendAndAdd()
 				inCommentLine = false
 				continue
-			} else if !isInQuote && !isInComment && char == '/' && nChar == '*' {
-				addTokenAndReset()
-				inCommentMulti = true
+			} else if !isInQuote && (!isInComment || commentDepth > 0 && dialect.NestedComments) && char == '/' && nChar == '*' && i > markerEnd {
+				if commentDepth == 0 {
+					addTokenAndReset()
+				}
+				commentDepth++
+				markerEnd = i + 1
+				isComment = true
 				appendByte()
 				continue
-			} else if inCommentMulti && pChar == '*' && char == '/' {
-				appendAndAdd()
-				inCommentMulti = false
+			} else if commentDepth > 0 && pChar == '*' && char == '/' && i-1 > markerEnd {
+				commentDepth--
+				markerEnd = i
+				if commentDepth == 0 {
+					appendAndAdd()
+				} else {
+					appendByte()
+				}
 				continue
 			} else if isInComment {
 				appendByte()
@@ -638,76 +771,100 @@ func Tokenize(text string, options *TokenizeOptions) (body TokenBody) {
 			}
 		}
 
+		// inside strings & quoted identifiers
+		if isInQuote {
+			switch {
+			case escaping:
+				escaping = false
+			case escapes && char == '\\':
+				escaping = true
+			case quote == '$':
+				if char == '$' && tokenBuilder.Len()+1 >= 2*len(quoteTag) && strings.HasSuffix(tokenBuilder.String(), quoteTag[:len(quoteTag)-1]) {
+					appendAndAdd()
+					quote = 0
+					continue
+				}
+			case char == quote && nChar == quote:
+				// doubled quote, such as 'it''s'
+				skip = 1
+			case char == quote:
+				quote = 0
+				if char != '`' || nChar != '.' {
+					appendAndAdd()
+					continue
+				}
+			}
+			appendByte()
+			continue
+		}
+
+		// numbers, such as 1.5 or .5e-3, and numbered parameters, such as $1
+		if !isWordByte(pChar) && pChar != '$' {
+			if end := literalEnd(text, i); end > i {
+				addTokenAndReset()
+				appendByte()
+				if skip = end - i - 1; skip == 0 {
+					addTokenAndReset()
+				} else {
+					skipEnds = true
+				}
+				continue
+			}
+		}
+
 		// parenthesis
 		{
 			switch {
-			case !isInQuote && char == '(':
+			case char == '(':
 				addTokenAndReset()
 				parenthesisLevel++
 				appendAndAdd()
 				startColNumber++
 				continue
-			case !isInQuote && char == ')':
+			case char == ')':
 				addTokenAndReset()
 				appendAndAdd()
 				startColNumber++
 				parenthesisLevel--
 				continue
+			case char == ',' || char == ';' || char == '.':
+				// punctuation ends operators, such as in `t.*, -1` or `a<>b;`,
+				// so parsers can match it by text
+				addTokenAndReset()
+				appendAndAdd()
+				startColNumber++
+				continue
 			}
 		}
 
 		// string & identifier quotes
 		{
-			if !isInQuote && char == '\'' {
-				inSingleQ = true
-				tokenStr := tokenBuilder.String()
-				if isWhite(tokenStr) || isOperand(tokenStr) {
-					addResetAndAppend()
-					continue
+			quoteTag = ""
+			switch {
+			case char == '\'' || char == '"' || char == '`':
+				quote = char
+			case char == '[' && dialect.BracketIdentifiers:
+				quote = ']'
+			case char == '$' && dialect.DollarQuotes && !isWordByte(pChar):
+				if quoteTag = dollarTag(text[i:]); quoteTag != "" {
+					quote = '$'
 				}
-			} else if inSingleQ && char == '\'' && !escaping {
-				appendAndAdd()
-				inSingleQ = false
-				continue
-			} else if inSingleQ && char == '\\' && !escaping {
-				escaping = true
-			} else if inSingleQ && escaping {
-				escaping = false
 			}
 
-			if !isInQuote && char == '"' {
-				inDoubleQ = true
+			if quote != 0 {
 				tokenStr := tokenBuilder.String()
-				if isWhite(tokenStr) || isOperand(tokenStr) {
+				escapes = dialect.BackslashEscapes && (char == '\'' || char == '"' && dialect.DoubleQuotedStrings) ||
+					dialect.EscapeStrings && char == '\'' && strings.EqualFold(tokenStr, "E")
+				if isWhite(tokenStr) || isOperand(tokenStr) || quote == '$' || quote == ']' {
 					addResetAndAppend()
-					continue
+				} else {
+					appendByte() // prefixed, such as E'...' or N'...'
 				}
-			} else if inDoubleQ && char == '"' {
-				inDoubleQ = false
-				appendAndAdd()
 				continue
 			}
-
-			if !isInQuote && char == '`' {
-				inTickQ = true
-				tokenStr := tokenBuilder.String()
-				if isWhite(tokenStr) || isOperand(tokenStr) {
-					addResetAndAppend()
-					continue
-				}
-			} else if inTickQ && char == '`' {
-				inTickQ = false
-				if nChar != '.' {
-					appendAndAdd()
-					continue
-				}
-			}
-
 		}
 
-		if isInQuote {
-			appendByte()
-		} else if isWhiteByte(char) != isWhiteByte(pChar) {
+		if isWhiteByte(char) != isWhiteByte(pChar) {
 			addResetAndAppend()
 		} else if isOperandByte(char) != isOperandByte(pChar) {
 			addResetAndAppend()
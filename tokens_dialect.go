@@ -0,0 +1,316 @@
+package g
+
+import (
+	"strings"
+)
+
+// TokenKind is the lexical class of a token.
+type TokenKind string
+
+const (
+	TokenKeyword     TokenKind = "keyword"
+	TokenIdentifier  TokenKind = "identifier"
+	TokenString      TokenKind = "string"
+	TokenNumber      TokenKind = "number"
+	TokenParameter   TokenKind = "parameter" // a numbered parameter, such as $1
+	TokenOperator    TokenKind = "operator"
+	TokenPunctuation TokenKind = "punctuation"
+	TokenComment     TokenKind = "comment"
+	TokenWhitespace  TokenKind = "whitespace"
+)
+
+// SQLDialect holds the lexical rules of a SQL dialect, used by Tokenize.
+type SQLDialect struct {
+	Name string
+
+	// LineComments are the markers starting a comment up to the end of the line.
+	LineComments []string
+	// NestedComments allows /* block comments */ to nest.
+	NestedComments bool
+	// BackslashEscapes allows backslash escapes in strings; quotes may
+	// always be escaped by doubling them.
+	BackslashEscapes bool
+	// EscapeStrings enables backslash escapes in E'...' strings.
+	EscapeStrings bool
+	// DollarQuotes enables $$...$$ and $tag$...$tag$ strings.
+	DollarQuotes bool
+	// BracketIdentifiers quotes identifiers between [ and ].
+	BracketIdentifiers bool
+	// DoubleQuotedStrings makes "..." a string rather than an identifier.
+	DoubleQuotedStrings bool
+	// BatchSeparator is a client command which, alone on its line, ends
+	// the current statement, such as GO.
+	BatchSeparator string
+	// UnicodeEscapes enables \uXXXX and \UXXXXXXXX escapes in strings with
+	// backslash escapes.
+	UnicodeEscapes bool
+	// QuestionOperators makes ? an operator, such as the jsonb key exists
+	// operator, so it is not a placeholder unless requested.
+	QuestionOperators bool
+
+	// IdentifierQuote opens quoted identifiers: ", ` or [. Defaults to ".
+	IdentifierQuote byte
+	// UnquotedCase is the case unquoted identifiers are folded to, if any.
+	UnquotedCase KeywordCase
+	// Reserved are the reserved words of the dialect in upper case, in
+	// addition to the common ones.
+	Reserved map[string]struct{}
+}
+
+var (
+	// DialectGeneric is the default dialect, with -- comments and backslash
+	// escapes in strings.
+	DialectGeneric = &SQLDialect{
+		Name:             "generic",
+		LineComments:     []string{"--"},
+		BackslashEscapes: true,
+	}
+
+	DialectPostgres = &SQLDialect{
+		Name:              "postgres",
+		LineComments:      []string{"--"},
+		NestedComments:    true,
+		EscapeStrings:     true,
+		DollarQuotes:      true,
+		QuestionOperators: true,
+		UnquotedCase:      KeywordCaseLower,
+		Reserved: stringSet(
+			"ANALYSE", "ANALYZE", "ARRAY", "ASYMMETRIC", "AUTHORIZATION", "BINARY", "BOTH", "COLLATE",
+			"COLLATION", "CONCURRENTLY", "CURRENT_CATALOG", "CURRENT_ROLE", "CURRENT_SCHEMA", "CURRENT_USER",
+			"DEFERRABLE", "DO", "FREEZE", "ILIKE", "INITIALLY", "ISNULL", "LEADING", "LOCALTIME",
+			"LOCALTIMESTAMP", "NOTNULL", "ONLY", "OVERLAPS", "PLACING", "SESSION_USER", "SIMILAR", "SOME",
+			"SYMMETRIC", "TABLESAMPLE", "TRAILING", "USER", "VARIADIC", "VERBOSE",
+		),
+	}
+
+	DialectMySQL = &SQLDialect{
+		Name:                "mysql",
+		LineComments:        []string{"--", "#"},
+		BackslashEscapes:    true,
+		DoubleQuotedStrings: true,
+		IdentifierQuote:     '`',
+		Reserved: stringSet(
+			"ACCESSIBLE", "ADD", "ANALYZE", "BEFORE", "BIGINT", "BINARY", "BLOB", "BOTH", "CALL", "CASCADE",
+			"CHANGE", "CHAR", "CHARACTER", "COLLATE", "CONDITION", "CONTINUE", "CONVERT", "CUME_DIST",
+			"CURRENT_USER", "CURSOR", "DATABASE", "DATABASES", "DAY_HOUR", "DAY_MINUTE", "DAY_SECOND",
+			"DEC", "DECIMAL", "DECLARE", "DELAYED", "DENSE_RANK", "DESCRIBE", "DIV", "DOUBLE", "DUAL",
+			"EACH", "ELSEIF", "EMPTY", "ENCLOSED", "ESCAPED", "EXIT", "EXPLAIN", "FIRST_VALUE", "FLOAT",
+			"FORCE", "FULLTEXT", "GENERATED", "GROUPING", "GROUPS", "HIGH_PRIORITY", "IGNORE", "INFILE",
+			"INOUT", "INT", "INTEGER", "INTERVAL", "ITERATE", "KEYS", "KILL", "LAG", "LAST_VALUE", "LEAD",
+			"LEADING", "LEAVE", "LINES", "LOAD", "LOCALTIME", "LOCALTIMESTAMP", "LOCK", "LONG", "LOOP",
+			"MATCH", "MOD", "MODIFIES", "NTILE", "NUMERIC", "OPTIMIZE", "OPTION", "OUT", "OUTFILE",
+			"PERCENT_RANK", "PRECISION", "PURGE", "RANGE", "RANK", "READ", "READS", "REAL", "REGEXP",
+			"RELEASE", "RENAME", "REPEAT", "REPLACE", "REQUIRE", "RESIGNAL", "RESTRICT", "RETURN",
+			"REVOKE", "RLIKE", "ROW_NUMBER", "SCHEMA", "SCHEMAS", "SEPARATOR", "SHOW", "SIGNAL",
+			"SMALLINT", "SPATIAL", "SQL", "STARTING", "STRAIGHT_JOIN", "SYSTEM", "TERMINATED", "TINYINT",
+			"TRAILING", "TRIGGER", "UNDO", "UNLOCK", "UNSIGNED", "USAGE", "USE", "UTC_DATE", "UTC_TIME",
+			"UTC_TIMESTAMP", "VARBINARY", "VARCHAR", "WHILE", "WRITE", "XOR", "YEAR_MONTH", "ZEROFILL",
+		),
+	}
+
+	DialectSQLServer = &SQLDialect{
+		Name:               "sqlserver",
+		LineComments:       []string{"--"},
+		NestedComments:     true,
+		BracketIdentifiers: true,
+		BatchSeparator:     "GO",
+		IdentifierQuote:    '[',
+		Reserved: stringSet(
+			"ADD", "AUTHORIZATION", "BACKUP", "BREAK", "BROWSE", "BULK", "CASCADE", "CHECKPOINT", "CLOSE",
+			"CLUSTERED", "COALESCE", "COLLATE", "COMPUTE", "CONTAINS", "CONTAINSTABLE", "CONTINUE",
+			"CONVERT", "CURRENT_USER", "CURSOR", "DATABASE", "DBCC", "DEALLOCATE", "DECLARE", "DENY",
+			"DISK", "DISTRIBUTED", "DOUBLE", "DUMP", "ERRLVL", "ESCAPE", "EXEC", "EXECUTE", "EXIT",
+			"EXTERNAL", "FILE", "FILLFACTOR", "FREETEXT", "FREETEXTTABLE", "GOTO", "HOLDLOCK", "IDENTITY",
+			"IDENTITYCOL", "IDENTITY_INSERT", "KILL", "LINENO", "LOAD", "NATIONAL", "NOCHECK",
+			"NONCLUSTERED", "NULLIF", "OF", "OFF", "OFFSETS", "OPEN", "OPENDATASOURCE", "OPENQUERY",
+			"OPENROWSET", "OPENXML", "OPTION", "PERCENT", "PIVOT", "PLAN", "PRECISION", "PRINT", "PROC",
+			"PUBLIC", "RAISERROR", "READ", "READTEXT", "RECONFIGURE", "REPLICATION", "RESTORE",
+			"RESTRICT", "RETURN", "REVERT", "REVOKE", "ROWCOUNT", "ROWGUIDCOL", "RULE", "SAVE", "SCHEMA",
+			"SECURITYAUDIT", "SESSION_USER", "SETUSER", "SHUTDOWN", "SOME", "STATISTICS", "SYSTEM_USER",
+			"TABLESAMPLE", "TEXTSIZE", "TRAN", "TRANSACTION", "TRIGGER", "TRY_CONVERT", "TSEQUAL",
+			"UNPIVOT", "UPDATETEXT", "USE", "USER", "VARYING", "WAITFOR", "WHILE", "WRITETEXT",
+		),
+	}
+
+	DialectSnowflake = &SQLDialect{
+		Name:             "snowflake",
+		LineComments:     []string{"--", "//"},
+		BackslashEscapes: true,
+		DollarQuotes:     true,
+		UnicodeEscapes:   true,
+		UnquotedCase:     KeywordCaseUpper,
+		Reserved: stringSet(
+			"ACCOUNT", "CONNECT", "CONNECTION", "CURRENT_USER", "DATABASE", "GSCLUSTER", "ILIKE",
+			"INCREMENT", "ISSUE", "LOCALTIME", "LOCALTIMESTAMP", "MINUS", "OF", "ORGANIZATION", "QUALIFY",
+			"REGEXP", "REVOKE", "RLIKE", "SAMPLE", "SCHEMA", "SOME", "START", "TABLESAMPLE", "TRIGGER",
+			"TRY_CAST", "WHENEVER",
+		),
+	}
+
+	DialectBigQuery = &SQLDialect{
+		Name:                "bigquery",
+		LineComments:        []string{"--", "#"},
+		BackslashEscapes:    true,
+		DoubleQuotedStrings: true,
+		UnicodeEscapes:      true,
+		IdentifierQuote:     '`',
+		Reserved: stringSet(
+			"ARRAY", "ASSERT_ROWS_MODIFIED", "AT", "COLLATE", "CONTAINS", "CUBE", "DEFINE", "ENUM", "ESCAPE",
+			"EXCLUDE", "EXTRACT", "GROUPING", "GROUPS", "HASH", "IGNORE", "LOOKUP", "NEW", "NO", "OF",
+			"PROTO", "QUALIFY", "RESPECT", "ROLLUP", "SOME", "STRUCT", "TABLESAMPLE", "TREAT", "UNNEST",
+		),
+	}
+)
+
+// sqlReserved are the reserved words common to the dialects.
+var sqlReserved = stringSet(
+	"ALL", "ALTER", "AND", "ANY", "AS", "ASC", "BEGIN", "BETWEEN", "BY", "CASE", "CAST", "CHECK",
+	"COLUMN", "COMMIT", "CONSTRAINT", "CREATE", "CROSS", "CURRENT_DATE", "CURRENT_TIME",
+	"CURRENT_TIMESTAMP", "DEFAULT", "DELETE", "DESC", "DISTINCT", "DROP", "ELSE", "END", "EXCEPT",
+	"EXISTS", "FALSE", "FETCH", "FOR", "FOREIGN", "FROM", "FULL", "FUNCTION", "GRANT", "GROUP",
+	"HAVING", "IF", "IN", "INDEX", "INNER", "INSERT", "INTERSECT", "INTO", "IS", "JOIN", "KEY",
+	"LATERAL", "LEFT", "LIKE", "LIMIT", "MERGE", "NATURAL", "NOT", "NULL", "OFFSET", "ON", "OR",
+	"ORDER", "OUTER", "OVER", "PARTITION", "PRIMARY", "PROCEDURE", "RECURSIVE", "REFERENCES",
+	"RETURNING", "RIGHT", "ROLLBACK", "SELECT", "SET", "TABLE", "THEN", "TO", "TOP", "TRUE",
+	"TRUNCATE", "UNION", "UNIQUE", "UPDATE", "USING", "VALUES", "VIEW", "WHEN", "WHERE", "WINDOW",
+	"WITH",
+)
+
+// sqlNonReserved are the keywords common to the dialects which may be
+// used unquoted as names, although they rarely are.
+var sqlNonReserved = stringSet(
+	"CURRENT", "DAY", "FILTER", "FIRST", "FOLLOWING", "HOUR", "INTERVAL", "LAST", "MATCHED", "MINUTE",
+	"MONTH", "NULLS", "ONLY", "PRECEDING", "RANGE", "ROW", "ROWS", "SECOND", "UNBOUNDED", "WITHIN", "YEAR",
+)
+
+// stringSet returns a set of the given strings.
+func stringSet(vals ...string) map[string]struct{} {
+	set := make(map[string]struct{}, len(vals))
+	for _, val := range vals {
+		set[val] = struct{}{}
+	}
+	return set
+}
+
+// IsKeyword returns true if word is a SQL keyword common to the dialects,
+// ignoring case, which Tokenize gives the TokenKeyword kind. The reserved
+// words of the dialect only decide which identifiers are quoted, see
+// SQLDialect.IsReserved, as many of them are common names, such as PLAN
+// or FILE.
+func IsKeyword(word string) bool {
+	word = strings.ToUpper(word)
+	if _, ok := sqlNonReserved[word]; ok {
+		return true
+	}
+	_, ok := sqlReserved[word]
+	return ok
+}
+
+// IsReserved returns true if word is a reserved word of the dialect,
+// ignoring case, which cannot be used unquoted as a name.
+func (d *SQLDialect) IsReserved(word string) bool {
+	if d == nil {
+		d = DialectGeneric
+	}
+	return d.isReserved(strings.ToUpper(word))
+}
+
+func (d *SQLDialect) isReserved(word string) bool {
+	if _, ok := sqlReserved[word]; ok {
+		return true
+	}
+	_, ok := d.Reserved[word]
+	return ok
+}
+
+// dialect returns the dialect of the options, or the generic one.
+func (o *TokenizeOptions) dialect() *SQLDialect {
+	if o == nil || o.Dialect == nil {
+		return DialectGeneric
+	}
+	return o.Dialect
+}
+
+// lineComment returns true if a line comment starts at text[i:].
+func (d *SQLDialect) lineComment(text string, i int) bool {
+	for _, marker := range d.LineComments {
+		if strings.HasPrefix(text[i:], marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// dollarTag returns the $tag$ starting text, or an empty string.
+func dollarTag(text string) string {
+	if len(text) < 2 || text[0] != '$' {
+		return ""
+	}
+	for i := 1; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case c == '$':
+			return text[:i+1]
+		case c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z':
+		case '0' <= c && c <= '9' && i > 1:
+		default:
+			return ""
+		}
+	}
+	return ""
+}
+
+// tokenKind classifies the text of a token.
+func (d *SQLDialect) tokenKind(text string, comment bool) TokenKind {
+	switch {
+	case comment:
+		return TokenComment
+	case isWhite(text):
+		return TokenWhitespace
+	case text == "(" || text == ")":
+		return TokenPunctuation
+	}
+
+	switch c := text[0]; {
+	case c == '\'':
+		return TokenString
+	case c == '"':
+		if d.DoubleQuotedStrings {
+			return TokenString
+		}
+		return TokenIdentifier
+	case c == '`' || c == '[' && d.BracketIdentifiers:
+		return TokenIdentifier
+	case c == '$' && d.DollarQuotes && dollarTag(text) != "":
+		return TokenString
+	case c == '$' && literalEnd(text, 0) == len(text):
+		return TokenParameter
+	case (c == '.' || '0' <= c && c <= '9') && literalEnd(text, 0) == len(text):
+		return TokenNumber
+	case isOperand(text):
+		if strings.Trim(text, ",;.") == "" {
+			return TokenPunctuation
+		}
+		return TokenOperator
+	case '0' <= c && c <= '9':
+		if len(text) > 2 && (text[1] == 'x' || text[1] == 'X') && text[0] == '0' {
+			return TokenNumber
+		}
+		return TokenIdentifier
+	case c == '@' || c == '#' || c >= 0x80 || isWordByte(c):
+		// prefixed strings, such as E'...' or N'...'
+		if q := strings.IndexByte(text, '\''); q > 0 && q <= 2 && len(text) > q+1 && text[len(text)-1] == '\'' && isWord(text[:q]) {
+			return TokenString
+		}
+		if IsKeyword(text) {
+			return TokenKeyword
+		}
+		return TokenIdentifier
+	}
+	return TokenOperator
+}
+
+// isWordByte checks if a byte is a word character.
+func isWordByte(c byte) bool {
+	return c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9'
+}
@@ -0,0 +1,977 @@
+package g
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/spf13/cast"
+)
+
+// FilterExpr is a compiled filter expression: a boolean expression over
+// the fields of a record, such as `(t = 'One' or t ~ '^T') and n > 3`.
+// It is safe for concurrent use.
+//
+// The language supports `and`, `or`, `not`, parentheses, the comparison
+// operators `= == != <> < <= > >=`, `[not] in (...)`, `[not] like` and
+// `ilike` with `%` and `_` wildcards, `~` and `!~` regular expressions,
+// `[not] between`, `is [not] null`, the arithmetic operators `+ - * / %`,
+// `||` concatenation and function calls (see RegisterFilterFunc).
+// Strings are single or double quoted, and fields with special characters
+// are quoted with backticks. Numeric strings compare as numbers. Null
+// equals only null, and is neither lower nor greater than other values.
+type FilterExpr struct {
+	text   string
+	root   filterNode
+	fields []string
+}
+
+// FilterFunc is a function callable from filter expressions.
+type FilterFunc func(args ...any) (any, error)
+
+var (
+	filterFuncs = map[string]FilterFunc{
+		"lower":    filterStringFunc(strings.ToLower),
+		"upper":    filterStringFunc(strings.ToUpper),
+		"trim":     filterStringFunc(strings.TrimSpace),
+		"length":   filterLength,
+		"substr":   filterSubstr,
+		"replace":  filterReplace,
+		"concat":   filterConcat,
+		"coalesce": filterCoalesce,
+		"abs":      filterMathFunc(math.Abs),
+		"floor":    filterMathFunc(math.Floor),
+		"ceil":     filterMathFunc(math.Ceil),
+		"round":    filterRound,
+	}
+	filterFuncsMu sync.RWMutex
+)
+
+// RegisterFilterFunc registers the function fn under name, case-insensitive,
+// for the filter expressions compiled afterwards. It replaces a function
+// registered under the same name, including the built-in ones: lower,
+// upper, trim, length, substr, replace, concat, coalesce, abs, floor,
+// ceil and round.
+func RegisterFilterFunc(name string, fn FilterFunc) {
+	filterFuncsMu.Lock()
+	defer filterFuncsMu.Unlock()
+	filterFuncs[strings.ToLower(name)] = fn
+}
+
+// CompileFilter parses the filter expression, to evaluate it on many records.
+func CompileFilter(expr string) (*FilterExpr, error) {
+	tokens, err := lexFilter(expr)
+	if err != nil {
+		return nil, err
+	}
+	p := &filterParser{text: expr, tokens: tokens}
+	if len(tokens) == 0 {
+		return nil, Error("empty filter expression")
+	}
+	root, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(tokens) {
+		return nil, p.unexpected()
+	}
+	return &FilterExpr{text: expr, root: root, fields: p.fields}, nil
+}
+
+// String returns the text of the expression.
+func (f *FilterExpr) String() string {
+	return f.text
+}
+
+// Fields returns the names of the fields read by the expression.
+func (f *FilterExpr) Fields() []string {
+	return f.fields
+}
+
+// Eval returns the value of the expression for the record. Fields missing
+// from the record are null, and are looked up case-insensitively if no
+// key matches exactly.
+func (f *FilterExpr) Eval(record map[string]any) (any, error) {
+	return f.root.eval(record)
+}
+
+// Match returns true if the expression is true for the record.
+func (f *FilterExpr) Match(record map[string]any) (bool, error) {
+	value, err := f.root.eval(record)
+	if err != nil {
+		return false, err
+	}
+	return filterTruth(value), nil
+}
+
+// filterToken is a lexeme of a filter expression.
+type filterToken struct {
+	kind   byte // filterNumber, filterString, filterField, filterWord or filterOperator
+	text   string
+	offset int
+}
+
+const (
+	filterNumber   = 'n'
+	filterString   = 's'
+	filterField    = 'f' // a quoted field name
+	filterWord     = 'w' // a keyword, or a field or function name
+	filterOperator = 'o'
+)
+
+var filterOperators = []string{"<=", ">=", "<>", "!=", "==", "!~", "||", "=", "<", ">", "~", "+", "-", "*", "/", "%", "(", ")", ","}
+
+var filterKeywords = stringSet("AND", "OR", "NOT", "IN", "LIKE", "ILIKE", "BETWEEN", "IS", "NULL", "TRUE", "FALSE")
+
+// lexFilter splits the expression in lexemes. Strings, quoted names and
+// comments are read with Tokenize, while the bytes between them are
+// scanned again, as numbers and operators may span several tokens.
+func lexFilter(text string) (lexemes []filterToken, err error) {
+	tokens := Tokenize(text, nil).Tokens
+	for i := 0; i < len(tokens); i++ {
+		tok := tokens[i]
+		switch {
+		case tok.IsComment || tok.Kind == TokenWhitespace:
+			continue
+		case tok.Kind == TokenString || tok.Text[0] == '"':
+			value, ok := unquoteFilter(tok.Text)
+			if !ok {
+				return nil, Error("unterminated string at offset %d of filter expression: %s", tok.Start, text)
+			}
+			lexemes = append(lexemes, filterToken{filterString, value, tok.Start})
+			continue
+		case tok.Text[0] == '`' || tok.Text[0] == '[':
+			value, ok := unquoteFilter(tok.Text)
+			if !ok {
+				return nil, Error("unterminated name at offset %d of filter expression: %s", tok.Start, text)
+			}
+			lexemes = append(lexemes, filterToken{filterField, value, tok.Start})
+			continue
+		}
+
+		// the run of unquoted code
+		j := i + 1
+		for j < len(tokens) && !tokens[j].IsComment && !quoted(tokens[j]) && tokens[j].Kind != TokenWhitespace {
+			j++
+		}
+		start, end := tok.Start, tokens[j-1].End
+		i = j - 1
+
+		for k := start; k < end; {
+			lexeme := filterToken{offset: k}
+			c := text[k]
+			switch {
+			case '0' <= c && c <= '9' || c == '.' && k+1 < end && '0' <= text[k+1] && text[k+1] <= '9':
+				lexeme.kind = filterNumber
+				k = numberEnd(text[:end], k)
+			case isNameStart(c):
+				lexeme.kind = filterWord
+				for k++; k < end && (isWordByte(text[k]) || text[k] == '.' && k+1 < end && isNameStart(text[k+1])); k++ {
+				}
+			default:
+				for _, op := range filterOperators {
+					if strings.HasPrefix(text[k:end], op) {
+						lexeme.kind = filterOperator
+						k += len(op)
+						break
+					}
+				}
+				if lexeme.kind == 0 {
+					return nil, Error("unexpected character %q at offset %d of filter expression: %s", c, k, text)
+				}
+			}
+			lexeme.text = text[lexeme.offset:k]
+			lexemes = append(lexemes, lexeme)
+		}
+	}
+	return lexemes, nil
+}
+
+// unquoteFilter returns the content of a quoted string or name, with
+// doubled quotes and backslash escapes resolved.
+func unquoteFilter(text string) (string, bool) {
+	closing := text[0]
+	if closing == '[' {
+		closing = ']'
+	}
+	if len(text) < 2 || text[len(text)-1] != closing {
+		return "", false
+	}
+	inner := text[1 : len(text)-1]
+	if strings.IndexByte(inner, closing) < 0 && strings.IndexByte(inner, '\\') < 0 {
+		return inner, true
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(inner); i++ {
+		c := inner[i]
+		switch {
+		case c == '\\' && closing != ']' && closing != '`' && i+1 < len(inner):
+			i++
+			c = inner[i]
+		case c == closing && i+1 < len(inner) && inner[i+1] == closing:
+			i++
+		}
+		b.WriteByte(c)
+	}
+	return b.String(), true
+}
+
+// filterParser builds the nodes of a filter expression, by precedence.
+type filterParser struct {
+	text   string
+	tokens []filterToken
+	pos    int
+	fields []string
+}
+
+func (p *filterParser) peek() filterToken {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return filterToken{offset: len(p.text)}
+}
+
+// keyword returns true if the token is the keyword.
+func (tok filterToken) keyword(word string) bool {
+	return tok.kind == filterWord && strings.EqualFold(tok.text, word)
+}
+
+// acceptKeyword skips the keyword if it follows.
+func (p *filterParser) acceptKeyword(word string) bool {
+	if p.peek().keyword(word) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// acceptOperator skips and returns the first of the operators which follows.
+func (p *filterParser) acceptOperator(ops ...string) (string, bool) {
+	tok := p.peek()
+	if tok.kind == filterOperator && In(tok.text, ops...) {
+		p.pos++
+		return tok.text, true
+	}
+	return "", false
+}
+
+func (p *filterParser) expectOperator(op string) error {
+	if _, ok := p.acceptOperator(op); !ok {
+		return p.unexpected()
+	}
+	return nil
+}
+
+func (p *filterParser) unexpected() error {
+	tok := p.peek()
+	if tok.kind == 0 {
+		return Error("unexpected end of filter expression: %s", p.text)
+	}
+	return Error("unexpected %s at offset %d of filter expression: %s", tok.text, tok.offset, p.text)
+}
+
+func (p *filterParser) or() (filterNode, error) {
+	x, err := p.and()
+	for err == nil && p.acceptKeyword("or") {
+		var y filterNode
+		if y, err = p.and(); err == nil {
+			x = &filterLogical{or: true, x: x, y: y}
+		}
+	}
+	return x, err
+}
+
+func (p *filterParser) and() (filterNode, error) {
+	x, err := p.not()
+	for err == nil && p.acceptKeyword("and") {
+		var y filterNode
+		if y, err = p.not(); err == nil {
+			x = &filterLogical{x: x, y: y}
+		}
+	}
+	return x, err
+}
+
+func (p *filterParser) not() (filterNode, error) {
+	if p.acceptKeyword("not") {
+		x, err := p.not()
+		return &filterNot{x: x}, err
+	}
+	return p.comparison()
+}
+
+func (p *filterParser) comparison() (filterNode, error) {
+	x, err := p.additive()
+	if err != nil {
+		return nil, err
+	}
+
+	if op, ok := p.acceptOperator("=", "==", "!=", "<>", "<", "<=", ">", ">=", "~", "!~"); ok {
+		y, err := p.additive()
+		if err != nil {
+			return nil, err
+		}
+		switch op {
+		case "~", "!~":
+			return newFilterMatch(x, y, op == "!~", regexp.Compile)
+		}
+		return &filterCompare{op: op, x: x, y: y}, nil
+	}
+
+	if p.acceptKeyword("is") {
+		negate := p.acceptKeyword("not")
+		if !p.acceptKeyword("null") {
+			return nil, p.unexpected()
+		}
+		return &filterIsNull{x: x, negate: negate}, nil
+	}
+
+	negate := false
+	if p.peek().keyword("not") {
+		negate = true
+		p.pos++
+	}
+	switch {
+	case p.acceptKeyword("in"):
+		node := &filterIn{x: x, negate: negate}
+		if err := p.expectOperator("("); err != nil {
+			return nil, err
+		}
+		for {
+			item, err := p.additive()
+			if err != nil {
+				return nil, err
+			}
+			node.list = append(node.list, item)
+			if _, ok := p.acceptOperator(","); !ok {
+				break
+			}
+		}
+		return node, p.expectOperator(")")
+	case p.peek().keyword("like") || p.peek().keyword("ilike"):
+		fold := p.peek().keyword("ilike")
+		p.pos++
+		y, err := p.additive()
+		if err != nil {
+			return nil, err
+		}
+		return newFilterMatch(x, y, negate, func(pattern string) (*regexp.Regexp, error) {
+			return likeRegexp(pattern, fold)
+		})
+	case p.acceptKeyword("between"):
+		low, err := p.additive()
+		if err != nil {
+			return nil, err
+		}
+		if !p.acceptKeyword("and") {
+			return nil, p.unexpected()
+		}
+		high, err := p.additive()
+		if err != nil {
+			return nil, err
+		}
+		var node filterNode = &filterLogical{
+			x: &filterCompare{op: ">=", x: x, y: low},
+			y: &filterCompare{op: "<=", x: x, y: high},
+		}
+		if negate {
+			node = &filterNot{x: node}
+		}
+		return node, nil
+	case negate:
+		p.pos--
+		return nil, p.unexpected()
+	}
+	return x, nil
+}
+
+func (p *filterParser) additive() (filterNode, error) {
+	x, err := p.multiplicative()
+	for err == nil {
+		op, ok := p.acceptOperator("+", "-", "||")
+		if !ok {
+			break
+		}
+		var y filterNode
+		if y, err = p.multiplicative(); err == nil {
+			x = &filterArithmetic{op: op, x: x, y: y}
+		}
+	}
+	return x, err
+}
+
+func (p *filterParser) multiplicative() (filterNode, error) {
+	x, err := p.unary()
+	for err == nil {
+		op, ok := p.acceptOperator("*", "/", "%")
+		if !ok {
+			break
+		}
+		var y filterNode
+		if y, err = p.unary(); err == nil {
+			x = &filterArithmetic{op: op, x: x, y: y}
+		}
+	}
+	return x, err
+}
+
+func (p *filterParser) unary() (filterNode, error) {
+	if op, ok := p.acceptOperator("-", "+"); ok {
+		x, err := p.unary()
+		if err != nil || op == "+" {
+			return x, err
+		}
+		return &filterArithmetic{op: "-", x: &filterLiteral{int64(0)}, y: x}, nil
+	}
+	return p.primary()
+}
+
+func (p *filterParser) primary() (filterNode, error) {
+	tok := p.peek()
+	switch tok.kind {
+	case filterNumber:
+		p.pos++
+		if i, err := strconv.ParseInt(tok.text, 10, 64); err == nil {
+			return &filterLiteral{i}, nil
+		}
+		f, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, Error("invalid number %s at offset %d of filter expression: %s", tok.text, tok.offset, p.text)
+		}
+		return &filterLiteral{f}, nil
+	case filterString:
+		p.pos++
+		return &filterLiteral{tok.text}, nil
+	case filterField:
+		p.pos++
+		return p.field(tok.text), nil
+	case filterOperator:
+		if tok.text != "(" {
+			break
+		}
+		p.pos++
+		x, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		return x, p.expectOperator(")")
+	case filterWord:
+		word := strings.ToUpper(tok.text)
+		_, keyword := filterKeywords[word]
+		switch {
+		case word == "NULL":
+			p.pos++
+			return &filterLiteral{nil}, nil
+		case word == "TRUE" || word == "FALSE":
+			p.pos++
+			return &filterLiteral{word == "TRUE"}, nil
+		case keyword:
+			return nil, p.unexpected()
+		}
+		p.pos++
+		if _, ok := p.acceptOperator("("); ok {
+			return p.call(tok)
+		}
+		return p.field(tok.text), nil
+	}
+	return nil, p.unexpected()
+}
+
+// call parses the arguments of the function call, after the parenthesis.
+func (p *filterParser) call(name filterToken) (filterNode, error) {
+	filterFuncsMu.RLock()
+	fn, ok := filterFuncs[strings.ToLower(name.text)]
+	filterFuncsMu.RUnlock()
+	if !ok {
+		return nil, Error("unknown function %s at offset %d of filter expression: %s", name.text, name.offset, p.text)
+	}
+
+	node := &filterCall{name: name.text, fn: fn}
+	if _, ok := p.acceptOperator(")"); ok {
+		return node, nil
+	}
+	for {
+		arg, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		node.args = append(node.args, arg)
+		if _, ok := p.acceptOperator(","); !ok {
+			break
+		}
+	}
+	return node, p.expectOperator(")")
+}
+
+func (p *filterParser) field(name string) filterNode {
+	if !In(name, p.fields...) {
+		p.fields = append(p.fields, name)
+	}
+	return &filterFieldNode{name}
+}
+
+// filterNode is a node of a compiled filter expression.
+type filterNode interface {
+	eval(record map[string]any) (any, error)
+}
+
+type filterLiteral struct{ value any }
+
+func (n *filterLiteral) eval(map[string]any) (any, error) { return n.value, nil }
+
+type filterFieldNode struct{ name string }
+
+func (n *filterFieldNode) eval(record map[string]any) (any, error) {
+	if value, ok := record[n.name]; ok {
+		return value, nil
+	}
+	for key, value := range record {
+		if strings.EqualFold(key, n.name) {
+			return value, nil
+		}
+	}
+	return nil, nil
+}
+
+type filterLogical struct {
+	or   bool
+	x, y filterNode
+}
+
+func (n *filterLogical) eval(record map[string]any) (any, error) {
+	x, err := n.x.eval(record)
+	if err != nil {
+		return nil, err
+	}
+	if filterTruth(x) == n.or {
+		return n.or, nil
+	}
+	y, err := n.y.eval(record)
+	if err != nil {
+		return nil, err
+	}
+	return filterTruth(y), nil
+}
+
+type filterNot struct{ x filterNode }
+
+func (n *filterNot) eval(record map[string]any) (any, error) {
+	x, err := n.x.eval(record)
+	if err != nil {
+		return nil, err
+	}
+	return !filterTruth(x), nil
+}
+
+type filterCompare struct {
+	op   string
+	x, y filterNode
+}
+
+func (n *filterCompare) eval(record map[string]any) (any, error) {
+	x, err := n.x.eval(record)
+	if err != nil {
+		return nil, err
+	}
+	y, err := n.y.eval(record)
+	if err != nil {
+		return nil, err
+	}
+
+	if x == nil || y == nil {
+		switch n.op {
+		case "=", "==":
+			return x == nil && y == nil, nil
+		case "!=", "<>":
+			return x != nil || y != nil, nil
+		}
+		return false, nil
+	}
+
+	c := compareFilterValues(x, y)
+	switch n.op {
+	case "=", "==":
+		return c == 0, nil
+	case "!=", "<>":
+		return c != 0, nil
+	case "<":
+		return c < 0, nil
+	case "<=":
+		return c <= 0, nil
+	case ">":
+		return c > 0, nil
+	}
+	return c >= 0, nil
+}
+
+type filterIsNull struct {
+	x      filterNode
+	negate bool
+}
+
+func (n *filterIsNull) eval(record map[string]any) (any, error) {
+	x, err := n.x.eval(record)
+	return (x == nil) != n.negate, err
+}
+
+type filterIn struct {
+	x      filterNode
+	list   []filterNode
+	negate bool
+}
+
+func (n *filterIn) eval(record map[string]any) (any, error) {
+	x, err := n.x.eval(record)
+	if err != nil || x == nil {
+		return false, err
+	}
+	for _, item := range n.list {
+		y, err := item.eval(record)
+		if err != nil {
+			return nil, err
+		}
+		if y != nil && compareFilterValues(x, y) == 0 {
+			return !n.negate, nil
+		}
+	}
+	return n.negate, nil
+}
+
+// filterMatch matches a value with a regular expression or a like pattern.
+type filterMatch struct {
+	x, pattern filterNode
+	re         *regexp.Regexp // compiled once if the pattern is a literal
+	compile    func(string) (*regexp.Regexp, error)
+	negate     bool
+}
+
+func newFilterMatch(x, pattern filterNode, negate bool, compile func(string) (*regexp.Regexp, error)) (filterNode, error) {
+	n := &filterMatch{x: x, pattern: pattern, compile: compile, negate: negate}
+	if literal, ok := pattern.(*filterLiteral); ok && literal.value != nil {
+		re, err := compile(cast.ToString(literal.value))
+		if err != nil {
+			return nil, Error(err, "invalid pattern in filter expression")
+		}
+		n.re = re
+	}
+	return n, nil
+}
+
+func (n *filterMatch) eval(record map[string]any) (any, error) {
+	x, err := n.x.eval(record)
+	if err != nil || x == nil {
+		return false, err
+	}
+	re := n.re
+	if re == nil {
+		pattern, err := n.pattern.eval(record)
+		if err != nil || pattern == nil {
+			return false, err
+		}
+		if re, err = n.compile(cast.ToString(pattern)); err != nil {
+			return nil, Error(err, "invalid pattern in filter expression")
+		}
+	}
+	return re.MatchString(cast.ToString(x)) != n.negate, nil
+}
+
+// likeRegexp returns the regular expression of a like pattern.
+func likeRegexp(pattern string, fold bool) (*regexp.Regexp, error) {
+	var b strings.Builder
+	b.WriteString("(?s)")
+	if fold {
+		b.WriteString("(?i)")
+	}
+	b.WriteString("^")
+	for _, r := range pattern {
+		switch r {
+		case '%':
+			b.WriteString(".*")
+		case '_':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.Compile(b.String())
+}
+
+type filterArithmetic struct {
+	op   string
+	x, y filterNode
+}
+
+func (n *filterArithmetic) eval(record map[string]any) (any, error) {
+	x, err := n.x.eval(record)
+	if err != nil {
+		return nil, err
+	}
+	y, err := n.y.eval(record)
+	if err != nil || x == nil || y == nil {
+		return nil, err
+	}
+
+	if n.op == "||" {
+		return cast.ToString(x) + cast.ToString(y), nil
+	}
+
+	if a, ok := filterInteger(x); ok && n.op != "/" {
+		if b, ok := filterInteger(y); ok {
+			switch n.op {
+			case "+":
+				return a + b, nil
+			case "-":
+				return a - b, nil
+			case "*":
+				return a * b, nil
+			}
+			if b == 0 {
+				return nil, Error("division by zero in filter expression")
+			}
+			return a % b, nil
+		}
+	}
+
+	a, ok1 := filterFloat(x)
+	b, ok2 := filterFloat(y)
+	if !ok1 || !ok2 {
+		return nil, Error("invalid operands for %s in filter expression: %#v and %#v", n.op, x, y)
+	}
+	switch n.op {
+	case "+":
+		return a + b, nil
+	case "-":
+		return a - b, nil
+	case "*":
+		return a * b, nil
+	}
+	if b == 0 {
+		return nil, Error("division by zero in filter expression")
+	}
+	if n.op == "%" {
+		return math.Mod(a, b), nil
+	}
+	return a / b, nil
+}
+
+type filterCall struct {
+	name string
+	fn   FilterFunc
+	args []filterNode
+}
+
+func (n *filterCall) eval(record map[string]any) (any, error) {
+	args := make([]any, len(n.args))
+	for i, arg := range n.args {
+		value, err := arg.eval(record)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = value
+	}
+	value, err := n.fn(args...)
+	if err != nil {
+		return nil, Error(err, "could not evaluate %s in filter expression", n.name)
+	}
+	return value, nil
+}
+
+// filterTruth returns the truth value of a filter value.
+func filterTruth(value any) bool {
+	switch v := value.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case string:
+		if b, err := strconv.ParseBool(v); err == nil {
+			return b
+		}
+		return v != ""
+	}
+	if f, ok := filterFloat(value); ok {
+		return f != 0
+	}
+	return true
+}
+
+// filterInteger returns the value as an integer, if it is an integer or
+// an integer string.
+func filterInteger(value any) (int64, bool) {
+	switch v := value.(type) {
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32:
+		return cast.ToInt64(v), true
+	case uint64:
+		return int64(v), v <= math.MaxInt64
+	case string:
+		i, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+		return i, err == nil
+	}
+	return 0, false
+}
+
+// filterFloat returns the value as a float, if it is a number or a
+// numeric string.
+func filterFloat(value any) (float64, bool) {
+	switch v := value.(type) {
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return cast.ToFloat64(v), true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// compareFilterValues compares non-null values: numerically if both are
+// numbers, as times if either is a time, and as strings otherwise.
+func compareFilterValues(x, y any) int {
+	if a, ok := filterFloat(x); ok {
+		if b, ok := filterFloat(y); ok {
+			return compareOrdered(a, b)
+		}
+	}
+
+	_, xTime := x.(time.Time)
+	_, yTime := y.(time.Time)
+	if xTime || yTime {
+		a, err1 := cast.ToTimeE(x)
+		b, err2 := cast.ToTimeE(y)
+		if err1 == nil && err2 == nil {
+			return a.Compare(b)
+		}
+	}
+
+	if a, ok := x.(bool); ok {
+		if b, ok := y.(bool); ok {
+			return compareOrdered(cast.ToInt(a), cast.ToInt(b))
+		}
+	}
+	return strings.Compare(cast.ToString(x), cast.ToString(y))
+}
+
+func compareOrdered[T int | float64](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func filterArgs(args []any, min, max int) error {
+	if len(args) < min || max >= 0 && len(args) > max {
+		return Error("invalid number of arguments: %d", len(args))
+	}
+	return nil
+}
+
+func filterStringFunc(fn func(string) string) FilterFunc {
+	return func(args ...any) (any, error) {
+		if err := filterArgs(args, 1, 1); err != nil || args[0] == nil {
+			return nil, err
+		}
+		return fn(cast.ToString(args[0])), nil
+	}
+}
+
+func filterMathFunc(fn func(float64) float64) FilterFunc {
+	return func(args ...any) (any, error) {
+		if err := filterArgs(args, 1, 1); err != nil || args[0] == nil {
+			return nil, err
+		}
+		if i, ok := filterInteger(args[0]); ok {
+			return int64(fn(float64(i))), nil
+		}
+		f, ok := filterFloat(args[0])
+		if !ok {
+			return nil, Error("invalid number: %#v", args[0])
+		}
+		return fn(f), nil
+	}
+}
+
+func filterRound(args ...any) (any, error) {
+	if err := filterArgs(args, 1, 2); err != nil || args[0] == nil {
+		return nil, err
+	}
+	f, ok := filterFloat(args[0])
+	if !ok {
+		return nil, Error("invalid number: %#v", args[0])
+	}
+	digits := int64(0)
+	if len(args) == 2 {
+		if digits, ok = filterInteger(args[1]); !ok {
+			return nil, Error("invalid number of digits: %#v", args[1])
+		}
+	}
+	scale := math.Pow(10, float64(digits))
+	return math.Round(f*scale) / scale, nil
+}
+
+func filterLength(args ...any) (any, error) {
+	if err := filterArgs(args, 1, 1); err != nil || args[0] == nil {
+		return nil, err
+	}
+	return int64(utf8.RuneCountInString(cast.ToString(args[0]))), nil
+}
+
+// filterSubstr returns the substring of args[0] starting at the 1-based
+// character args[1], of at most args[2] characters.
+func filterSubstr(args ...any) (any, error) {
+	if err := filterArgs(args, 2, 3); err != nil || args[0] == nil {
+		return nil, err
+	}
+	runes := []rune(cast.ToString(args[0]))
+	start, ok := filterInteger(args[1])
+	if !ok {
+		return nil, Error("invalid start: %#v", args[1])
+	}
+	start = int64(math.Max(float64(start-1), 0))
+	end := int64(len(runes))
+	if len(args) == 3 {
+		n, ok := filterInteger(args[2])
+		if !ok || n < 0 {
+			return nil, Error("invalid length: %#v", args[2])
+		}
+		end = start + n
+	}
+	if start > int64(len(runes)) {
+		start = int64(len(runes))
+	}
+	if end > int64(len(runes)) {
+		end = int64(len(runes))
+	}
+	return string(runes[start:end]), nil
+}
+
+func filterReplace(args ...any) (any, error) {
+	if err := filterArgs(args, 3, 3); err != nil || args[0] == nil {
+		return nil, err
+	}
+	return strings.ReplaceAll(cast.ToString(args[0]), cast.ToString(args[1]), cast.ToString(args[2])), nil
+}
+
+func filterConcat(args ...any) (any, error) {
+	var b strings.Builder
+	for _, arg := range args {
+		b.WriteString(cast.ToString(arg))
+	}
+	return b.String(), nil
+}
+
+func filterCoalesce(args ...any) (any, error) {
+	for _, arg := range args {
+		if arg != nil {
+			return arg, nil
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,90 @@
+package g
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompileFilter(t *testing.T) {
+	RegisterFilterFunc("func", func(args ...any) (any, error) {
+		return len(args[0].(string)), nil
+	})
+
+	record := map[string]any{
+		"t":      "ToT T",
+		"ok":     "abc",
+		"n":      int64(7),
+		"f":      2.5,
+		"s":      "12",
+		"name":   "Alice Smith",
+		"Email":  "alice@example.com",
+		"none":   nil,
+		"a.b":    "dotted",
+		"my col": true,
+	}
+
+	cases := map[string]bool{
+		`((t = 'One There') or t = "ToT T") and (func(ok) = 3 )`: true,
+		`t in ('one', 'two')`:        false,
+		`t not in ('one', 'two')`:    true,
+		`t='ToT T'`:                  true,
+		`t ~ '^To'`:                  true,
+		`t !~ "val ff"`:              true,
+		`n > 5 and n <= 7`:           true,
+		`n >= 7.5`:                   false,
+		`s = 12 and s > 9`:           true, // numeric strings compare as numbers
+		`n * 2 - 4 = 10`:             true,
+		`n % 4 = 3 and n / 2 = 3.5`:  true,
+		`f*-2 = -5`:                  true,
+		`-n < 0`:                     true,
+		`name like 'Alice%'`:         true,
+		`name like 'alice%'`:         false,
+		`name ilike 'alice%'`:        true,
+		`name not like '%Jones'`:     true,
+		`email like '%@example.___'`: true, // case-insensitive field fallback
+		`none is null and missing is null and n is not null`: true,
+		`none = null`:                                               true,
+		`none != 1 and not none < 1`:                                true,
+		`n between 1 and 10 and f not between 3 and 4`:              true,
+		`lower(t) || '!' = 'tot t!'`:                                true,
+		`length(name) = 11 and upper(substr(name, 1, 5)) = 'ALICE'`: true,
+		`coalesce(none, missing, 'x') = 'x'`:                        true,
+		`round(f) = 3 and abs(-n) = 7 and round(1.234, 2) = 1.23`:   true,
+		`a.b = 'dotted' and ` + "`my col`":                          true,
+		`not (n = 7) or false`:                                      false,
+		`n = 7 -- trailing comment`:                                 true,
+	}
+	for expr, expected := range cases {
+		f, err := CompileFilter(expr)
+		if !assert.NoError(t, err, expr) {
+			continue
+		}
+		match, err := f.Match(record)
+		assert.NoError(t, err, expr)
+		assert.Equal(t, expected, match, expr)
+	}
+
+	f, err := CompileFilter(`n + 1 = 8 and lower(name) ~ ok and n in (1, n)`)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"n", "name", "ok"}, f.Fields())
+
+	value, err := CompileFilter(`n * 2`)
+	assert.NoError(t, err)
+	result, err := value.Eval(record)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(14), result)
+
+	for _, expr := range []string{``, `t = `, `t = 'abc`, `(n > 1`, `n > 1)`, `unknown(n)`, `t ~ '('`, `n not 1`, `n # 2`} {
+		_, err := CompileFilter(expr)
+		assert.Error(t, err, expr)
+	}
+
+	f, err = CompileFilter(`n / (n - 7) > 1`)
+	assert.NoError(t, err)
+	_, err = f.Match(record)
+	if assert.Error(t, err) {
+		assert.True(t, strings.Contains(err.Error(), "division by zero"))
+	}
+}
@@ -0,0 +1,240 @@
+package g
+
+import (
+	"strings"
+)
+
+// KeywordCase is the case of the keywords written by the SQL formatter.
+type KeywordCase string
+
+const (
+	KeywordCasePreserve KeywordCase = ""
+	KeywordCaseUpper    KeywordCase = "upper"
+	KeywordCaseLower    KeywordCase = "lower"
+)
+
+// FormatOptions configures FormatSQL and Tokens.Format.
+type FormatOptions struct {
+	// Dialect holds the lexical rules of the text. Defaults to DialectGeneric.
+	Dialect *SQLDialect
+	// KeywordCase is the case of the keywords. Defaults to preserving it.
+	KeywordCase KeywordCase
+	// Indent is the indentation of each level. Defaults to two spaces.
+	Indent string
+	// Compact writes each statement on a single line, except after line comments.
+	Compact bool
+}
+
+// FormatSQL formats the SQL text. See Tokens.Format.
+func FormatSQL(text string, options *FormatOptions) string {
+	if options == nil {
+		options = &FormatOptions{}
+	}
+	tokens := Tokenize(text, &TokenizeOptions{Dialect: options.Dialect}).Tokens
+	return tokens.Format(options)
+}
+
+// Format writes the tokens as formatted SQL: whitespace is normalized,
+// major clauses start on their own line, select lists are written one
+// column per line, and subqueries are indented by parenthesis level.
+// Comments are preserved. Tokenizing the output gives the same
+// non-whitespace tokens, ignoring the case of keywords.
+func (ts Tokens) Format(options *FormatOptions) string {
+	if options == nil {
+		options = &FormatOptions{}
+	}
+	f := &sqlFormatter{options: options, indent: options.Indent, frames: []formatFrame{{}}}
+	if f.indent == "" {
+		f.indent = "  "
+	}
+
+	// non-whitespace tokens, and whether whitespace preceded them
+	var tokens Tokens
+	var spaced []bool
+	space := false
+	for _, tok := range ts {
+		if tok.Kind == TokenWhitespace || tok.Kind == "" && tok.IsWhitespace() {
+			space = true
+			continue
+		}
+		tokens = append(tokens, tok)
+		spaced = append(spaced, space)
+		space = false
+	}
+
+	var b strings.Builder
+	for i, tok := range tokens {
+		if i > 0 {
+			b.WriteString(f.separator(tokens, i, spaced[i]))
+		}
+		text := tok.Text
+		switch {
+		case tok.IsComment:
+			text = strings.TrimSuffix(text, "\n")
+		case tok.Kind == TokenKeyword && (i == 0 || tokens[i-1].Text != "."):
+			switch options.KeywordCase {
+			case KeywordCaseUpper:
+				text = strings.ToUpper(text)
+			case KeywordCaseLower:
+				text = strings.ToLower(text)
+			}
+		}
+		b.WriteString(text)
+		f.update(tokens, i)
+	}
+	if last := tokens.Last(); last.IsComment && strings.HasSuffix(last.Text, "\n") {
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// sqlFormatter holds the state of Tokens.Format.
+type sqlFormatter struct {
+	options *FormatOptions
+	indent  string
+	frames  []formatFrame // the statement, then each open parenthesis
+
+	selectComma bool // the previous token separates select columns
+}
+
+type formatFrame struct {
+	subquery bool // the parenthesis contains a query
+	inSelect bool // in the select list
+}
+
+// depth returns the number of open subqueries.
+func (f *sqlFormatter) depth() (n int) {
+	for _, frame := range f.frames[1:] {
+		if frame.subquery {
+			n++
+		}
+	}
+	return n
+}
+
+func (f *sqlFormatter) top() *formatFrame {
+	return &f.frames[len(f.frames)-1]
+}
+
+func (f *sqlFormatter) newLine(depth int) string {
+	if f.options.Compact {
+		return "\n"
+	}
+	return "\n" + strings.Repeat(f.indent, depth)
+}
+
+// separator returns the whitespace to write before tokens[i].
+func (f *sqlFormatter) separator(tokens Tokens, i int, spaced bool) string {
+	tok, prev := tokens[i], tokens[i-1]
+	clause := f.clauseStart(tokens, i)
+	depth := f.depth()
+
+	switch {
+	case prev.IsComment && strings.HasSuffix(prev.Text, "\n"):
+		switch {
+		case tok.Text == ")" && f.top().subquery:
+			return f.newLine(depth - 1)
+		case clause || tok.Text == ";":
+			return f.newLine(depth)
+		}
+		return f.newLine(depth + 1)
+	case prev.Text == ";" && len(f.frames) == 1 && !tok.IsComment:
+		return f.newLine(0)
+	case f.options.Compact:
+	case prev.Text == "(" && f.top().subquery:
+		return f.newLine(depth)
+	case tok.Text == ")" && f.top().subquery:
+		return f.newLine(depth - 1)
+	case f.selectComma:
+		return f.newLine(depth + 1)
+	case clause && (spaced || prev.Text == ")" || prev.IsComment):
+		return f.newLine(depth)
+	}
+	if spaced || prev.Text == ";" || isWordKind(prev.Kind) && isWordKind(tok.Kind) {
+		return " "
+	}
+	return ""
+}
+
+// isWordKind returns true for the kinds of tokens which must be separated
+// by whitespace from one another, such as keywords and names.
+func isWordKind(kind TokenKind) bool {
+	switch kind {
+	case TokenKeyword, TokenIdentifier, TokenNumber, TokenParameter, TokenString:
+		return true
+	}
+	return false
+}
+
+// update updates the state after writing tokens[i].
+func (f *sqlFormatter) update(tokens Tokens, i int) {
+	tok := tokens[i]
+	f.selectComma = false
+	switch {
+	case tok.Text == "(":
+		next := strings.ToUpper(nextCode(tokens, i).Text)
+		f.frames = append(f.frames, formatFrame{subquery: next == "SELECT" || next == "WITH"})
+	case tok.Text == ")":
+		if len(f.frames) > 1 {
+			f.frames = f.frames[:len(f.frames)-1]
+		}
+	case strings.Contains(tok.Text, ";") && tok.Kind != TokenString && !tok.IsComment:
+		f.frames = f.frames[:1]
+		f.frames[0] = formatFrame{}
+	case tok.Text == "," && f.top().inSelect:
+		f.selectComma = true
+	case tok.Kind == TokenKeyword && tok.EqualsFold("SELECT"):
+		f.top().inSelect = true
+	case f.clauseStart(tokens, i):
+		f.top().inSelect = false
+	}
+}
+
+// clauseStart returns true if tokens[i] starts a major clause of the
+// current query.
+func (f *sqlFormatter) clauseStart(tokens Tokens, i int) bool {
+	tok := tokens[i]
+	if tok.Kind != TokenKeyword || len(f.frames) > 1 && !f.top().subquery {
+		return false
+	}
+	if i > 0 && tokens[i-1].Text == "." {
+		return false
+	}
+	next := strings.ToUpper(nextCode(tokens, i).Text)
+	switch strings.ToUpper(tok.Text) {
+	case "SELECT", "FROM", "WHERE", "HAVING", "LIMIT", "OFFSET", "UNION", "INTERSECT", "EXCEPT",
+		"VALUES", "SET", "RETURNING", "WINDOW", "INSERT", "UPDATE", "DELETE", "MERGE", "WITH":
+		return true
+	case "GROUP", "ORDER":
+		return next == "BY"
+	case "LEFT", "RIGHT", "FULL", "INNER", "CROSS", "NATURAL":
+		return next == "JOIN" || next == "OUTER"
+	case "JOIN":
+		switch strings.ToUpper(previousCode(tokens, i).Text) {
+		case "LEFT", "RIGHT", "FULL", "INNER", "CROSS", "NATURAL", "OUTER":
+			return false
+		}
+		return true
+	}
+	return false
+}
+
+// nextCode returns the token following tokens[i], skipping comments.
+func nextCode(tokens Tokens, i int) Token {
+	for i++; i < len(tokens); i++ {
+		if !tokens[i].IsComment && !tokens[i].IsWhitespace() {
+			return tokens[i]
+		}
+	}
+	return Token{Index: -1}
+}
+
+// previousCode returns the token preceding tokens[i], skipping comments.
+func previousCode(tokens Tokens, i int) Token {
+	for i--; i >= 0; i-- {
+		if !tokens[i].IsComment && !tokens[i].IsWhitespace() {
+			return tokens[i]
+		}
+	}
+	return Token{Index: -1}
+}
@@ -0,0 +1,63 @@
+package g
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatSQL(t *testing.T) {
+	text := `select a, b as "B", count(*) from t left join (select id, max(x) m from u where y in (1,2) group by id) v on v.id = t.a -- join
+where t.c = 'x,y' and extract(year from d) > 2000 group by a, b order by 1;insert into t (a) values (1)`
+
+	out := FormatSQL(text, &FormatOptions{KeywordCase: KeywordCaseUpper})
+	assert.Equal(t, `SELECT a,
+  b AS "B",
+  count(*)
+FROM t
+LEFT JOIN (
+  SELECT id,
+    max(x) m
+  FROM u
+  WHERE y IN (1,2)
+  GROUP BY id
+) v ON v.id = t.a -- join
+WHERE t.c = 'x,y' AND extract(YEAR FROM d) > 2000
+GROUP BY a, b
+ORDER BY 1;
+INSERT INTO t (a)
+VALUES (1)`, out)
+
+	out = FormatSQL(text, &FormatOptions{KeywordCase: KeywordCaseLower, Compact: true})
+	assert.Equal(t, `select a, b as "B", count(*) from t left join (select id, max(x) m from u where y in (1,2) group by id) v on v.id = t.a -- join
+where t.c = 'x,y' and extract(year from d) > 2000 group by a, b order by 1;
+insert into t (a) values (1)`, out)
+
+	// whitespace is normalized between statements and words
+	out = FormatSQL(`select "a"from t;select 'x'as y ;  -- c`+"\n", &FormatOptions{Compact: true})
+	assert.Equal(t, `select "a" from t;`+"\n"+`select 'x' as y ; -- c`+"\n", out)
+
+	// round trip
+	for _, text := range []string{
+		text,
+		"/* a */ SELECT x.from,\n\t'a''b' FROM [t] -- c\r\n-- d\nWHERE a<>b UNION ALL SELECT $$ x $$, E'\\n'",
+		"with c as (select 1) select * from c",
+	} {
+		for _, options := range []*FormatOptions{
+			{KeywordCase: KeywordCaseUpper},
+			{KeywordCase: KeywordCaseLower, Compact: true, Dialect: DialectPostgres},
+			{Dialect: DialectSQLServer, Indent: "\t"},
+		} {
+			tokenize := func(text string) (ss []string) {
+				for _, tok := range Tokenize(text, &TokenizeOptions{Dialect: options.Dialect}).Tokens {
+					if !tok.IsWhitespace() {
+						ss = append(ss, strings.ToLower(tok.Text))
+					}
+				}
+				return
+			}
+			assert.Equal(t, tokenize(text), tokenize(FormatSQL(text, options)), text)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package g
+
+import (
+	"sort"
+	"strings"
+)
+
+// TextEdit replaces the bytes from Start to End of a text with Text.
+type TextEdit struct {
+	Start int    `json:"start"` // The byte offset of the replaced range
+	End   int    `json:"end"`   // The byte offset following the replaced range
+	Text  string `json:"text"`  // The replacement text
+}
+
+// Retokenize returns the tokens of the text of the body after the edit,
+// with the options of Tokenize. Only the affected region is tokenized
+// again: tokenization resumes at the token preceding the edit, and stops
+// once a token following the edit matches a previous token, outside of
+// strings and comments. The following tokens are reused with shifted
+// offsets, positions and parenthesis levels. The result equals Tokenize
+// on the edited text.
+func (tb TokenBody) Retokenize(edit TextEdit) TokenBody {
+	if tb.text == "" && len(tb.Tokens) > 0 {
+		// tokens added by hand, without offsets
+		return Tokenize(tb.Tokens.Join(), tb.options).Retokenize(edit)
+	}
+
+	old := tb.text
+	edit.Start = clampOffset(edit.Start, len(old))
+	edit.End = clampOffset(edit.End, len(old))
+	if edit.End < edit.Start {
+		edit.End = edit.Start
+	}
+	text := old[:edit.Start] + edit.Text + old[edit.End:]
+	delta := len(edit.Text) - (edit.End - edit.Start)
+	editEnd := edit.Start + len(edit.Text) // in the edited text
+
+	// resume before the token holding the byte preceding the edit, which
+	// the edit may extend, so that the lookahead of the tokenizer at the
+	// resumed offset reads unedited bytes
+	tokens := tb.Tokens
+	if len(tokens) == 0 {
+		return Tokenize(text, tb.options)
+	}
+	k := sort.Search(len(tokens), func(i int) bool { return tokens[i].End >= edit.Start })
+	if k == len(tokens) {
+		k = len(tokens) - 1
+	}
+	if k > 0 {
+		k--
+	}
+	state := tokenizeState{}
+	if k > 0 {
+		state = resumeState(text, tokens[k])
+	}
+
+	matched := -1
+	fresh := tokenize(text, tb.options, state, func(t Token) bool {
+		if t.Start < editEnd {
+			return false
+		}
+		j := sort.Search(len(tokens), func(i int) bool { return tokens[i].Start >= t.Start-delta })
+		if j < len(tokens) && tokens[j].Start == t.Start-delta && tokens[j].Text == t.Text {
+			matched = j
+			return true
+		}
+		return false
+	})
+
+	// the unchanged tokens are copied in bulk, and fixed in place
+	var following Tokens
+	if matched >= 0 {
+		following = tokens[matched+1:]
+	}
+	body := &TokenBody{
+		Tokens:  make(Tokens, k+len(fresh.Tokens)+len(following)),
+		text:    text,
+		options: tb.options,
+	}
+	copy(body.Tokens, tokens[:k])
+	copy(body.Tokens[k:], fresh.Tokens)
+	shifted := body.Tokens[k+len(fresh.Tokens):]
+	copy(shifted, following)
+	if matched >= 0 {
+		// the positions of the following tokens depend on the line and
+		// column counters at the end of the matched token
+		m, n := tokens[matched], fresh.Tokens.Last()
+		oldLine, oldColumn, _ := lineCounters(old[:m.End])
+		newLine, newColumn, _ := lineCounters(text[:n.End])
+		lines, columns := newLine-oldLine, newColumn-oldColumn
+		levels := n.ParenthesisLevel - m.ParenthesisLevel
+		for i := range shifted {
+			t := &shifted[i]
+			if t.Position.Line == oldLine {
+				t.Position.Column += columns
+			}
+			t.Position.Line += lines
+			t.ParenthesisLevel += levels
+			t.Start += delta
+			t.End += delta
+		}
+	}
+	body.link()
+	body.detectBaseParenthesisLevel()
+	return *body
+}
+
+// link sets the indexes of the tokens, and their links to the body and
+// the neighbouring tokens, as AddToken does.
+func (tb *TokenBody) link() {
+	for i := range tb.Tokens {
+		t := &tb.Tokens[i]
+		t.Index, t.body, t.previous, t.next = i, tb, nil, nil
+		if i > 0 {
+			t.previous = &tb.Tokens[i-1]
+			t.previous.next = t
+		}
+	}
+}
+
+// resumeState returns the state of the tokenizer at the start of the
+// token, which follows the same bytes in text as in its tokenized text.
+func resumeState(text string, tok Token) (state tokenizeState) {
+	state.offset = tok.Start
+	state.start = tok.Position
+	state.parenthesisLevel = tok.ParenthesisLevel
+	if tok.Text == "(" {
+		state.parenthesisLevel--
+	}
+
+	state.line, state.column, state.newLine = lineCounters(text[:tok.Start])
+	return state
+}
+
+// lineCounters returns the line and column counters of the tokenizer
+// after the bytes of text, and whether the last one is a line feed.
+func lineCounters(text string) (line, column int, newLine bool) {
+	line = strings.Count(text, "\n")
+	switch n := strings.LastIndexByte(text, '\n'); {
+	case n < 0:
+		column = len(text)
+	case n == len(text)-1:
+		newLine = true
+	default:
+		column = len(text) - n - 2
+	}
+	return line, column, newLine
+}
+
+func clampOffset(offset, length int) int {
+	if offset < 0 {
+		return 0
+	} else if offset > length {
+		return length
+	}
+	return offset
+}
+
+// TokenAt returns the token holding the byte at offset of the tokenized
+// text, or a token with Index -1 if offset is out of range.
+func (tb TokenBody) TokenAt(offset int) Token {
+	i := sort.Search(len(tb.Tokens), func(i int) bool { return tb.Tokens[i].End > offset })
+	if i < len(tb.Tokens) && tb.Tokens[i].Start <= offset {
+		return tb.Tokens[i]
+	}
+	return Token{Index: -1}
+}
@@ -0,0 +1,101 @@
+package g
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetokenize(t *testing.T) {
+	text := "select a, (b + 1) as c -- note\nfrom t\nwhere d = 'x y' /* block */ and e in (1, 2);\nselect $$f$$ from u"
+
+	// comparable fields of the tokens
+	type tokenFields struct {
+		Text             string
+		Kind             TokenKind
+		Index            int
+		ParenthesisLevel int
+		Position         Position
+		Start, End       int
+	}
+	fields := func(tb TokenBody) (out []tokenFields) {
+		for _, tok := range tb.Tokens {
+			out = append(out, tokenFields{tok.Text, tok.Kind, tok.Index, tok.ParenthesisLevel, tok.Position, tok.Start, tok.End})
+		}
+		return out
+	}
+
+	edits := []TextEdit{
+		{Start: 0, End: 0, Text: "  "},
+		{Start: 3, End: 3, Text: "x"},                 // inside a keyword
+		{Start: 10, End: 11, Text: "((b"},             // adds a parenthesis
+		{Start: 29, End: 29, Text: "\n"},              // ends the line comment
+		{Start: 51, End: 51, Text: "'"},               // opens a string
+		{Start: 57, End: 59, Text: ""},                // removes the comment opener
+		{Start: 40, End: 80, Text: "x"},               // spans several tokens
+		{Start: len(text), End: len(text), Text: ";"}, // appends
+		{Start: 0, End: len(text), Text: "select 1"},  // replaces everything
+		{Start: 92, End: 92, Text: "$"},               // in the dollar-quoted string
+		{Start: 95, End: 95, Text: "-"},               // after the dollar-quoted string
+	}
+	// every insertion of a lexically significant byte, and every deletion
+	for i := 0; i <= len(text); i++ {
+		for _, c := range []string{" ", "\n", "(", ")", "'", "-", "/", "*", "$", "a"} {
+			edits = append(edits, TextEdit{Start: i, End: i, Text: c})
+		}
+		if i < len(text) {
+			edits = append(edits, TextEdit{Start: i, End: i + 1})
+		}
+	}
+	for _, options := range []*TokenizeOptions{nil, {Dialect: DialectPostgres}} {
+		prev := Tokenize(text, options)
+		for _, edit := range edits {
+			edited := text[:edit.Start] + edit.Text + text[edit.End:]
+			expected := Tokenize(edited, options)
+			actual := prev.Retokenize(edit)
+			assert.Equal(t, fields(expected), fields(actual), "%#v", edit)
+			assert.Equal(t, expected.baseParenthesisLevel, actual.baseParenthesisLevel)
+
+			// edits on the result
+			again := actual.Retokenize(TextEdit{Start: edit.Start, End: edit.Start + len(edit.Text), Text: text[edit.Start:edit.End]})
+			assert.Equal(t, fields(prev), fields(again), "%#v reverted", edit)
+		}
+	}
+}
+
+func TestTokenOffsets(t *testing.T) {
+	text := "select a,\n  'b' from t"
+	tb := Tokenize(text, nil)
+	for _, tok := range tb.Tokens {
+		assert.Equal(t, tok.Text, text[tok.Start:tok.End])
+	}
+
+	assert.Equal(t, "select", tb.TokenAt(0).Text)
+	assert.Equal(t, "select", tb.TokenAt(5).Text)
+	assert.Equal(t, " ", tb.TokenAt(6).Text)
+	assert.Equal(t, "'b'", tb.TokenAt(13).Text)
+	assert.Equal(t, "t", tb.TokenAt(len(text)-1).Text)
+	assert.Equal(t, -1, tb.TokenAt(len(text)).Index)
+	assert.Equal(t, -1, tb.TokenAt(-1).Index)
+}
+
+// go test -bench=Retokenize -benchmem -run '^$'
+func BenchmarkRetokenize(b *testing.B) {
+	text := strings.Repeat("select a, (b + 1) as c -- note\nfrom t\nwhere d = 'x y' /* block */ and e in (1, 2);\n", 20000)
+	edit := TextEdit{Start: len(text) / 2, End: len(text) / 2, Text: "x"}
+	edited := text[:edit.Start] + edit.Text + text[edit.End:]
+
+	b.Run("Tokenize", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			Tokenize(edited, nil)
+		}
+	})
+	b.Run("Retokenize", func(b *testing.B) {
+		tb := Tokenize(text, nil)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			tb.Retokenize(edit)
+		}
+	})
+}
@@ -0,0 +1,787 @@
+package g
+
+import (
+	"strings"
+)
+
+// StatementKind is the kind of a SQL statement.
+type StatementKind string
+
+const (
+	StatementSelect        StatementKind = "SELECT"
+	StatementInsert        StatementKind = "INSERT"
+	StatementUpdate        StatementKind = "UPDATE"
+	StatementDelete        StatementKind = "DELETE"
+	StatementMerge         StatementKind = "MERGE"
+	StatementCreateTableAs StatementKind = "CREATE TABLE AS"
+	StatementCreateView    StatementKind = "CREATE VIEW"
+)
+
+// Lineage describes the tables a statement reads and writes, and the
+// source columns of the columns it outputs or writes.
+type Lineage struct {
+	Kind    StatementKind   `json:"kind"`
+	Tables  []TableRef      `json:"tables"`
+	Columns []ColumnLineage `json:"columns"`
+}
+
+// TableRef is a table referenced by a statement. Common table
+// expressions are resolved, and not referenced as tables.
+type TableRef struct {
+	Database string   `json:"database,omitempty"`
+	Schema   string   `json:"schema,omitempty"`
+	Name     string   `json:"name"`
+	Aliases  []string `json:"aliases,omitempty"`
+	Read     bool     `json:"read"`
+	Written  bool     `json:"written"`
+}
+
+// FullName returns the qualified name of the table.
+func (t TableRef) FullName() string {
+	var parts []string
+	for _, part := range []string{t.Database, t.Schema, t.Name} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, ".")
+}
+
+// ColumnLineage is an output or written column, with the source
+// columns of its expression.
+type ColumnLineage struct {
+	Name    string      `json:"name"`
+	Sources []ColumnRef `json:"sources"`
+	// Star is set for a `*` expansion which could not be resolved, as the
+	// columns of the table are unknown.
+	Star bool `json:"star,omitempty"`
+}
+
+// ColumnRef is a column of a table. Table is empty if it could not be
+// resolved, and Column is `*` for all the columns.
+type ColumnRef struct {
+	Table  string `json:"table"`
+	Column string `json:"column"`
+}
+
+// AnalyzeLineage returns the lineage of a SELECT, INSERT, UPDATE, DELETE,
+// MERGE, CREATE TABLE AS or CREATE VIEW statement. The analysis is best
+// effort, without the schemas of the tables: unqualified columns are only
+// resolved when a single source could hold them.
+func AnalyzeLineage(text string, options *TokenizeOptions) (*Lineage, error) {
+	a := &lineageAnalyzer{}
+	p := &selectParser{tokens: Tokenize(text, options).Tokens}
+	if err := a.statement(p); err != nil {
+		return nil, err
+	}
+	for p.accept(";") {
+	}
+	if i := p.peek(); i < len(p.tokens) {
+		return nil, Error("unexpected %s after the %s statement", p.tokens[i].Text, a.lineage.Kind)
+	}
+	for _, table := range a.tables {
+		a.lineage.Tables = append(a.lineage.Tables, *table)
+	}
+	return &a.lineage, nil
+}
+
+// lineageAnalyzer holds the state of AnalyzeLineage.
+type lineageAnalyzer struct {
+	lineage Lineage
+	tables  []*TableRef
+}
+
+// lineageScope holds the sources visible in a query.
+type lineageScope struct {
+	parent  *lineageScope
+	ctes    map[string][]ColumnLineage
+	sources []*lineageSource
+}
+
+// lineageSource is a source of a query: a table, or the columns of a
+// common table expression or subquery.
+type lineageSource struct {
+	alias   string
+	table   *TableRef
+	columns []ColumnLineage
+}
+
+// statement analyzes the statement of p.
+func (a *lineageAnalyzer) statement(p *selectParser) error {
+	i := p.peek()
+	if i >= len(p.tokens) {
+		return Error("no statement")
+	}
+	switch word := strings.ToUpper(p.tokens[i].Text); word {
+	case "SELECT", "WITH", "(":
+		a.lineage.Kind = StatementSelect
+		columns, err := a.parseQuery(p, nil)
+		a.lineage.Columns = columns
+		return err
+
+	case "INSERT":
+		a.lineage.Kind = StatementInsert
+		p.pos = i + 1
+		p.accept("INTO")
+		if p.accept("OVERWRITE") {
+			p.accept("TABLE")
+		}
+		a.target(p)
+		names := columnNames(p.parens())
+		if !p.at("SELECT") && !p.at("WITH") && !p.at("(") {
+			// such as VALUES
+			a.reads(p.expr(p.span(func(int) bool { return false })), &lineageScope{})
+			return nil
+		}
+		columns, err := a.parseQuery(p, nil)
+		if err != nil {
+			return err
+		}
+		for j := range columns {
+			if j < len(names) {
+				columns[j].Name = names[j]
+			}
+		}
+		a.lineage.Columns = columns
+		return nil
+
+	case "UPDATE":
+		a.lineage.Kind = StatementUpdate
+		p.pos = i + 1
+		scope := &lineageScope{}
+		target := p.source()
+		if !p.accept("SET") {
+			return Error("expected SET after UPDATE %s", target.SQL())
+		}
+		assignments := a.assignments(p, p.listStop)
+		if p.accept("FROM") {
+			for _, source := range p.from() {
+				a.from(source, scope)
+			}
+		}
+		a.targetSource(target, scope)
+		for _, assignment := range assignments {
+			a.lineage.Columns = append(a.lineage.Columns, ColumnLineage{Name: assignment.name, Sources: a.refs(assignment.expr, scope)})
+		}
+		if p.accept("WHERE") {
+			a.reads(p.expr(p.span(p.clauseAt)), scope)
+		}
+		a.reads(p.expr(p.span(func(i int) bool { return p.tokens[i].Text == ";" })), scope)
+		return nil
+
+	case "DELETE":
+		a.lineage.Kind = StatementDelete
+		p.pos = i + 1
+		p.accept("FROM")
+		scope := &lineageScope{}
+		target := p.source()
+		if p.accept("USING") || p.accept("FROM") {
+			for _, source := range p.from() {
+				a.from(source, scope)
+			}
+		}
+		a.targetSource(target, scope)
+		a.reads(p.expr(p.span(func(i int) bool { return p.tokens[i].Text == ";" })), scope)
+		return nil
+
+	case "MERGE":
+		a.lineage.Kind = StatementMerge
+		p.pos = i + 1
+		p.accept("INTO")
+		scope := &lineageScope{}
+		target := p.source()
+		if !p.accept("USING") {
+			return Error("expected USING after MERGE INTO %s", target.SQL())
+		}
+		a.targetSource(target, scope)
+		a.from(p.source(), scope)
+		a.merge(p, scope)
+		return nil
+
+	case "CREATE":
+		// CREATE [OR REPLACE] [TEMPORARY...] TABLE|VIEW [IF NOT EXISTS] name [(columns)] AS query
+	kind:
+		for j := i; j < len(p.tokens) && !p.tokens[j].EqualsFold("AS") && p.tokens[j].Text != "("; j = p.skip(j + 1) {
+			switch {
+			case p.tokens[j].EqualsFold("TABLE"):
+				a.lineage.Kind = StatementCreateTableAs
+			case p.tokens[j].EqualsFold("VIEW"):
+				a.lineage.Kind = StatementCreateView
+			default:
+				continue
+			}
+			p.pos = j + 1
+			break kind
+		}
+		if a.lineage.Kind == "" {
+			return Error("unsupported statement: CREATE without TABLE or VIEW")
+		}
+		p.accept("IF", "NOT", "EXISTS")
+		a.target(p)
+		names := columnNames(p.parens())
+		if !p.accept("AS") {
+			return Error("unsupported statement: %s without AS", a.lineage.Kind)
+		}
+		columns, err := a.parseQuery(p, nil)
+		if err != nil {
+			return err
+		}
+		for j := range columns {
+			if j < len(names) {
+				columns[j].Name = names[j]
+			}
+		}
+		a.lineage.Columns = columns
+		return nil
+
+	default:
+		return Error("unsupported statement: %s", word)
+	}
+}
+
+// parseQuery parses and analyzes the query of p, optionally in parentheses.
+func (a *lineageAnalyzer) parseQuery(p *selectParser, parent *lineageScope) ([]ColumnLineage, error) {
+	stmt, err := p.query()
+	if err != nil {
+		return nil, err
+	}
+	return a.query(stmt, parent), nil
+}
+
+// target consumes the name of a written table.
+func (a *lineageAnalyzer) target(p *selectParser) *TableRef {
+	i := p.peek()
+	end := i
+	for end < len(p.tokens) && (p.tokens[end].Kind == TokenIdentifier || p.tokens[end].Kind == TokenKeyword) {
+		end++
+		if end < len(p.tokens) && p.tokens[end].Text == "." {
+			end++
+			continue
+		}
+		break
+	}
+	p.pos = end
+	table := a.table(p.tokens[i:end].Join(), "")
+	table.Written = true
+	return table
+}
+
+// table returns the referenced table of name, adding it if new.
+func (a *lineageAnalyzer) table(name, alias string) *TableRef {
+	parts := splitQualified(name)
+	ref := TableRef{Name: parts[len(parts)-1]}
+	if n := len(parts); n > 1 {
+		ref.Schema = parts[n-2]
+		ref.Database = strings.Join(parts[:n-2], ".")
+	}
+
+	var table *TableRef
+	for _, t := range a.tables {
+		if strings.EqualFold(t.FullName(), ref.FullName()) {
+			table = t
+			break
+		}
+	}
+	if table == nil {
+		table = &ref
+		a.tables = append(a.tables, table)
+	}
+	if alias != "" && !In(alias, table.Aliases...) {
+		table.Aliases = append(table.Aliases, alias)
+	}
+	return table
+}
+
+// targetSource adds the source written by UPDATE, DELETE or MERGE to the
+// scope, unless it is the alias of a source of the FROM clause, as in
+// UPDATE t SET ... FROM table t.
+func (a *lineageAnalyzer) targetSource(target *TableSource, scope *lineageScope) {
+	if target.Name == "" {
+		a.source(target, scope)
+		return
+	}
+	if target.Alias == "" {
+		for _, source := range scope.sources {
+			if source.table != nil && strings.EqualFold(source.alias, target.Name) {
+				source.table.Written = true
+				return
+			}
+		}
+	}
+	s := &lineageSource{alias: target.Alias, table: a.table(target.Name, target.Alias)}
+	s.table.Written = true
+	if s.alias == "" {
+		s.alias = s.table.Name
+	}
+	scope.sources = append(scope.sources, s)
+}
+
+// query analyzes a query, and returns its columns.
+func (a *lineageAnalyzer) query(stmt *SelectStatement, parent *lineageScope) []ColumnLineage {
+	ctes := &lineageScope{parent: parent, ctes: map[string][]ColumnLineage{}}
+	for _, cte := range stmt.With {
+		key := strings.ToLower(cte.Name)
+		ctes.ctes[key] = nil // recursive references
+		columns := a.query(cte.Query, ctes)
+		for i, name := range columnNames(cte.Columns) {
+			if i < len(columns) {
+				columns[i].Name = name
+			}
+		}
+		ctes.ctes[key] = columns
+	}
+
+	scope := &lineageScope{parent: ctes}
+	for _, source := range stmt.From {
+		a.from(source, scope)
+	}
+	for _, e := range append([]*Expr{stmt.Where, stmt.Having, stmt.Into, stmt.Tail, stmt.DistinctOn}, stmt.GroupBy...) {
+		a.reads(e, scope)
+	}
+	for _, item := range stmt.OrderBy {
+		a.reads(item.Expr, scope)
+	}
+
+	var columns []ColumnLineage
+	if stmt.Query != nil {
+		columns = a.query(stmt.Query, ctes)
+	}
+	for _, col := range stmt.Columns {
+		columns = append(columns, a.column(col, scope)...)
+	}
+
+	if stmt.SetQuery != nil {
+		for i, other := range a.query(stmt.SetQuery, ctes) {
+			if i < len(columns) && !columns[i].Star && !other.Star {
+				columns[i].Sources = appendColumnRefs(columns[i].Sources, other.Sources...)
+			}
+		}
+	}
+	return columns
+}
+
+// from adds a source of a FROM clause, and its joins, to the scope.
+func (a *lineageAnalyzer) from(source *TableSource, scope *lineageScope) {
+	a.source(source, scope)
+	for _, join := range source.Joins {
+		a.source(join.Source, scope)
+		a.reads(join.On, scope)
+	}
+}
+
+// source adds a source to the scope, without its joins.
+func (a *lineageAnalyzer) source(source *TableSource, scope *lineageScope) *lineageSource {
+	s := &lineageSource{alias: source.Alias}
+	switch {
+	case source.Subquery != nil:
+		s.columns = a.query(source.Subquery, scope.parent)
+	case source.Raw != nil:
+		a.reads(source.Raw, scope)
+	default:
+		if columns, ok := scope.cte(source.Name); ok {
+			s.columns = columns
+			if s.alias == "" {
+				s.alias = source.Name
+			}
+			break
+		}
+		s.table = a.table(source.Name, source.Alias)
+		s.table.Read = true
+		if s.alias == "" {
+			s.alias = s.table.Name
+		}
+	}
+	scope.sources = append(scope.sources, s)
+	return s
+}
+
+// cte returns the columns of the common table expression name, if any.
+func (scope *lineageScope) cte(name string) ([]ColumnLineage, bool) {
+	for ; scope != nil; scope = scope.parent {
+		if columns, ok := scope.ctes[strings.ToLower(name)]; ok {
+			return columns, true
+		}
+	}
+	return nil, false
+}
+
+// column returns the lineage of a select column, expanding `*`.
+func (a *lineageAnalyzer) column(col *SelectColumn, scope *lineageScope) []ColumnLineage {
+	if chain, ok := exprChain(col.Expr); ok && chain[len(chain)-1] == "*" {
+		var columns []ColumnLineage
+		for _, source := range scope.sources {
+			if len(chain) > 1 && !source.matches(chain[:len(chain)-1]) {
+				continue
+			}
+			if source.table != nil {
+				columns = append(columns, ColumnLineage{
+					Name:    "*",
+					Sources: []ColumnRef{{Table: source.table.FullName(), Column: "*"}},
+					Star:    true,
+				})
+			} else {
+				columns = append(columns, source.columns...)
+			}
+		}
+		if columns == nil && len(chain) > 1 {
+			// unknown qualifier
+			var parts []string
+			for _, part := range chain[:len(chain)-1] {
+				parts = append(parts, unquoteIdentifier(part))
+			}
+			columns = append(columns, ColumnLineage{
+				Name:    "*",
+				Sources: []ColumnRef{{Table: strings.Join(parts, "."), Column: "*"}},
+				Star:    true,
+			})
+		}
+		return columns
+	}
+
+	name := col.Alias
+	if chain, ok := exprChain(col.Expr); ok && name == "" {
+		name = chain[len(chain)-1]
+	} else if name == "" {
+		name = col.Expr.SQL()
+	}
+	return []ColumnLineage{{Name: unquoteIdentifier(name), Sources: a.refs(col.Expr, scope)}}
+}
+
+// reads analyzes the subqueries of an expression.
+func (a *lineageAnalyzer) reads(e *Expr, scope *lineageScope) {
+	a.refs(e, scope)
+}
+
+// refs returns the columns referenced by an expression, including the
+// sources of its subqueries.
+func (a *lineageAnalyzer) refs(e *Expr, scope *lineageScope) (refs []ColumnRef) {
+	if e == nil {
+		return nil
+	}
+	for _, part := range e.Parts {
+		if part.Subquery != nil {
+			for _, col := range a.query(part.Subquery, scope) {
+				refs = appendColumnRefs(refs, col.Sources...)
+			}
+			continue
+		}
+		for _, chain := range columnChains(part.Tokens) {
+			refs = appendColumnRefs(refs, scope.resolve(chain)...)
+		}
+	}
+	return refs
+}
+
+// resolve returns the source columns of a column reference.
+func (scope *lineageScope) resolve(chain []string) []ColumnRef {
+	column := unquoteIdentifier(chain[len(chain)-1])
+	qualifier := chain[:len(chain)-1]
+	for s := scope; s != nil; s = s.parent {
+		var candidates []*lineageSource
+		for _, source := range s.sources {
+			if len(qualifier) == 0 || source.matches(qualifier) {
+				candidates = append(candidates, source)
+			}
+		}
+		if len(qualifier) == 0 && len(candidates) > 1 {
+			// the only derived source with the column, or unresolved
+			var found []*lineageSource
+			for _, source := range candidates {
+				if source.column(column) != nil {
+					found = append(found, source)
+				}
+			}
+			if len(found) != 1 {
+				return []ColumnRef{{Column: column}}
+			}
+			candidates = found
+		}
+		if len(candidates) == 1 {
+			source := candidates[0]
+			if source.table != nil {
+				return []ColumnRef{{Table: source.table.FullName(), Column: column}}
+			}
+			if col := source.column(column); col != nil {
+				return col.Sources
+			}
+			return []ColumnRef{{Column: column}}
+		}
+	}
+	return []ColumnRef{{Table: strings.Join(qualifier, "."), Column: column}}
+}
+
+// matches returns true if the source is named by qualifier.
+func (source *lineageSource) matches(qualifier []string) bool {
+	name := unquoteIdentifier(qualifier[len(qualifier)-1])
+	if strings.EqualFold(unquoteIdentifier(source.alias), name) {
+		return true
+	}
+	if source.table != nil {
+		var parts []string
+		for _, part := range qualifier {
+			parts = append(parts, unquoteIdentifier(part))
+		}
+		full := source.table.FullName()
+		return strings.EqualFold(full, strings.Join(parts, ".")) ||
+			strings.HasSuffix(strings.ToLower(full), "."+strings.ToLower(strings.Join(parts, ".")))
+	}
+	return false
+}
+
+// column returns the derived column of the source named name, or nil.
+// Columns which are not named come from the `*` of a single table, if
+// any, as in WITH c AS (SELECT * FROM t) SELECT x FROM c.
+func (source *lineageSource) column(name string) *ColumnLineage {
+	var stars []ColumnLineage
+	for i, col := range source.columns {
+		if col.Star {
+			stars = append(stars, col)
+		} else if strings.EqualFold(col.Name, name) {
+			return &source.columns[i]
+		}
+	}
+	if len(stars) == 1 && len(stars[0].Sources) == 1 {
+		return &ColumnLineage{Name: name, Sources: []ColumnRef{{Table: stars[0].Sources[0].Table, Column: name}}}
+	}
+	return nil
+}
+
+type lineageAssignment struct {
+	name string
+	expr *Expr
+}
+
+// assignments parses `column = expression` assignments separated by
+// commas, up to stop.
+func (a *lineageAnalyzer) assignments(p *selectParser, stop func(i int) bool) (assignments []lineageAssignment) {
+	for {
+		start, end := p.span(stop)
+		codes := p.codes(start, end)
+		for j, i := range codes {
+			if p.tokens[i].Text == "=" && j > 0 {
+				chain := strings.Split(p.tokens[start:codes[j-1]+1].Join(), ".")
+				assignments = append(assignments, lineageAssignment{
+					name: unquoteIdentifier(strings.TrimSpace(chain[len(chain)-1])),
+					expr: p.expr(i+1, end),
+				})
+				break
+			}
+		}
+		if !p.accept(",") {
+			return assignments
+		}
+	}
+}
+
+// merge analyzes the ON condition and the WHEN clauses of a MERGE
+// statement, whose updated and inserted columns are written.
+func (a *lineageAnalyzer) merge(p *selectParser, scope *lineageScope) {
+	whenStop := func(i int) bool {
+		return p.tokens[i].InFold("WHEN", "THEN", ";")
+	}
+	for {
+		i := p.peek()
+		switch {
+		case i >= len(p.tokens) || p.tokens[i].Text == ";":
+			return
+		case p.accept("UPDATE", "SET"):
+			for _, assignment := range a.assignments(p, func(i int) bool { return p.tokens[i].Text == "," || whenStop(i) }) {
+				a.addColumn(assignment.name, a.refs(assignment.expr, scope))
+			}
+		case p.accept("INSERT"):
+			names := columnNames(p.parens())
+			if p.accept("VALUES") {
+				if values := p.parens(); values != nil {
+					sub := &selectParser{tokens: values.Parts[0].Tokens}
+					if len(values.Parts) == 1 && len(sub.tokens) > 2 {
+						sub.tokens = sub.tokens[1 : len(sub.tokens)-1]
+						for j := 0; ; j++ {
+							e := sub.expr(sub.span(func(i int) bool { return sub.tokens[i].Text == "," }))
+							if j < len(names) {
+								a.addColumn(names[j], a.refs(e, scope))
+							}
+							if !sub.accept(",") {
+								break
+							}
+						}
+					}
+				}
+			}
+		default:
+			a.reads(p.expr(p.span(whenStop)), scope)
+			if !p.accept("WHEN") {
+				p.accept("THEN")
+			}
+		}
+	}
+}
+
+// addColumn adds the sources of a written column.
+func (a *lineageAnalyzer) addColumn(name string, sources []ColumnRef) {
+	for i, col := range a.lineage.Columns {
+		if strings.EqualFold(col.Name, name) {
+			a.lineage.Columns[i].Sources = appendColumnRefs(col.Sources, sources...)
+			return
+		}
+	}
+	a.lineage.Columns = append(a.lineage.Columns, ColumnLineage{Name: name, Sources: sources})
+}
+
+// appendColumnRefs appends the refs which are not in refs already.
+func appendColumnRefs(refs []ColumnRef, others ...ColumnRef) []ColumnRef {
+	for _, other := range others {
+		if !In(other, refs...) {
+			refs = append(refs, other)
+		}
+	}
+	return refs
+}
+
+// columnChains returns the column references of tokens, as the parts of
+// their qualified names. Function names, types and keywords are skipped.
+func columnChains(tokens Tokens) (chains [][]string) {
+	var codes Tokens
+	for _, tok := range tokens {
+		if !tok.IsComment && !tok.IsWhitespace() {
+			codes = append(codes, tok)
+		}
+	}
+	for i := 0; i < len(codes); i++ {
+		if !columnName(codes[i]) || i > 0 && codes[i-1].Text == "." || i > 0 && codes[i-1].EqualsFold("AS") {
+			continue
+		}
+		if codes[i].Kind == TokenKeyword && !keywordColumn(codes, i) {
+			continue
+		}
+		chain := []string{codes[i].Text}
+		for i+2 < len(codes) && codes[i+1].Text == "." && (columnName(codes[i+2]) || codes[i+2].Kind == TokenKeyword || codes[i+2].Text == "*") {
+			chain = append(chain, codes[i+2].Text)
+			i += 2
+		}
+		last := chain[len(chain)-1]
+		if j := strings.Index(last, "::"); j > 0 {
+			chain[len(chain)-1] = last[:j] // cast
+		}
+		if i+1 < len(codes) && (codes[i+1].Text == "(" || codes[i+1].Kind == TokenString) {
+			continue // function or typed literal, such as DATE '2020-01-01'
+		}
+		if chain[len(chain)-1] != "*" {
+			chains = append(chains, chain)
+		}
+	}
+	return chains
+}
+
+// columnName returns true if tok may name a column: an identifier, or a
+// keyword which is not reserved, such as YEAR.
+func columnName(tok Token) bool {
+	switch tok.Kind {
+	case TokenIdentifier:
+		return tok.Text[0] != '@' && tok.Text[0] != ':'
+	case TokenKeyword:
+		return !DialectGeneric.IsReserved(tok.Text)
+	}
+	return false
+}
+
+// keywordColumn returns true if the non-reserved keyword codes[i] is in
+// the position of a column, rather than part of a clause such as
+// `INTERVAL '1' DAY`, `AND CURRENT ROW` or `EXTRACT(YEAR FROM d)`.
+func keywordColumn(codes Tokens, i int) bool {
+	var prev, next Token
+	if i > 0 {
+		prev = codes[i-1]
+	}
+	if i+1 < len(codes) {
+		next = codes[i+1]
+	}
+	switch {
+	case prev.Text == ")" || prev.Kind == TokenString || prev.Kind == TokenNumber || prev.Kind == TokenParameter ||
+		prev.Kind == TokenIdentifier:
+		return false
+	case prev.Kind == TokenKeyword && !DialectGeneric.IsReserved(prev.Text):
+		return false
+	case next.InFold("PRECEDING", "FOLLOWING", "ROW"):
+		return false // frame bound, such as CURRENT ROW
+	case prev.Text == "(" && next.EqualsFold("FROM"):
+		return false
+	}
+	return true
+}
+
+// exprChain returns the qualified name of an expression made of a single
+// column reference or `*`.
+func exprChain(e *Expr) ([]string, bool) {
+	if e == nil || len(e.Parts) != 1 || e.Parts[0].Subquery != nil {
+		return nil, false
+	}
+	var chain []string
+	for _, tok := range e.Parts[0].Tokens {
+		switch {
+		case tok.IsComment || tok.IsWhitespace():
+		case len(chain)%2 == 1 && tok.Text == ".":
+			chain = append(chain, ".")
+		case len(chain)%2 == 0 && (columnName(tok) || len(chain) > 0 && tok.Kind == TokenKeyword || tok.Text == "*"):
+			chain = append(chain, tok.Text)
+		default:
+			return nil, false
+		}
+	}
+	if len(chain)%2 == 0 {
+		return nil, false
+	}
+	var names []string
+	for i := 0; i < len(chain); i += 2 {
+		names = append(names, chain[i])
+	}
+	return names, true
+}
+
+// columnNames returns the names of a parenthesized column list.
+func columnNames(e *Expr) (names []string) {
+	if e == nil {
+		return nil
+	}
+	for _, part := range e.Parts {
+		for _, tok := range part.Tokens {
+			if tok.Kind == TokenIdentifier || tok.Kind == TokenKeyword {
+				names = append(names, unquoteIdentifier(tok.Text))
+			}
+		}
+	}
+	return names
+}
+
+// splitQualified splits a qualified name into its unquoted parts.
+func splitQualified(name string) (parts []string) {
+	var quote byte
+	start := 0
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '`':
+			quote = c
+		case c == '[':
+			quote = ']'
+		case c == '.':
+			parts = append(parts, unquoteIdentifier(strings.TrimSpace(name[start:i])))
+			start = i + 1
+		}
+	}
+	return append(parts, unquoteIdentifier(strings.TrimSpace(name[start:])))
+}
+
+// unquoteIdentifier removes the quotes of an identifier.
+func unquoteIdentifier(name string) string {
+	if n := len(name); n >= 2 {
+		switch {
+		case name[0] == '"' && name[n-1] == '"', name[0] == '`' && name[n-1] == '`', name[0] == '[' && name[n-1] == ']':
+			return name[1 : n-1]
+		}
+	}
+	return name
+}
@@ -0,0 +1,192 @@
+package g
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnalyzeLineage(t *testing.T) {
+	text := `with recent as (select o.id, o.amount * 2 as total from sales.orders o where o.ts > date '2020-01-01')
+select r.id, r.total, c.name as customer, cast(c.score as int) score, x.*, (select max(p.v) from db.pub.prices p where p.id = r.id) max_v
+from recent r join "Crm"."Customers" c on c.id = r.id, extra x
+where c.region in (select region from regions)`
+
+	lineage, err := AnalyzeLineage(text, nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, StatementSelect, lineage.Kind)
+
+	var names []string
+	for _, table := range lineage.Tables {
+		names = append(names, table.FullName())
+		assert.True(t, table.Read)
+		assert.False(t, table.Written)
+	}
+	assert.Equal(t, []string{"sales.orders", "Crm.Customers", "extra", "regions", "db.pub.prices"}, names)
+	assert.Equal(t, TableRef{Database: "db", Schema: "pub", Name: "prices", Aliases: []string{"p"}, Read: true}, lineage.Tables[4])
+
+	assert.Equal(t, []ColumnLineage{
+		{Name: "id", Sources: []ColumnRef{{"sales.orders", "id"}}},
+		{Name: "total", Sources: []ColumnRef{{"sales.orders", "amount"}}},
+		{Name: "customer", Sources: []ColumnRef{{"Crm.Customers", "name"}}},
+		{Name: "score", Sources: []ColumnRef{{"Crm.Customers", "score"}}},
+		{Name: "*", Sources: []ColumnRef{{"extra", "*"}}, Star: true},
+		{Name: "max_v", Sources: []ColumnRef{{"db.pub.prices", "v"}}},
+	}, lineage.Columns)
+
+	// star of a common table expression, set operations
+	lineage, err = AnalyzeLineage("with a as (select x, y + 1 as y from t1) select * from a union all select u, v from t2", nil)
+	if assert.NoError(t, err) {
+		assert.Equal(t, []ColumnLineage{
+			{Name: "x", Sources: []ColumnRef{{"t1", "x"}, {"t2", "u"}}},
+			{Name: "y", Sources: []ColumnRef{{"t1", "y"}, {"t2", "v"}}},
+		}, lineage.Columns)
+	}
+
+	// parenthesized operands of set operations
+	lineage, err = AnalyzeLineage("(select a from t1) union (select b from t2) order by 1", nil)
+	if assert.NoError(t, err) {
+		assert.Equal(t, []ColumnLineage{
+			{Name: "a", Sources: []ColumnRef{{"t1", "a"}, {"t2", "b"}}},
+		}, lineage.Columns)
+	}
+
+	// non-reserved keywords as column names
+	lineage, err = AnalyzeLineage(`select year, month + 1 as next, s.first, extract(day from d) as day, sum(amount) over (order by year rows between unbounded preceding and current row) as running
+from sales s where ts > current_date - interval '1' day`, nil)
+	if assert.NoError(t, err) {
+		assert.Equal(t, []ColumnLineage{
+			{Name: "year", Sources: []ColumnRef{{"sales", "year"}}},
+			{Name: "next", Sources: []ColumnRef{{"sales", "month"}}},
+			{Name: "first", Sources: []ColumnRef{{"sales", "first"}}},
+			{Name: "day", Sources: []ColumnRef{{"sales", "d"}}},
+			{Name: "running", Sources: []ColumnRef{{"sales", "amount"}, {"sales", "year"}}},
+		}, lineage.Columns)
+	}
+	lineage, err = AnalyzeLineage("select interval '1' day i from t", nil)
+	if assert.NoError(t, err) {
+		assert.Equal(t, []ColumnLineage{{Name: "i"}}, lineage.Columns)
+	}
+
+	// stars of unknown qualifiers, and columns of a single table star
+	lineage, err = AnalyzeLineage("with c as (select * from s.t) select q.*, x, c.y from c", nil)
+	if assert.NoError(t, err) {
+		assert.Equal(t, []ColumnLineage{
+			{Name: "*", Sources: []ColumnRef{{"q", "*"}}, Star: true},
+			{Name: "x", Sources: []ColumnRef{{"s.t", "x"}}},
+			{Name: "y", Sources: []ColumnRef{{"s.t", "y"}}},
+		}, lineage.Columns)
+	}
+	lineage, err = AnalyzeLineage("with c as (select * from t1, t2) select x from c", nil)
+	if assert.NoError(t, err) {
+		assert.Equal(t, []ColumnRef{{"", "x"}}, lineage.Columns[0].Sources)
+	}
+
+	// reserved words of a dialect are names when not quoted
+	lineage, err = AnalyzeLineage("select plan, [file] from t", &TokenizeOptions{Dialect: DialectSQLServer})
+	if assert.NoError(t, err) {
+		assert.Equal(t, []ColumnLineage{
+			{Name: "plan", Sources: []ColumnRef{{"t", "plan"}}},
+			{Name: "file", Sources: []ColumnRef{{"t", "file"}}},
+		}, lineage.Columns)
+	}
+
+	// unresolved columns of several sources
+	lineage, err = AnalyzeLineage("select a, t1.b from t1, t2", nil)
+	if assert.NoError(t, err) {
+		assert.Equal(t, []ColumnRef{{"", "a"}}, lineage.Columns[0].Sources)
+		assert.Equal(t, []ColumnRef{{"t1", "b"}}, lineage.Columns[1].Sources)
+	}
+}
+
+func TestAnalyzeLineageWrites(t *testing.T) {
+	tests := []struct {
+		text    string
+		kind    StatementKind
+		written string
+		read    []string
+		columns []ColumnLineage
+	}{
+		{
+			text: "insert into dw.facts (k, v) select s.key, sum(s.val) from staging s group by s.key;",
+			kind: StatementInsert, written: "dw.facts", read: []string{"staging"},
+			columns: []ColumnLineage{
+				{Name: "k", Sources: []ColumnRef{{"staging", "key"}}},
+				{Name: "v", Sources: []ColumnRef{{"staging", "val"}}},
+			},
+		},
+		{
+			text: "insert into t values (1, (select max(id) from u))",
+			kind: StatementInsert, written: "t", read: []string{"u"},
+		},
+		{
+			text: "update t set a = s.a + 1, b = 2 from src s where t.id = s.id and s.x in (select x from f)",
+			kind: StatementUpdate, written: "t", read: []string{"src", "f"},
+			columns: []ColumnLineage{
+				{Name: "a", Sources: []ColumnRef{{"src", "a"}}},
+				{Name: "b"},
+			},
+		},
+		{
+			text: "UPDATE u SET u.name = n.name FROM dbo.users u JOIN names n ON n.id = u.id",
+			kind: StatementUpdate, written: "dbo.users", read: []string{"dbo.users", "names"},
+			columns: []ColumnLineage{
+				{Name: "name", Sources: []ColumnRef{{"names", "name"}}},
+			},
+		},
+		{
+			text: "delete from t where id in (select id from old)",
+			kind: StatementDelete, written: "t", read: []string{"old"},
+		},
+		{
+			text: `merge into tgt t using (select id, v from src) s on t.id = s.id
+when matched and s.v > 0 then update set v = s.v
+when not matched then insert (id, v) values (s.id, s.v + 1);`,
+			kind: StatementMerge, written: "tgt", read: []string{"src"},
+			columns: []ColumnLineage{
+				{Name: "v", Sources: []ColumnRef{{"src", "v"}}},
+				{Name: "id", Sources: []ColumnRef{{"src", "id"}}},
+			},
+		},
+		{
+			text: "create or replace temporary table if not exists s.t (c) as (select a from x)",
+			kind: StatementCreateTableAs, written: "s.t", read: []string{"x"},
+			columns: []ColumnLineage{{Name: "c", Sources: []ColumnRef{{"x", "a"}}}},
+		},
+		{
+			text: "create table reports.view as select a from x",
+			kind: StatementCreateTableAs, written: "reports.view", read: []string{"x"},
+			columns: []ColumnLineage{{Name: "a", Sources: []ColumnRef{{"x", "a"}}}},
+		},
+		{
+			text: "create view v as select * from x",
+			kind: StatementCreateView, written: "v", read: []string{"x"},
+			columns: []ColumnLineage{{Name: "*", Sources: []ColumnRef{{"x", "*"}}, Star: true}},
+		},
+	}
+	for _, tt := range tests {
+		lineage, err := AnalyzeLineage(tt.text, nil)
+		if !assert.NoError(t, err, tt.text) {
+			continue
+		}
+		assert.Equal(t, tt.kind, lineage.Kind, tt.text)
+		var written string
+		var read []string
+		for _, table := range lineage.Tables {
+			if table.Written {
+				written = table.FullName()
+			}
+			if table.Read {
+				read = append(read, table.FullName())
+			}
+		}
+		assert.Equal(t, tt.written, written, tt.text)
+		assert.Equal(t, tt.read, read, tt.text)
+		assert.Equal(t, tt.columns, lineage.Columns, tt.text)
+	}
+
+	_, err := AnalyzeLineage("drop table t", nil)
+	assert.ErrorContains(t, err, "unsupported statement: DROP")
+}
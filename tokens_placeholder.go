@@ -0,0 +1,183 @@
+package g
+
+import (
+	"strconv"
+	"strings"
+)
+
+// PlaceholderStyle is a style of bind parameter placeholders in SQL.
+type PlaceholderStyle string
+
+const (
+	PlaceholderColon    PlaceholderStyle = "colon"    // :name
+	PlaceholderAt       PlaceholderStyle = "at"       // @name
+	PlaceholderDollar   PlaceholderStyle = "dollar"   // $1
+	PlaceholderQuestion PlaceholderStyle = "question" // ?
+)
+
+// Placeholder is a bind parameter placeholder found in a SQL text.
+type Placeholder struct {
+	Text     string           `json:"text"`     // The placeholder as written, such as :id or $1
+	Name     string           `json:"name"`     // The name, or the number of positional placeholders
+	Style    PlaceholderStyle `json:"style"`    // The style of the placeholder
+	Offset   int              `json:"offset"`   // The byte offset in the text
+	Position Position         `json:"position"` // The position in the text
+}
+
+// PlaceholderOptions configures FindPlaceholders and RewritePlaceholders.
+type PlaceholderOptions struct {
+	// Dialect holds the lexical rules of the text. Defaults to DialectGeneric.
+	Dialect *SQLDialect
+	// Styles are the styles to detect. Defaults to all of them, except ?
+	// for dialects where it is an operator, such as Postgres.
+	Styles []PlaceholderStyle
+}
+
+func (o *PlaceholderOptions) detects(style PlaceholderStyle) bool {
+	switch {
+	case o == nil:
+		return true
+	case len(o.Styles) == 0:
+		return style != PlaceholderQuestion || o.Dialect == nil || !o.Dialect.QuestionOperators
+	}
+	return In(style, o.Styles...)
+}
+
+// FindPlaceholders returns the placeholders of text, outside of strings,
+// quoted identifiers and comments. `?` placeholders are numbered in
+// order of appearance. Casts such as `::int`, variables such as
+// `@@ROWCOUNT` and operators such as `?|` are not placeholders.
+func FindPlaceholders(text string, options *PlaceholderOptions) (placeholders []Placeholder) {
+	var dialect *SQLDialect
+	if options != nil {
+		dialect = options.Dialect
+	}
+
+	var cursor positionCursor
+	offset, questions := 0, 0
+	for _, tok := range Tokenize(text, &TokenizeOptions{Dialect: dialect}).Tokens {
+		start := offset
+		offset += len(tok.Text)
+		switch {
+		case tok.Kind == TokenString || tok.IsComment || tok.Kind == TokenWhitespace:
+			continue
+		case tok.Kind == TokenIdentifier && strings.IndexByte("\"`[", tok.Text[0]) >= 0:
+			continue
+		}
+
+		for i := start; i < offset; i++ {
+			var prev, next byte
+			if i > 0 {
+				prev = text[i-1]
+			}
+			if i+1 < len(text) {
+				next = text[i+1]
+			}
+
+			p := Placeholder{Offset: i}
+			end := i + 1
+			switch c := text[i]; {
+			case c == ':' && next == ':':
+				i++ // cast
+				continue
+			case c == ':' && prev != ':' && !isWordByte(prev) && isNameStart(next):
+				p.Style = PlaceholderColon
+				end = nameEnd(text, i+1)
+				p.Name = text[i+1 : end]
+			case c == '@' && prev != '@' && !isWordByte(prev) && isNameStart(next):
+				p.Style = PlaceholderAt
+				end = nameEnd(text, i+1)
+				p.Name = text[i+1 : end]
+			case c == '$' && !isWordByte(prev) && '0' <= next && next <= '9':
+				p.Style = PlaceholderDollar
+				for end = i + 1; end < len(text) && '0' <= text[end] && text[end] <= '9'; end++ {
+				}
+				p.Name = text[i+1 : end]
+			case c == '?' && prev != '?' && prev != '@' && (next == 0 || strings.IndexByte("?|&-#", next) < 0):
+				p.Style = PlaceholderQuestion
+				p.Name = strconv.Itoa(questions + 1)
+			case c == '@' && next == '@':
+				i++ // system variable
+				continue
+			default:
+				continue
+			}
+			if end > offset || !options.detects(p.Style) {
+				continue
+			}
+			if p.Style == PlaceholderQuestion {
+				questions++
+			}
+			p.Text = text[i:end]
+			p.Position = cursor.position(text, i)
+			placeholders = append(placeholders, p)
+			i = end - 1
+		}
+	}
+	return placeholders
+}
+
+// RewritePlaceholders rewrites the placeholders of text to the target
+// style, and returns the arguments to bind in order, taken from args by
+// placeholder name (`$1` and the first `?` are named "1"). Positional
+// targets number the names in order of first appearance: `$n`
+// placeholders reuse the number of a repeated name, while `?`
+// placeholders repeat its argument. Named targets keep the names and
+// return the arguments of the distinct names, prefixing numbers with p
+// to make them names, such as `:p1` for `$1`. A name missing from args
+// is an error.
+func RewritePlaceholders(text string, target PlaceholderStyle, args map[string]any, options *PlaceholderOptions) (string, []any, error) {
+	var b strings.Builder
+	var ordered []any
+	numbers := map[string]int{}
+	last := 0
+	for _, p := range FindPlaceholders(text, options) {
+		value, ok := args[p.Name]
+		if !ok {
+			return "", nil, Error("missing argument for placeholder %s at line %d, column %d", p.Text, p.Position.Line, p.Position.Column)
+		}
+
+		number, seen := numbers[p.Name]
+		if !seen {
+			number = len(numbers) + 1
+			numbers[p.Name] = number
+		}
+		if !seen || target == PlaceholderQuestion {
+			ordered = append(ordered, value)
+		}
+
+		name := p.Name
+		if !isNameStart(name[0]) {
+			name = "p" + name
+		}
+
+		b.WriteString(text[last:p.Offset])
+		switch target {
+		case PlaceholderColon:
+			b.WriteString(":" + name)
+		case PlaceholderAt:
+			b.WriteString("@" + name)
+		case PlaceholderDollar:
+			b.WriteString("$" + strconv.Itoa(number))
+		case PlaceholderQuestion:
+			b.WriteString("?")
+		default:
+			return "", nil, Error("invalid placeholder style: %s", target)
+		}
+		last = p.Offset + len(p.Text)
+	}
+	b.WriteString(text[last:])
+	return b.String(), ordered, nil
+}
+
+func isNameStart(c byte) bool {
+	return c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+// nameEnd returns the offset following the name starting at i in text.
+func nameEnd(text string, i int) int {
+	for i < len(text) && isWordByte(text[i]) {
+		i++
+	}
+	return i
+}
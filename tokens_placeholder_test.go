@@ -0,0 +1,74 @@
+package g
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindPlaceholders(t *testing.T) {
+	text := "select a::int, ':x', \"@y\" -- :z\nfrom t where id = :id and b=@b_2 and d ?| array['a'] and e ? 'k'\nand f = $1 and g in (?, ?) and @@rowcount > 0 and h = :id"
+	var names []string
+	for _, p := range FindPlaceholders(text, nil) {
+		names = append(names, string(p.Style)+" "+p.Name)
+	}
+	assert.Equal(t, []string{"colon id", "at b_2", "question 1", "dollar 1", "question 2", "question 3", "colon id"}, names)
+
+	placeholders := FindPlaceholders(text, &PlaceholderOptions{Dialect: DialectPostgres, Styles: []PlaceholderStyle{PlaceholderColon, PlaceholderDollar}})
+	if assert.Len(t, placeholders, 3) {
+		assert.Equal(t, ":id", placeholders[0].Text)
+		assert.Equal(t, 50, placeholders[0].Offset)
+		assert.Equal(t, Position{Line: 2, Column: 18}, placeholders[0].Position)
+		assert.Equal(t, "$1", placeholders[1].Text)
+		assert.Equal(t, Position{Line: 3, Column: 8}, placeholders[1].Position)
+	}
+
+	// quoting rules of the dialect
+	names = nil
+	for _, p := range FindPlaceholders("select $$ :a $$, [:b], `:c`, :d", &PlaceholderOptions{Dialect: DialectPostgres}) {
+		names = append(names, p.Name)
+	}
+	assert.Equal(t, []string{"b", "d"}, names)
+	names = nil
+	for _, p := range FindPlaceholders("select $$ :a $$, [:b], `:c`, :d", &PlaceholderOptions{Dialect: DialectSQLServer}) {
+		names = append(names, p.Name)
+	}
+	assert.Equal(t, []string{"a", "d"}, names)
+}
+
+func TestRewritePlaceholders(t *testing.T) {
+	text := "update t set a = :a, b = :b where a <> :a and c::text = 'x:y'"
+	args := map[string]any{"a": 1, "b": "two", "c": 3}
+
+	sql, ordered, err := RewritePlaceholders(text, PlaceholderDollar, args, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "update t set a = $1, b = $2 where a <> $1 and c::text = 'x:y'", sql)
+	assert.Equal(t, []any{1, "two"}, ordered)
+
+	sql, ordered, err = RewritePlaceholders(text, PlaceholderQuestion, args, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "update t set a = ?, b = ? where a <> ? and c::text = 'x:y'", sql)
+	assert.Equal(t, []any{1, "two", 1}, ordered)
+
+	sql, ordered, err = RewritePlaceholders("select ? + ?", PlaceholderAt, map[string]any{"1": 1, "2": 2}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "select @p1 + @p2", sql)
+	assert.Equal(t, []any{1, 2}, ordered)
+
+	// rewritten placeholders are found again
+	sql, _, err = RewritePlaceholders("select $1 + $2", PlaceholderColon, map[string]any{"1": 1, "2": 2}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "select :p1 + :p2", sql)
+	assert.Len(t, FindPlaceholders(sql, nil), 2)
+
+	// jsonb operators are left alone in Postgres
+	postgres := &PlaceholderOptions{Dialect: DialectPostgres}
+	sql, ordered, err = RewritePlaceholders("select data ? 'k', data ?| array['a'] from t where id = :id", PlaceholderDollar, map[string]any{"id": 1}, postgres)
+	assert.NoError(t, err)
+	assert.Equal(t, "select data ? 'k', data ?| array['a'] from t where id = $1", sql)
+	assert.Equal(t, []any{1}, ordered)
+	assert.Len(t, FindPlaceholders("select ?", &PlaceholderOptions{Dialect: DialectPostgres, Styles: []PlaceholderStyle{PlaceholderQuestion}}), 1)
+
+	_, _, err = RewritePlaceholders(text, PlaceholderQuestion, map[string]any{"a": 1}, nil)
+	assert.ErrorContains(t, err, "missing argument for placeholder :b")
+}
@@ -0,0 +1,341 @@
+package g
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// identifierQuotes returns the opening and closing quotes of identifiers.
+func (d *SQLDialect) identifierQuotes() (open, close byte) {
+	switch d.IdentifierQuote {
+	case 0:
+		return '"', '"'
+	case '[':
+		return '[', ']'
+	}
+	return d.IdentifierQuote, d.IdentifierQuote
+}
+
+// NeedsQuotes returns true if the name must be quoted to be used as an
+// identifier: if it is empty or reserved, has characters other than
+// ASCII letters, digits and underscores, starts with a digit, or would
+// be folded to another case unquoted.
+func (d *SQLDialect) NeedsQuotes(name string) bool {
+	if d == nil {
+		d = DialectGeneric
+	}
+	if name == "" || !isNameStart(name[0]) {
+		return true
+	}
+	for i := 1; i < len(name); i++ {
+		if !isWordByte(name[i]) {
+			return true
+		}
+	}
+	switch d.UnquotedCase {
+	case KeywordCaseLower:
+		if name != strings.ToLower(name) {
+			return true
+		}
+	case KeywordCaseUpper:
+		if name != strings.ToUpper(name) {
+			return true
+		}
+	}
+	return d.IsReserved(name)
+}
+
+// QuoteIdentifier returns the name quoted as an identifier, doubling the
+// embedded closing quotes.
+func (d *SQLDialect) QuoteIdentifier(name string) string {
+	if d == nil {
+		d = DialectGeneric
+	}
+	open, close := d.identifierQuotes()
+	return string(open) + d.escapeString(name, close, false) + string(close)
+}
+
+// QuoteIdentifierIfNeeded returns the name as an identifier, quoted only
+// if it must be. See NeedsQuotes.
+func (d *SQLDialect) QuoteIdentifierIfNeeded(name string) string {
+	if d.NeedsQuotes(name) {
+		return d.QuoteIdentifier(name)
+	}
+	return name
+}
+
+// UnquoteIdentifier returns the name of a quoted identifier, such as
+// "a ""b""", `a`, [a] or U&"\0061", or the name of an unquoted one,
+// folded to the case of the dialect.
+func (d *SQLDialect) UnquoteIdentifier(text string) (string, error) {
+	if d == nil {
+		d = DialectGeneric
+	}
+	if text == "" {
+		return "", Error("empty identifier")
+	}
+
+	unicode := false
+	if len(text) > 2 && strings.EqualFold(text[:2], "U&") {
+		unicode, text = true, text[2:]
+	}
+
+	var close byte
+	switch text[0] {
+	case '"':
+		if !d.DoubleQuotedStrings {
+			close = '"'
+		}
+	case '`':
+		close = '`'
+	case '[':
+		if d.BracketIdentifiers {
+			close = ']'
+		}
+	}
+	if close == 0 {
+		if unicode || strings.IndexFunc(text, func(r rune) bool { return r >= utf8.RuneSelf || !isWordByte(byte(r)) }) >= 0 {
+			return "", Error("invalid identifier: %s", text)
+		}
+		switch d.UnquotedCase {
+		case KeywordCaseLower:
+			return strings.ToLower(text), nil
+		case KeywordCaseUpper:
+			return strings.ToUpper(text), nil
+		}
+		return text, nil
+	}
+
+	name, err := unquote(text, close, false)
+	if err == nil && unicode {
+		name, err = unicodeUnescape(name)
+	}
+	if err != nil {
+		return "", Error(err, "invalid identifier: %s", text)
+	}
+	return name, nil
+}
+
+// QuoteString returns s as a string literal. Embedded quotes are doubled,
+// and if the dialect has backslash escapes, backslashes and control
+// characters are escaped, the latter with Unicode escapes if available.
+func (d *SQLDialect) QuoteString(s string) string {
+	if d == nil {
+		d = DialectGeneric
+	}
+	if !d.BackslashEscapes {
+		return "'" + d.escapeString(s, '\'', false) + "'"
+	}
+
+	var b strings.Builder
+	b.Grow(len(s) + 2)
+	b.WriteByte('\'')
+	for _, r := range s {
+		switch {
+		case r == '\'':
+			b.WriteString("''")
+		case r == '\\':
+			b.WriteString(`\\`)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r < ' ' && d.UnicodeEscapes:
+			b.WriteString(`\u00`)
+			b.WriteString(strconv.FormatInt(int64(r)>>4, 16))
+			b.WriteString(strconv.FormatInt(int64(r)&15, 16))
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('\'')
+	return b.String()
+}
+
+// UnquoteString returns the value of a string literal, such as 'a',
+// E'a\n', N'a', U&'\0061', $tag$a$tag$, or "a" if the dialect has double
+// quoted strings. Doubled quotes are undoubled, and backslash escapes are
+// resolved where the dialect reads them, including \xhh and, if enabled,
+// \uhhhh Unicode escapes.
+func (d *SQLDialect) UnquoteString(text string) (string, error) {
+	if d == nil {
+		d = DialectGeneric
+	}
+
+	if d.DollarQuotes {
+		if tag := dollarTag(text); tag != "" {
+			if len(text) < 2*len(tag) || !strings.HasSuffix(text, tag) || strings.Contains(text[len(tag):len(text)-len(tag)], tag) {
+				return "", Error("invalid string literal: %s", text)
+			}
+			return text[len(tag) : len(text)-len(tag)], nil
+		}
+	}
+
+	i := strings.IndexAny(text, `'"`)
+	if i < 0 || text[i] == '"' && !d.DoubleQuotedStrings {
+		return "", Error("invalid string literal: %s", text)
+	}
+	prefix := strings.ToUpper(text[:i])
+	escapes := d.BackslashEscapes
+	switch prefix {
+	case "", "N":
+	case "E":
+		escapes = escapes || d.EscapeStrings
+	case "U&":
+		escapes = false
+	case "R":
+		escapes = false // raw strings
+	default:
+		return "", Error("invalid string literal: %s", text)
+	}
+
+	value, err := unquote(text[i:], text[i], escapes)
+	if err == nil && escapes {
+		value, err = d.backslashUnescape(value)
+	} else if err == nil && prefix == "U&" {
+		value, err = unicodeUnescape(value)
+	}
+	if err != nil {
+		return "", Error(err, "invalid string literal: %s", text)
+	}
+	return value, nil
+}
+
+// unquote returns the content between the quotes of text, where doubled
+// closing quotes are undoubled. Backslash sequences are kept, and skipped
+// if escapes is true.
+func unquote(text string, close byte, escapes bool) (string, error) {
+	if len(text) < 2 || text[len(text)-1] != close {
+		return "", Error("unterminated quote")
+	}
+	inner := text[1 : len(text)-1]
+
+	var b strings.Builder
+	b.Grow(len(inner))
+	for i := 0; i < len(inner); i++ {
+		c := inner[i]
+		switch {
+		case c == '\\' && escapes && i+1 < len(inner):
+			b.WriteByte(c)
+			i++
+			c = inner[i]
+		case c == close && i+1 < len(inner) && inner[i+1] == close:
+			i++
+		case c == close:
+			return "", Error("unescaped quote at offset %d", i+1)
+		}
+		b.WriteByte(c)
+	}
+	return b.String(), nil
+}
+
+// backslashUnescape resolves the backslash escapes of s.
+func (d *SQLDialect) backslashUnescape(s string) (string, error) {
+	if strings.IndexByte(s, '\\') < 0 {
+		return s, nil
+	}
+
+	var b strings.Builder
+	b.Grow(len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch c := s[i]; c {
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 't':
+			b.WriteByte('\t')
+		case 'b':
+			b.WriteByte('\b')
+		case 'f':
+			b.WriteByte('\f')
+		case '0', '1', '2', '3', '4', '5', '6', '7':
+			if !d.EscapeStrings {
+				if c == '0' {
+					c = 0
+				}
+				b.WriteByte(c)
+				break
+			}
+			// octal, such as \041
+			end := i + 1
+			for end < len(s) && end < i+3 && '0' <= s[end] && s[end] <= '7' {
+				end++
+			}
+			code, _ := strconv.ParseUint(s[i:end], 8, 8)
+			b.WriteByte(byte(code))
+			i = end - 1
+		case 'Z':
+			b.WriteByte(26) // MySQL
+		case 'x', 'u', 'U':
+			digits := 2
+			if c != 'x' {
+				if !d.UnicodeEscapes && !d.EscapeStrings {
+					b.WriteByte(c)
+					break
+				}
+				digits = 4
+				if c == 'U' {
+					digits = 8
+				}
+			}
+			if i+digits >= len(s) {
+				return "", Error("invalid escape \\%s", s[i:])
+			}
+			code, err := strconv.ParseUint(s[i+1:i+1+digits], 16, 32)
+			if err != nil {
+				return "", Error("invalid escape \\%s", s[i:i+1+digits])
+			}
+			if c == 'x' {
+				b.WriteByte(byte(code))
+			} else {
+				b.WriteRune(rune(code))
+			}
+			i += digits
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String(), nil
+}
+
+// unicodeUnescape resolves the escapes of U& strings and identifiers:
+// \XXXX, \+XXXXXX and \\.
+func unicodeUnescape(s string) (string, error) {
+	var b strings.Builder
+	b.Grow(len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' {
+			b.WriteByte(s[i])
+			continue
+		}
+		if i+1 < len(s) && s[i+1] == '\\' {
+			b.WriteByte('\\')
+			i++
+			continue
+		}
+
+		start, digits := i+1, 4
+		if start < len(s) && s[start] == '+' {
+			start, digits = start+1, 6
+		}
+		if start+digits > len(s) {
+			return "", Error("invalid Unicode escape %s", s[i:])
+		}
+		code, err := strconv.ParseUint(s[start:start+digits], 16, 32)
+		if err != nil {
+			return "", Error("invalid Unicode escape %s", s[i:start+digits])
+		}
+		b.WriteRune(rune(code))
+		i = start + digits - 1
+	}
+	return b.String(), nil
+}
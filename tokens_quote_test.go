@@ -0,0 +1,119 @@
+package g
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuoteIdentifier(t *testing.T) {
+	cases := []struct {
+		dialect  *SQLDialect
+		name     string
+		expected string
+	}{
+		{DialectGeneric, "users", "users"},
+		{DialectGeneric, "Users", "Users"},
+		{DialectGeneric, "select", `"select"`},
+		{DialectGeneric, "year", "year"}, // non-reserved
+		{DialectGeneric, "first name", `"first name"`},
+		{DialectGeneric, `a"b`, `"a""b"`},
+		{DialectGeneric, "1st", `"1st"`},
+		{DialectGeneric, "", `""`},
+		{DialectPostgres, "Users", `"Users"`},
+		{DialectPostgres, "user", `"user"`},
+		{DialectSnowflake, "users", `"users"`},
+		{DialectSnowflake, "USERS", "USERS"},
+		{DialectMySQL, "interval", "`interval`"},
+		{DialectMySQL, "a`b", "`a``b`"},
+		{DialectBigQuery, "struct", "`struct`"},
+		{DialectSQLServer, "user", "[user]"},
+		{DialectSQLServer, "a]b", "[a]]b]"},
+		{nil, "order", `"order"`},
+	}
+	for _, c := range cases {
+		actual := c.dialect.QuoteIdentifierIfNeeded(c.name)
+		assert.Equal(t, c.expected, actual, c.name)
+
+		// round trip
+		name, err := c.dialect.UnquoteIdentifier(c.dialect.QuoteIdentifier(c.name))
+		assert.NoError(t, err, c.name)
+		assert.Equal(t, c.name, name)
+		if c.name != "" {
+			name, err = c.dialect.UnquoteIdentifier(actual)
+			assert.NoError(t, err, c.name)
+			assert.Equal(t, c.name, name)
+		}
+	}
+
+	assert.True(t, DialectPostgres.IsReserved("Verbose"))
+	assert.False(t, DialectGeneric.IsReserved("verbose"))
+	assert.True(t, DialectSQLServer.IsReserved("exec"))
+	assert.False(t, IsKeyword("exec"))
+	assert.True(t, IsKeyword("Select"))
+	assert.Equal(t, "[plan]", DialectSQLServer.QuoteIdentifierIfNeeded("plan"))
+
+	name, err := DialectPostgres.UnquoteIdentifier(`U&"d\0061t\+000061"`)
+	assert.NoError(t, err)
+	assert.Equal(t, "data", name)
+	name, err = DialectPostgres.UnquoteIdentifier("Users")
+	assert.NoError(t, err)
+	assert.Equal(t, "users", name)
+	for _, text := range []string{`"a`, `"a"b"`, `U&abc`, `a b`, `U&"\00"`} {
+		_, err = DialectPostgres.UnquoteIdentifier(text)
+		assert.Error(t, err, text)
+	}
+}
+
+func TestQuoteString(t *testing.T) {
+	value := "it's \\ a\n\ttab \x01 é"
+	cases := map[*SQLDialect]string{
+		DialectGeneric:   `'it''s \\ a\n\ttab ` + "\x01" + ` é'`,
+		DialectPostgres:  "'it''s \\ a\n\ttab \x01 é'",
+		DialectSQLServer: "'it''s \\ a\n\ttab \x01 é'",
+		DialectMySQL:     `'it''s \\ a\n\ttab ` + "\x01" + ` é'`,
+		DialectBigQuery:  `'it''s \\ a\n\ttab \u0001 é'`,
+		DialectSnowflake: `'it''s \\ a\n\ttab \u0001 é'`,
+	}
+	for dialect, expected := range cases {
+		actual := dialect.QuoteString(value)
+		assert.Equal(t, expected, actual, dialect.Name)
+
+		unquoted, err := dialect.UnquoteString(actual)
+		assert.NoError(t, err, dialect.Name)
+		assert.Equal(t, value, unquoted, dialect.Name)
+
+		// the literal is a single string token
+		tokens := Tokenize(actual, &TokenizeOptions{Dialect: dialect}).Tokens
+		if assert.Len(t, tokens, 1, dialect.Name) {
+			assert.Equal(t, TokenString, tokens[0].Kind)
+		}
+	}
+
+	literals := []struct {
+		dialect  *SQLDialect
+		text     string
+		expected string
+	}{
+		{DialectPostgres, `E'a\'b\n\x41\101é'`, "a'b\nAAé"},
+		{DialectPostgres, `'a\n'`, `a\n`},
+		{DialectPostgres, `U&'d\0061t\\a'`, `dat\a`},
+		{DialectPostgres, `$tag$it's $$ raw$tag$`, "it's $$ raw"},
+		{DialectPostgres, `N'x'`, "x"},
+		{DialectMySQL, `"say ""hi"" \"there\""`, `say "hi" "there"`},
+		{DialectMySQL, `'a\0\Z\%'`, "a\x00\x1a%"},
+		{DialectBigQuery, `r'a\n'`, `a\n`},
+		{DialectBigQuery, `'\U0001F600'`, "😀"},
+		{DialectSQLServer, `N'it''s'`, "it's"},
+	}
+	for _, c := range literals {
+		actual, err := c.dialect.UnquoteString(c.text)
+		assert.NoError(t, err, c.text)
+		assert.Equal(t, c.expected, actual, c.text)
+	}
+
+	for _, text := range []string{`'abc`, `'a'b'`, `"a"`, `X'00'`, `E'\x4'`, `abc`} {
+		_, err := DialectPostgres.UnquoteString(text)
+		assert.Error(t, err, text)
+	}
+}
@@ -0,0 +1,762 @@
+package g
+
+import (
+	"strings"
+)
+
+// SelectStatement is the syntax tree of a SELECT query, parsed with
+// TokenBody.ParseSelect. Expressions and unrecognized syntax are kept as
+// token spans, so that the statement can be modified and rendered back
+// with SQL.
+type SelectStatement struct {
+	With      []*CommonTableExpr
+	Recursive bool
+
+	Distinct   bool
+	DistinctOn *Expr // the parenthesized expressions of DISTINCT ON
+	Top        *Expr
+	Columns    []*SelectColumn
+	Into       *Expr
+	From       []*TableSource // the sources separated by commas
+	Where      *Expr
+	GroupBy    []*Expr
+	Having     *Expr
+	OrderBy    []*OrderItem
+	Limit      *Expr
+	Offset     *Expr
+	Tail       *Expr // unrecognized trailing clauses, such as FOR UPDATE
+
+	// Query is the query of a parenthesized query, such as an operand of a
+	// set operation, in place of the clauses from SELECT to HAVING.
+	Query *SelectStatement
+
+	SetOperator string           // UNION, UNION ALL, INTERSECT, EXCEPT...
+	SetQuery    *SelectStatement // the query combined with SetOperator
+}
+
+// CommonTableExpr is a query named in a WITH clause.
+type CommonTableExpr struct {
+	Name     string
+	Columns  *Expr  // the parenthesized column names, if any
+	Modifier string // MATERIALIZED or NOT MATERIALIZED, if any
+	Query    *SelectStatement
+}
+
+// SelectColumn is an expression of the select list.
+type SelectColumn struct {
+	Expr  *Expr
+	Alias string
+}
+
+// TableSource is a table, a subquery or an unrecognized source, such as
+// a table function, followed by its joins.
+type TableSource struct {
+	Name     string // the table name, qualified as written
+	Subquery *SelectStatement
+	Raw      *Expr
+	Alias    string
+	Joins    []*Join
+}
+
+// Join is a table source joined to the previous ones.
+type Join struct {
+	Type   string // such as JOIN or LEFT OUTER JOIN, in upper case
+	Source *TableSource
+	On     *Expr
+	Using  *Expr // the parenthesized column names
+}
+
+// OrderItem is an expression of the ORDER BY clause.
+type OrderItem struct {
+	Expr      *Expr
+	Direction string // such as DESC or ASC NULLS LAST, in upper case
+}
+
+// Expr is an expression, kept as tokens, in which subqueries are parsed.
+type Expr struct {
+	Parts []ExprPart
+}
+
+// ExprPart is a span of tokens or a parenthesized subquery.
+type ExprPart struct {
+	Tokens   Tokens
+	Subquery *SelectStatement
+}
+
+// NewExpr returns the expression of a SQL text.
+func NewExpr(text string) *Expr {
+	p := &selectParser{tokens: Tokenize(text, nil).Tokens}
+	return p.expr(0, len(p.tokens))
+}
+
+// ParseSelect parses the tokens of a SELECT query, optionally followed by
+// a semicolon.
+func (tb TokenBody) ParseSelect() (*SelectStatement, error) {
+	p := &selectParser{tokens: tb.Tokens}
+	stmt, err := p.query()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept(";") {
+	}
+	if i := p.peek(); i < len(p.tokens) {
+		return nil, Error("unexpected %s after the SELECT statement", p.tokens[i].Text)
+	}
+	return stmt, nil
+}
+
+// selectParser is a recursive-descent parser of SELECT queries.
+type selectParser struct {
+	tokens Tokens
+	pos    int
+}
+
+// peek returns the index of the next token, skipping whitespace and
+// comments, or the number of tokens at the end.
+func (p *selectParser) peek() int {
+	return p.skip(p.pos)
+}
+
+func (p *selectParser) skip(i int) int {
+	for i < len(p.tokens) && (p.tokens[i].IsComment || p.tokens[i].IsWhitespace()) {
+		i++
+	}
+	return i
+}
+
+// at returns true if the next tokens match words, ignoring case.
+func (p *selectParser) at(words ...string) bool {
+	return p.matchAt(p.peek(), words...) > 0
+}
+
+// matchAt returns the index following the tokens starting at i which
+// match words, or 0.
+func (p *selectParser) matchAt(i int, words ...string) int {
+	for j, word := range words {
+		if j > 0 {
+			i = p.skip(i)
+		}
+		if i >= len(p.tokens) || !p.tokens[i].EqualsFold(word) {
+			return 0
+		}
+		i++
+	}
+	return i
+}
+
+// accept consumes the next tokens if they match words.
+func (p *selectParser) accept(words ...string) bool {
+	if i := p.matchAt(p.peek(), words...); i > 0 {
+		p.pos = i
+		return true
+	}
+	return false
+}
+
+// closing returns the index of the parenthesis closing the one at i.
+func (p *selectParser) closing(i int) int {
+	level := 0
+	for ; i < len(p.tokens); i++ {
+		switch p.tokens[i].Text {
+		case "(":
+			level++
+		case ")":
+			if level--; level == 0 {
+				return i
+			}
+		}
+	}
+	return len(p.tokens)
+}
+
+// parens consumes the parenthesized tokens which follow, and returns them.
+func (p *selectParser) parens() *Expr {
+	start := p.peek()
+	if start >= len(p.tokens) || p.tokens[start].Text != "(" {
+		return nil
+	}
+	p.pos = p.closing(start) + 1
+	if p.pos > len(p.tokens) {
+		p.pos = len(p.tokens)
+	}
+	return p.expr(start, p.pos)
+}
+
+// subquery returns true if the parenthesis at i contains a query.
+func (p *selectParser) subquery(i int) bool {
+	j := p.skip(i + 1)
+	return p.tokens[i].Text == "(" && j < len(p.tokens) && p.tokens[j].InFold("SELECT", "WITH")
+}
+
+// parenthesizedQuery returns true if the parenthesis at i contains a
+// query, itself parenthesized or not, as in ((SELECT 1)).
+func (p *selectParser) parenthesizedQuery(i int) bool {
+	if i >= len(p.tokens) || p.tokens[i].Text != "(" {
+		return false
+	}
+	j := p.skip(i + 1)
+	return p.subquery(i) || p.parenthesizedQuery(j)
+}
+
+// parseSubquery parses the query between the parenthesis at open and
+// the one at close.
+func (p *selectParser) parseSubquery(open, close int) (*SelectStatement, error) {
+	sub := &selectParser{tokens: p.tokens[open+1 : close]}
+	stmt, err := sub.query()
+	if err != nil {
+		return nil, err
+	}
+	if i := sub.peek(); i < len(sub.tokens) {
+		return nil, Error("unexpected %s in subquery", sub.tokens[i].Text)
+	}
+	return stmt, nil
+}
+
+// quoted returns true if tok is a string or a quoted identifier.
+func quoted(tok Token) bool {
+	return tok.Kind == TokenString || strings.IndexByte("'\"`[", tok.Text[0]) >= 0
+}
+
+// clauseAt returns true if a clause, or the end of the query, starts at i.
+func (p *selectParser) clauseAt(i int) bool {
+	if i >= len(p.tokens) {
+		return true
+	}
+	tok := p.tokens[i]
+	if quoted(tok) {
+		return false
+	}
+	switch strings.ToUpper(tok.Text) {
+	case ";", ")", "FROM", "WHERE", "HAVING", "LIMIT", "OFFSET", "UNION", "INTERSECT", "EXCEPT", "MINUS",
+		"INTO", "WINDOW", "QUALIFY", "FETCH", "FOR":
+		return true
+	case "GROUP", "ORDER":
+		return p.matchAt(i, tok.Text, "BY") > 0
+	}
+	return false
+}
+
+// joinAt returns the index following the join type starting at i, or 0.
+func (p *selectParser) joinAt(i int) int {
+	if i >= len(p.tokens) || quoted(p.tokens[i]) {
+		return 0
+	}
+	switch strings.ToUpper(p.tokens[i].Text) {
+	case "JOIN":
+		return i + 1
+	case "LEFT", "RIGHT", "FULL", "INNER", "CROSS", "NATURAL", "OUTER":
+		return p.joinAt(p.skip(i + 1))
+	}
+	return 0
+}
+
+// span consumes the tokens up to a stop token at the parenthesis level
+// of the start, and returns their range without surrounding whitespace.
+func (p *selectParser) span(stop func(i int) bool) (start, end int) {
+	start = p.peek()
+	level := 0
+	i := start
+	for ; i < len(p.tokens); i = p.skip(i + 1) {
+		if level == 0 && stop(i) {
+			break
+		}
+		switch p.tokens[i].Text {
+		case "(":
+			level++
+		case ")":
+			level--
+		}
+	}
+	p.pos = i
+	end = i
+	for end > start && (p.tokens[end-1].IsComment || p.tokens[end-1].IsWhitespace()) {
+		end--
+	}
+	return start, end
+}
+
+func (p *selectParser) listStop(i int) bool {
+	return p.tokens[i].Text == "," || p.clauseAt(i)
+}
+
+func (p *selectParser) sourceStop(i int) bool {
+	return p.listStop(i) || p.joinAt(i) > 0 || p.tokens[i].InFold("ON", "USING", "SET")
+}
+
+// expr returns the expression of the tokens in [start, end), or nil.
+func (p *selectParser) expr(start, end int) *Expr {
+	if start >= end {
+		return nil
+	}
+	e := &Expr{}
+	from := start
+	for i := start; i < end; i++ {
+		if !p.subquery(i) {
+			continue
+		}
+		close := p.closing(i)
+		if close >= end {
+			break
+		}
+		sub, err := p.parseSubquery(i, close)
+		if err != nil {
+			continue // kept as tokens
+		}
+		if from < i {
+			e.Parts = append(e.Parts, ExprPart{Tokens: p.tokens[from:i]})
+		}
+		e.Parts = append(e.Parts, ExprPart{Subquery: sub})
+		from = close + 1
+		i = close
+	}
+	if from < end {
+		e.Parts = append(e.Parts, ExprPart{Tokens: p.tokens[from:end]})
+	}
+	return e
+}
+
+// query parses a query, with its set operations.
+func (p *selectParser) query() (stmt *SelectStatement, err error) {
+	stmt = &SelectStatement{}
+	if p.accept("WITH") {
+		stmt.Recursive = p.accept("RECURSIVE")
+		for {
+			i := p.peek()
+			if i >= len(p.tokens) {
+				return nil, Error("expected the name of a common table expression")
+			}
+			cte := &CommonTableExpr{Name: p.tokens[i].Text}
+			p.pos = i + 1
+			cte.Columns = p.parens()
+			if !p.accept("AS") {
+				return nil, Error("expected AS after %s", cte.Name)
+			}
+			if p.accept("NOT", "MATERIALIZED") {
+				cte.Modifier = "NOT MATERIALIZED"
+			} else if p.accept("MATERIALIZED") {
+				cte.Modifier = "MATERIALIZED"
+			}
+			open := p.peek()
+			if open >= len(p.tokens) || p.tokens[open].Text != "(" {
+				return nil, Error("expected ( after %s AS", cte.Name)
+			}
+			close := p.closing(open)
+			if cte.Query, err = p.parseSubquery(open, close); err != nil {
+				return nil, Error(err, "could not parse "+cte.Name)
+			}
+			p.pos = close + 1
+			stmt.With = append(stmt.With, cte)
+			if !p.accept(",") {
+				break
+			}
+		}
+	}
+
+	if open := p.peek(); p.parenthesizedQuery(open) {
+		close := p.closing(open)
+		if stmt.Query, err = p.parseSubquery(open, close); err != nil {
+			return nil, err
+		}
+		p.pos = close + 1
+	} else if err = p.selectClauses(stmt); err != nil {
+		return nil, err
+	}
+
+	if !p.setOperator(stmt) {
+		if p.accept("ORDER", "BY") {
+			for {
+				stmt.OrderBy = append(stmt.OrderBy, p.orderItem())
+				if !p.accept(",") {
+					break
+				}
+			}
+		}
+		if p.accept("LIMIT") {
+			stmt.Limit = p.expr(p.span(p.clauseAt))
+		}
+		if p.accept("OFFSET") {
+			stmt.Offset = p.expr(p.span(p.clauseAt))
+		}
+
+		// unrecognized clauses, up to the end of the query or a set operation
+		stmt.Tail = p.expr(p.span(func(i int) bool {
+			return p.tokens[i].InFold(";", ")", "UNION", "INTERSECT", "EXCEPT", "MINUS")
+		}))
+		p.setOperator(stmt)
+	}
+	if stmt.SetOperator != "" {
+		if stmt.SetQuery, err = p.query(); err != nil {
+			return nil, err
+		}
+	}
+	return stmt, nil
+}
+
+// selectClauses parses the clauses of a query from SELECT to HAVING.
+func (p *selectParser) selectClauses(stmt *SelectStatement) error {
+	if !p.accept("SELECT") {
+		if i := p.peek(); i < len(p.tokens) {
+			return Error("expected SELECT, got %s", p.tokens[i].Text)
+		}
+		return Error("expected SELECT")
+	}
+	if p.accept("DISTINCT") {
+		stmt.Distinct = true
+		if p.accept("ON") {
+			stmt.DistinctOn = p.parens()
+		}
+	} else {
+		p.accept("ALL")
+	}
+	if p.accept("TOP") {
+		start := p.peek()
+		if p.parens() == nil && start < len(p.tokens) {
+			p.pos = start + 1
+		}
+		p.accept("PERCENT")
+		stmt.Top = p.expr(start, p.pos)
+	}
+
+	for {
+		start, end := p.span(p.listStop)
+		if start >= end {
+			return Error("expected a column")
+		}
+		stmt.Columns = append(stmt.Columns, p.column(start, end))
+		if !p.accept(",") {
+			break
+		}
+	}
+	if p.accept("INTO") {
+		stmt.Into = p.expr(p.span(p.clauseAt))
+	}
+	if p.accept("FROM") {
+		stmt.From = p.from()
+	}
+	if p.accept("WHERE") {
+		stmt.Where = p.expr(p.span(p.clauseAt))
+	}
+	if p.accept("GROUP", "BY") {
+		for {
+			stmt.GroupBy = append(stmt.GroupBy, p.expr(p.span(p.listStop)))
+			if !p.accept(",") {
+				break
+			}
+		}
+	}
+	if p.accept("HAVING") {
+		stmt.Having = p.expr(p.span(p.clauseAt))
+	}
+	return nil
+}
+
+// from parses the sources of a FROM clause, with their joins.
+func (p *selectParser) from() (sources []*TableSource) {
+	for {
+		source := p.source()
+		for {
+			i := p.joinAt(p.peek())
+			if i == 0 {
+				break
+			}
+			join := &Join{Type: strings.ToUpper(joinWords(p.tokens[p.peek():i]))}
+			p.pos = i
+			join.Source = p.source()
+			if p.accept("ON") {
+				join.On = p.expr(p.span(p.sourceStop))
+			} else if p.accept("USING") {
+				join.Using = p.parens()
+			}
+			source.Joins = append(source.Joins, join)
+		}
+		sources = append(sources, source)
+		if !p.accept(",") {
+			return sources
+		}
+	}
+}
+
+// setOperator consumes a set operator, if any.
+func (p *selectParser) setOperator(stmt *SelectStatement) bool {
+	for _, op := range []string{"UNION", "INTERSECT", "EXCEPT", "MINUS"} {
+		if p.accept(op) {
+			stmt.SetOperator = op
+			if p.accept("ALL") {
+				stmt.SetOperator += " ALL"
+			} else if p.accept("DISTINCT") {
+				stmt.SetOperator += " DISTINCT"
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// column returns the select column of the tokens in [start, end), with
+// its alias.
+func (p *selectParser) column(start, end int) *SelectColumn {
+	codes := p.codes(start, end)
+	if n := len(codes); n >= 2 {
+		last, prev := p.tokens[codes[n-1]], p.tokens[codes[n-2]]
+		switch {
+		case prev.EqualsFold("AS"):
+			return &SelectColumn{Expr: p.expr(start, codes[n-2]), Alias: last.Text}
+		case last.Kind == TokenIdentifier && (prev.Text == ")" || prev.EqualsFold("END") ||
+			prev.Kind == TokenIdentifier || prev.Kind == TokenString || prev.Kind == TokenNumber || prev.Kind == TokenParameter ||
+			prev.Kind == TokenKeyword && !DialectGeneric.IsReserved(prev.Text)):
+			return &SelectColumn{Expr: p.expr(start, codes[n-1]), Alias: last.Text}
+		}
+	}
+	return &SelectColumn{Expr: p.expr(start, end)}
+}
+
+// source parses a table source, without its joins.
+func (p *selectParser) source() *TableSource {
+	start, end := p.span(p.sourceStop)
+	codes := p.codes(start, end)
+	source := &TableSource{}
+	raw := func() *TableSource {
+		return &TableSource{Raw: p.expr(start, end)}
+	}
+	if len(codes) == 0 {
+		return raw()
+	}
+
+	// the table or subquery
+	rest := 0
+	if first := codes[0]; p.subquery(first) {
+		close := p.closing(first)
+		sub, err := p.parseSubquery(first, close)
+		if err != nil {
+			return raw()
+		}
+		source.Subquery = sub
+		for rest < len(codes) && codes[rest] <= close {
+			rest++
+		}
+	} else {
+		for rest < len(codes) {
+			tok := p.tokens[codes[rest]]
+			if tok.Kind != TokenIdentifier && tok.Kind != TokenKeyword || tok.Text == "(" {
+				return raw()
+			}
+			rest++
+			if rest < len(codes) && p.tokens[codes[rest]].Text == "." {
+				rest++
+				continue
+			}
+			break
+		}
+		source.Name = Tokens(p.tokens[codes[0] : codes[rest-1]+1]).Join()
+	}
+
+	// the alias
+	codes = codes[rest:]
+	if len(codes) == 2 && p.tokens[codes[0]].EqualsFold("AS") {
+		codes = codes[1:]
+	}
+	switch {
+	case len(codes) == 0:
+	case len(codes) == 1 && p.tokens[codes[0]].Kind == TokenIdentifier:
+		source.Alias = p.tokens[codes[0]].Text
+	default:
+		return raw()
+	}
+	return source
+}
+
+// orderItem parses an expression of the ORDER BY clause.
+func (p *selectParser) orderItem() *OrderItem {
+	start, end := p.span(p.listStop)
+	codes := p.codes(start, end)
+	n := len(codes)
+	var words []string
+	if n > 2 && p.tokens[codes[n-2]].EqualsFold("NULLS") && p.tokens[codes[n-1]].InFold("FIRST", "LAST") {
+		words = []string{"NULLS", strings.ToUpper(p.tokens[codes[n-1]].Text)}
+		n -= 2
+	}
+	if n > 1 && p.tokens[codes[n-1]].InFold("ASC", "DESC") {
+		words = append([]string{strings.ToUpper(p.tokens[codes[n-1]].Text)}, words...)
+		n--
+	}
+	if n < len(codes) {
+		end = codes[n-1] + 1
+	}
+	return &OrderItem{Expr: p.expr(start, end), Direction: strings.Join(words, " ")}
+}
+
+// codes returns the indexes of the tokens in [start, end) which are not
+// whitespace or comments.
+func (p *selectParser) codes(start, end int) (codes []int) {
+	for i := start; i < end; i = p.skip(i + 1) {
+		codes = append(codes, i)
+	}
+	return codes
+}
+
+// joinWords returns the words of tokens, separated by a space.
+func joinWords(tokens Tokens) string {
+	var words []string
+	for _, tok := range tokens {
+		if !tok.IsComment && !tok.IsWhitespace() {
+			words = append(words, tok.Text)
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// SQL renders the statement on a single line.
+func (s *SelectStatement) SQL() string {
+	var b strings.Builder
+	if len(s.With) > 0 {
+		b.WriteString("WITH ")
+		if s.Recursive {
+			b.WriteString("RECURSIVE ")
+		}
+		for i, cte := range s.With {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			b.WriteString(cte.Name)
+			if cte.Columns != nil {
+				b.WriteString(" " + cte.Columns.SQL())
+			}
+			b.WriteString(" AS ")
+			if cte.Modifier != "" {
+				b.WriteString(cte.Modifier + " ")
+			}
+			b.WriteString("(" + cte.Query.SQL() + ")")
+		}
+		b.WriteString(" ")
+	}
+
+	if s.Query != nil {
+		b.WriteString("(" + s.Query.SQL() + ")")
+	} else {
+		b.WriteString("SELECT")
+		if s.Distinct {
+			b.WriteString(" DISTINCT")
+			if s.DistinctOn != nil {
+				b.WriteString(" ON " + s.DistinctOn.SQL())
+			}
+		}
+		if s.Top != nil {
+			b.WriteString(" TOP " + s.Top.SQL())
+		}
+		for i, col := range s.Columns {
+			if i > 0 {
+				b.WriteString(",")
+			}
+			b.WriteString(" " + col.Expr.SQL())
+			if col.Alias != "" {
+				b.WriteString(" AS " + col.Alias)
+			}
+		}
+		if s.Into != nil {
+			b.WriteString(" INTO " + s.Into.SQL())
+		}
+		for i, source := range s.From {
+			if i == 0 {
+				b.WriteString(" FROM ")
+			} else {
+				b.WriteString(", ")
+			}
+			b.WriteString(source.SQL())
+		}
+		if s.Where != nil {
+			b.WriteString(" WHERE " + s.Where.SQL())
+		}
+		for i, e := range s.GroupBy {
+			if i == 0 {
+				b.WriteString(" GROUP BY ")
+			} else {
+				b.WriteString(", ")
+			}
+			b.WriteString(e.SQL())
+		}
+		if s.Having != nil {
+			b.WriteString(" HAVING " + s.Having.SQL())
+		}
+	}
+	for i, item := range s.OrderBy {
+		if i == 0 {
+			b.WriteString(" ORDER BY ")
+		} else {
+			b.WriteString(", ")
+		}
+		b.WriteString(item.Expr.SQL())
+		if item.Direction != "" {
+			b.WriteString(" " + item.Direction)
+		}
+	}
+	if s.Limit != nil {
+		b.WriteString(" LIMIT " + s.Limit.SQL())
+	}
+	if s.Offset != nil {
+		b.WriteString(" OFFSET " + s.Offset.SQL())
+	}
+	if s.Tail != nil {
+		b.WriteString(" " + s.Tail.SQL())
+	}
+	if s.SetOperator != "" && s.SetQuery != nil {
+		b.WriteString(" " + s.SetOperator + " " + s.SetQuery.SQL())
+	}
+	return b.String()
+}
+
+// SQL renders the source and its joins.
+func (ts *TableSource) SQL() string {
+	var b strings.Builder
+	switch {
+	case ts.Subquery != nil:
+		b.WriteString("(" + ts.Subquery.SQL() + ")")
+	case ts.Raw != nil:
+		b.WriteString(ts.Raw.SQL())
+	default:
+		b.WriteString(ts.Name)
+	}
+	if ts.Alias != "" {
+		b.WriteString(" " + ts.Alias)
+	}
+	for _, join := range ts.Joins {
+		b.WriteString(" " + join.Type + " " + join.Source.SQL())
+		if join.On != nil {
+			b.WriteString(" ON " + join.On.SQL())
+		}
+		if join.Using != nil {
+			b.WriteString(" USING " + join.Using.SQL())
+		}
+	}
+	return b.String()
+}
+
+// SQL renders the expression, with its whitespace collapsed.
+func (e *Expr) SQL() string {
+	if e == nil {
+		return ""
+	}
+	var b strings.Builder
+	space := false
+	for _, part := range e.Parts {
+		if part.Subquery != nil {
+			if space {
+				b.WriteString(" ")
+				space = false
+			}
+			b.WriteString("(" + part.Subquery.SQL() + ")")
+			continue
+		}
+		for _, tok := range part.Tokens {
+			if tok.IsWhitespace() {
+				space = b.Len() > 0
+				continue
+			}
+			if space {
+				b.WriteString(" ")
+				space = false
+			}
+			b.WriteString(tok.Text)
+		}
+	}
+	return b.String()
+}
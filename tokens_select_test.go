@@ -0,0 +1,128 @@
+package g
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSelect(t *testing.T) {
+	text := `with recent as (select id, max(ts) last_ts from events group by id), u (a) as materialized (select 1)
+select distinct r.id, count(*) as n, case when x > 1 then 'a' end flag, (select max(v) from w where w.id = r.id) top_v
+from recent r
+left outer join users u on u.id = r.id -- users
+join lateral generate_series(1, 3) g on true, other o
+where r.last_ts > now() - interval '1 day' and r.id in (select id from banned)
+group by r.id, 3 having count(*) > 1
+order by n desc nulls last, r.id
+limit 10 offset 5
+for update`
+
+	stmt, err := Tokenize(text, nil).ParseSelect()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	if assert.Len(t, stmt.With, 2) {
+		assert.Equal(t, "recent", stmt.With[0].Name)
+		assert.Equal(t, "SELECT id, max(ts) AS last_ts FROM events GROUP BY id", stmt.With[0].Query.SQL())
+		assert.Equal(t, "(a)", stmt.With[1].Columns.SQL())
+		assert.Equal(t, "MATERIALIZED", stmt.With[1].Modifier)
+	}
+	assert.True(t, stmt.Distinct)
+	if assert.Len(t, stmt.Columns, 4) {
+		assert.Equal(t, "r.id", stmt.Columns[0].Expr.SQL())
+		assert.Equal(t, "n", stmt.Columns[1].Alias)
+		assert.Equal(t, "flag", stmt.Columns[2].Alias)
+		assert.Equal(t, "top_v", stmt.Columns[3].Alias)
+		assert.NotNil(t, stmt.Columns[3].Expr.Parts[0].Subquery)
+	}
+	if assert.Len(t, stmt.From, 2) {
+		from := stmt.From[0]
+		assert.Equal(t, "recent", from.Name)
+		assert.Equal(t, "r", from.Alias)
+		if assert.Len(t, from.Joins, 2) {
+			assert.Equal(t, "LEFT OUTER JOIN", from.Joins[0].Type)
+			assert.Equal(t, "users", from.Joins[0].Source.Name)
+			assert.Equal(t, "u.id = r.id", from.Joins[0].On.SQL())
+			assert.Equal(t, "JOIN", from.Joins[1].Type)
+			assert.Equal(t, "lateral generate_series(1, 3) g", from.Joins[1].Source.Raw.SQL())
+		}
+		assert.Equal(t, "other", stmt.From[1].Name)
+	}
+	assert.Len(t, stmt.Where.Parts, 2)
+	assert.Len(t, stmt.GroupBy, 2)
+	assert.Equal(t, "count(*) > 1", stmt.Having.SQL())
+	if assert.Len(t, stmt.OrderBy, 2) {
+		assert.Equal(t, "DESC NULLS LAST", stmt.OrderBy[0].Direction)
+		assert.Equal(t, "", stmt.OrderBy[1].Direction)
+	}
+	assert.Equal(t, "10", stmt.Limit.SQL())
+	assert.Equal(t, "5", stmt.Offset.SQL())
+	assert.Equal(t, "for update", stmt.Tail.SQL())
+
+	// aliases following a non-reserved keyword, but not a reserved one
+	stmt2, err := Tokenize("select interval '1' day i, x between 1 and 2, $1 p from t", &TokenizeOptions{Dialect: DialectPostgres}).ParseSelect()
+	if assert.NoError(t, err) && assert.Len(t, stmt2.Columns, 3) {
+		assert.Equal(t, "interval '1' day", stmt2.Columns[0].Expr.SQL())
+		assert.Equal(t, "i", stmt2.Columns[0].Alias)
+		assert.Equal(t, "", stmt2.Columns[1].Alias)
+		assert.Equal(t, "p", stmt2.Columns[2].Alias)
+	}
+
+	// rewrite and render
+	stmt.From[0].Joins[0].Source.Name = "app.users"
+	stmt.Where.Parts[1].Subquery.Where = NewExpr("active")
+	stmt.Limit = NewExpr("100")
+	sql := stmt.SQL()
+	assert.Equal(t, "WITH recent AS (SELECT id, max(ts) AS last_ts FROM events GROUP BY id), u (a) AS MATERIALIZED (SELECT 1) "+
+		"SELECT DISTINCT r.id, count(*) AS n, case when x > 1 then 'a' end AS flag, (SELECT max(v) FROM w WHERE w.id = r.id) AS top_v "+
+		"FROM recent r LEFT OUTER JOIN app.users u ON u.id = r.id JOIN lateral generate_series(1, 3) g ON true, other o "+
+		"WHERE r.last_ts > now() - interval '1 day' and r.id in (SELECT id FROM banned WHERE active) "+
+		"GROUP BY r.id, 3 HAVING count(*) > 1 ORDER BY n DESC NULLS LAST, r.id LIMIT 100 OFFSET 5 for update", sql)
+
+	// the rendered statement parses the same
+	stmt2, err = Tokenize(sql, nil).ParseSelect()
+	if assert.NoError(t, err) {
+		assert.Equal(t, sql, stmt2.SQL())
+	}
+}
+
+func TestParseSelectSetOperations(t *testing.T) {
+	stmt, err := Tokenize("select top 5 a from [t] union all select b from (select b from u) x except select 1 order by 1;", &TokenizeOptions{Dialect: DialectSQLServer}).ParseSelect()
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "5", stmt.Top.SQL())
+	assert.Equal(t, "[t]", stmt.From[0].Name)
+	assert.Equal(t, "UNION ALL", stmt.SetOperator)
+	assert.Equal(t, "x", stmt.SetQuery.From[0].Alias)
+	assert.NotNil(t, stmt.SetQuery.From[0].Subquery)
+	assert.Equal(t, "EXCEPT", stmt.SetQuery.SetOperator)
+	assert.Len(t, stmt.SetQuery.SetQuery.OrderBy, 1)
+	assert.Equal(t, "SELECT TOP 5 a FROM [t] UNION ALL SELECT b FROM (SELECT b FROM u) x EXCEPT SELECT 1 ORDER BY 1", stmt.SQL())
+
+	// parenthesized queries
+	for text, expected := range map[string]string{
+		"(select 1)":                "(SELECT 1)",
+		"((select a from t))":       "((SELECT a FROM t))",
+		"select 1 union (select 2)": "SELECT 1 UNION (SELECT 2)",
+		"(select 1 union select 2) except (select 3) order by 1 limit 2": "(SELECT 1 UNION SELECT 2) EXCEPT (SELECT 3) ORDER BY 1 LIMIT 2",
+		"with c as (select 1) (select * from c)":                         "WITH c AS (SELECT 1) (SELECT * FROM c)",
+	} {
+		stmt, err = Tokenize(text, nil).ParseSelect()
+		if assert.NoError(t, err, text) {
+			assert.Equal(t, expected, stmt.SQL(), text)
+		}
+	}
+	stmt, _ = Tokenize("(select 1 union select 2) except (select 3) order by 1", nil).ParseSelect()
+	assert.Equal(t, "UNION", stmt.Query.SetOperator)
+	assert.Equal(t, "EXCEPT", stmt.SetOperator)
+	assert.Equal(t, "SELECT 3", stmt.SetQuery.Query.SQL())
+	assert.Len(t, stmt.SetQuery.OrderBy, 1)
+
+	_, err = Tokenize("update t set a = 1", nil).ParseSelect()
+	assert.ErrorContains(t, err, "expected SELECT, got update")
+	_, err = Tokenize("select a from t) x", nil).ParseSelect()
+	assert.ErrorContains(t, err, "unexpected )")
+}
@@ -0,0 +1,270 @@
+package g
+
+import (
+	"strings"
+)
+
+// Statement is a SQL statement split from a text by SplitStatements.
+type Statement struct {
+	Text  string   `json:"text"`  // The text of the statement, without its delimiter
+	Start Position `json:"start"` // The position of the first character
+	End   Position `json:"end"`   // The position following the last character
+}
+
+// SplitOptions configures SplitStatements.
+type SplitOptions struct {
+	// Dialect holds the lexical rules of the text. Defaults to DialectGeneric.
+	Dialect *SQLDialect
+	// KeepComments attaches the comments preceding a statement to it.
+	KeepComments bool
+}
+
+// SplitStatements splits text into its statements, delimited by `;`.
+// Semicolons in strings, comments, BEGIN ... END blocks and routine
+// bodies do not end a statement. The client commands `DELIMITER xx`,
+// which changes the delimiter, and the dialect BatchSeparator, such as
+// `GO`, are recognized on their own line. Empty statements are skipped.
+func SplitStatements(text string, options *SplitOptions) (statements []Statement) {
+	if options == nil {
+		options = &SplitOptions{}
+	}
+	s := &statementSplitter{
+		text:      text,
+		options:   options,
+		dialect:   (&TokenizeOptions{Dialect: options.Dialect}).dialect(),
+		delimiter: ";",
+	}
+	s.reset()
+
+	tokens := Tokenize(text, &TokenizeOptions{Dialect: s.dialect}).Tokens
+	offsets := make([]int, len(tokens)+1)
+	for i, tok := range tokens {
+		offsets[i+1] = offsets[i] + len(tok.Text)
+	}
+
+	for i := 0; i < len(tokens); i++ {
+		tok, off := tokens[i], offsets[i]
+		switch {
+		case tok.Kind == TokenWhitespace:
+			continue
+		case tok.IsComment:
+			if s.start < 0 {
+				if s.commentStart < 0 {
+					s.commentStart = off
+				}
+			} else {
+				s.end = off + len(strings.TrimRight(tok.Text, " \t\r\n"))
+			}
+			continue
+		case s.start < 0 && tok.EqualsFold("DELIMITER") && s.lineStart(off):
+			// client command changing the delimiter up to the end of the line
+			lineEnd := strings.IndexByte(text[off:], '\n')
+			if lineEnd < 0 {
+				lineEnd = len(text)
+			} else {
+				lineEnd += off
+			}
+			if delimiter := strings.TrimSpace(text[off+len(tok.Text) : lineEnd]); delimiter != "" {
+				s.delimiter = delimiter
+			}
+			for i+1 < len(tokens) && offsets[i+1] < lineEnd {
+				i++
+			}
+			s.commentStart = -1
+			continue
+		case s.dialect.BatchSeparator != "" && tok.EqualsFold(s.dialect.BatchSeparator) &&
+			s.lineStart(off) && s.lineEnd(off+len(tok.Text)):
+			statements = s.flush(statements)
+			continue
+		}
+
+		if s.delimiter == ";" {
+			s.track(tokens, i)
+		}
+
+		// the delimiter may be glued to other characters, such as END$$
+		pos := 0
+		for s.delimiterAllowed(tok) {
+			idx := strings.Index(tok.Text[pos:], s.delimiter)
+			if idx < 0 {
+				break
+			}
+			s.content(off+pos, off+pos+idx)
+			statements = s.flush(statements)
+			pos += idx + len(s.delimiter)
+		}
+		s.content(off+pos, off+len(tok.Text))
+		if s.start == off && pos == 0 {
+			s.baseLevel = tok.ParenthesisLevel
+		}
+	}
+
+	return s.flush(statements)
+}
+
+// statementSplitter holds the state of SplitStatements.
+type statementSplitter struct {
+	text      string
+	options   *SplitOptions
+	dialect   *SQLDialect
+	delimiter string
+	cursor    positionCursor
+
+	start        int // offset of the first character of the statement, or -1
+	end          int // offset following the last character of the statement
+	commentStart int // offset of the first leading comment, or -1
+	baseLevel    int // parenthesis level of the statement
+
+	words      []string // first words of the statement, in upper case
+	blockDepth int      // depth of BEGIN ... END and CASE ... END blocks
+	inBody     bool     // in the body of a routine, up to the end of its block
+}
+
+func (s *statementSplitter) reset() {
+	s.start, s.end, s.commentStart = -1, -1, -1
+	s.words = s.words[:0]
+	s.blockDepth = 0
+	s.inBody = false
+}
+
+// content records text[start:end] as part of the statement.
+func (s *statementSplitter) content(start, end int) {
+	if start >= end || strings.TrimSpace(s.text[start:end]) == "" {
+		return
+	}
+	if s.start < 0 {
+		s.start = start
+	}
+	s.end = end
+}
+
+// flush appends the current statement, if any, and starts the next one.
+func (s *statementSplitter) flush(statements []Statement) []Statement {
+	if s.start >= 0 {
+		start := s.start
+		if s.options.KeepComments && s.commentStart >= 0 {
+			start = s.commentStart
+		}
+		statements = append(statements, Statement{
+			Text:  s.text[start:s.end],
+			Start: s.cursor.position(s.text, start),
+			End:   s.cursor.position(s.text, s.end),
+		})
+	}
+	s.reset()
+	return statements
+}
+
+// delimiterAllowed returns true if the delimiter ends a statement in tok.
+func (s *statementSplitter) delimiterAllowed(tok Token) bool {
+	switch {
+	case tok.Kind == TokenString || tok.Kind == TokenComment || tok.Kind == TokenWhitespace:
+		return false
+	case tok.Kind == TokenIdentifier && strings.IndexByte("\"`[", tok.Text[0]) >= 0:
+		return false
+	case s.delimiter == ";" && (s.blockDepth > 0 || s.inBody):
+		return false
+	}
+	return true
+}
+
+// track follows the procedural blocks and routine bodies, in which the
+// `;` delimiter does not end the statement.
+func (s *statementSplitter) track(tokens Tokens, i int) {
+	tok := tokens[i]
+	if tok.Kind != TokenKeyword && tok.Kind != TokenIdentifier {
+		return
+	}
+	word := strings.ToUpper(tok.Text)
+	if len(s.words) < 6 {
+		s.words = append(s.words, word)
+	}
+	next := strings.ToUpper(tok.NextNonWhitespace().Text)
+
+	switch word {
+	case "BEGIN":
+		// BEGIN alone starts a transaction
+		switch next {
+		case "", ";", "TRANSACTION", "TRAN", "WORK", "DEFERRED", "IMMEDIATE", "EXCLUSIVE", "ISOLATION", "DISTRIBUTED":
+		default:
+			s.blockDepth++
+		}
+	case "CASE":
+		// END CASE closes the block opened by CASE
+		if !tok.PreviousNonWhitespace().EqualsFold("END") {
+			s.blockDepth++
+		}
+	case "END":
+		switch next {
+		case "IF", "LOOP", "WHILE", "REPEAT", "FOR":
+		default:
+			if s.blockDepth > 0 {
+				s.blockDepth--
+				if s.blockDepth == 0 {
+					s.inBody = false
+				}
+			}
+		}
+	case "AS", "IS":
+		// the body of a routine, unless quoted such as AS $$ ... $$
+		if s.routine() && !s.inBody && s.blockDepth == 0 && tok.ParenthesisLevel == s.baseLevel &&
+			tok.NextNonWhitespace().Kind != TokenString {
+			s.inBody = true
+		}
+	}
+}
+
+// routine returns true if the statement creates a function, procedure
+// or trigger.
+func (s *statementSplitter) routine() bool {
+	if len(s.words) == 0 || s.words[0] != "CREATE" {
+		return false
+	}
+	for _, word := range s.words[1:] {
+		switch word {
+		case "FUNCTION", "PROCEDURE", "PROC", "TRIGGER", "PACKAGE":
+			return true
+		}
+	}
+	return false
+}
+
+// lineStart returns true if only whitespace precedes offset on its line.
+func (s *statementSplitter) lineStart(offset int) bool {
+	line := s.text[:offset]
+	if i := strings.LastIndexByte(line, '\n'); i >= 0 {
+		line = line[i+1:]
+	}
+	return strings.TrimSpace(line) == ""
+}
+
+// lineEnd returns true if only whitespace follows offset on its line.
+func (s *statementSplitter) lineEnd(offset int) bool {
+	line := s.text[offset:]
+	if i := strings.IndexByte(line, '\n'); i >= 0 {
+		line = line[:i]
+	}
+	return strings.TrimSpace(line) == ""
+}
+
+// positionCursor computes the positions of increasing offsets in a text.
+type positionCursor struct {
+	offset   int
+	line     int
+	lineFrom int // offset of the start of the line
+}
+
+// position returns the position of offset in text, with lines starting
+// at 1 and columns at 0.
+func (c *positionCursor) position(text string, offset int) Position {
+	if c.line == 0 || offset < c.offset {
+		*c = positionCursor{line: 1}
+	}
+	for ; c.offset < offset && c.offset < len(text); c.offset++ {
+		if text[c.offset] == '\n' {
+			c.line++
+			c.lineFrom = c.offset + 1
+		}
+	}
+	return Position{Line: c.line, Column: offset - c.lineFrom}
+}
@@ -0,0 +1,78 @@
+package g
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitStatements(t *testing.T) {
+	texts := func(statements []Statement) (ss []string) {
+		for _, s := range statements {
+			ss = append(ss, s.Text)
+		}
+		return
+	}
+
+	text := "-- users\ncreate table t (a int, b text);\ninsert into t values (1, 'a;b'); /* c; */ ;\n\nselect case when a = 1 then 'x' end as c from t"
+	statements := SplitStatements(text, nil)
+	assert.Equal(t, []string{
+		"create table t (a int, b text)",
+		"insert into t values (1, 'a;b')",
+		"select case when a = 1 then 'x' end as c from t",
+	}, texts(statements))
+	assert.Equal(t, Position{Line: 2, Column: 0}, statements[0].Start)
+	assert.Equal(t, Position{Line: 2, Column: 30}, statements[0].End)
+	assert.Equal(t, Position{Line: 5, Column: 0}, statements[2].Start)
+
+	statements = SplitStatements(text, &SplitOptions{KeepComments: true})
+	assert.Equal(t, "-- users\ncreate table t (a int, b text)", statements[0].Text)
+	assert.Equal(t, Position{Line: 1, Column: 0}, statements[0].Start)
+	assert.Equal(t, "insert into t values (1, 'a;b')", statements[1].Text)
+	assert.Equal(t, "select case when a = 1 then 'x' end as c from t", statements[2].Text)
+
+	// routine bodies and blocks
+	text = `create function f() returns int as $$ begin return 1; end; $$ language plpgsql;
+do $body$ begin perform 1; end $body$;
+begin;
+create trigger tr before insert on t for each row begin set new.a = 1; if new.b then set new.c = 2; end if; end;
+commit`
+	assert.Equal(t, []string{
+		"create function f() returns int as $$ begin return 1; end; $$ language plpgsql",
+		"do $body$ begin perform 1; end $body$",
+		"begin",
+		"create trigger tr before insert on t for each row begin set new.a = 1; if new.b then set new.c = 2; end if; end",
+		"commit",
+	}, texts(SplitStatements(text, &SplitOptions{Dialect: DialectPostgres})))
+
+	text = "CREATE PROCEDURE p() BEGIN CASE x WHEN 1 THEN SELECT 1; END CASE; END; SELECT 2; SELECT 3;"
+	assert.Equal(t, []string{
+		"CREATE PROCEDURE p() BEGIN CASE x WHEN 1 THEN SELECT 1; END CASE; END",
+		"SELECT 2",
+		"SELECT 3",
+	}, texts(SplitStatements(text, &SplitOptions{Dialect: DialectMySQL})))
+
+	text = `create or replace procedure p is x int; begin x := 1; end;
+select 1`
+	assert.Equal(t, []string{
+		"create or replace procedure p is x int; begin x := 1; end",
+		"select 1",
+	}, texts(SplitStatements(text, nil)))
+
+	// client commands
+	text = "DELIMITER //\ncreate procedure p() begin select 1; select 2; end//\nDELIMITER ;\nselect 3; select 4"
+	assert.Equal(t, []string{
+		"create procedure p() begin select 1; select 2; end",
+		"select 3",
+		"select 4",
+	}, texts(SplitStatements(text, &SplitOptions{Dialect: DialectMySQL})))
+
+	text = "DELIMITER $$\ncreate procedure p() begin select 1; end$$\nDELIMITER ;"
+	assert.Equal(t, []string{"create procedure p() begin select 1; end"}, texts(SplitStatements(text, &SplitOptions{Dialect: DialectMySQL})))
+
+	text = "create procedure p as\nselect [a;b] from t;\nselect 2\nGO\nselect 3\ngo\n"
+	statements = SplitStatements(text, &SplitOptions{Dialect: DialectSQLServer})
+	assert.Equal(t, []string{"create procedure p as\nselect [a;b] from t;\nselect 2", "select 3"}, texts(statements))
+	assert.Equal(t, Position{Line: 5, Column: 0}, statements[1].Start)
+	assert.Equal(t, Position{Line: 5, Column: 8}, statements[1].End)
+}
@@ -92,3 +92,80 @@ func TestTokenMethods(t *testing.T) {
 		assert.Equal(t, "5", trimmed[len(trimmed)-1].Text)
 	})
 }
+
+func TestTokenizeDialects(t *testing.T) {
+	texts := func(tb TokenBody) (ss []string) {
+		for _, tok := range tb.Tokens {
+			if !tok.IsWhitespace() {
+				ss = append(ss, tok.Text)
+			}
+		}
+		return
+	}
+
+	tb := Tokenize(`select $body$ it's ) $x$ $body$, $$a$$, $1 from t`, &TokenizeOptions{Dialect: DialectPostgres})
+	assert.Equal(t, []string{"select", "$body$ it's ) $x$ $body$", ",", "$$a$$", ",", "$1", "from", "t"}, texts(tb))
+
+	tb = Tokenize(`select 1 /* a /* b */ c */ , E'it\'s', 'x''y'`, &TokenizeOptions{Dialect: DialectPostgres})
+	assert.Equal(t, []string{"select", "1", "/* a /* b */ c */", ",", `E'it\'s'`, ",", `'x''y'`}, texts(tb))
+	assert.True(t, tb.Tokens[4].IsComment)
+
+	tb = Tokenize(`select 'a\' # b`+"\n"+`from t`, &TokenizeOptions{Dialect: DialectPostgres})
+	assert.Equal(t, []string{"select", `'a\'`, "#", "b", "from", "t"}, texts(tb))
+
+	tb = Tokenize(`select 'a\'' # b`+"\n"+`from "t"`, &TokenizeOptions{Dialect: DialectMySQL})
+	assert.Equal(t, []string{"select", `'a\''`, "# b\n", "from", `"t"`}, texts(tb))
+	assert.Equal(t, TokenString, tb.Tokens[len(tb.Tokens)-1].Kind)
+
+	tb = Tokenize(`select [my col]] (x)] from [dbo].[t] /* a /* b */`, &TokenizeOptions{Dialect: DialectSQLServer})
+	assert.Equal(t, []string{"select", "[my col]] (x)]", "from", "[dbo]", ".", "[t]", "/* a /* b */"}, texts(tb))
+	assert.Equal(t, 0, tb.Tokens[2].ParenthesisLevel)
+
+	// block comments do not nest by default
+	tb = Tokenize(`/*/ a /* b */ c */`, nil)
+	assert.Equal(t, []string{"/*/ a /* b */", "c", "*/"}, texts(tb))
+}
+
+func TestTokenKinds(t *testing.T) {
+	tb := Tokenize(`SELECT t.id, count(*) AS "n" -- total`+"\nFROM users t WHERE name = 'x' AND id >= 10", nil)
+	kinds := map[string]TokenKind{}
+	for _, tok := range tb.Tokens {
+		kinds[tok.Text] = tok.Kind
+	}
+	assert.Equal(t, TokenKeyword, kinds["SELECT"])
+	assert.Equal(t, TokenKeyword, kinds["AS"])
+	assert.Equal(t, TokenIdentifier, kinds["users"])
+	assert.Equal(t, TokenIdentifier, kinds["count"])
+	assert.Equal(t, TokenIdentifier, kinds[`"n"`])
+	assert.Equal(t, TokenString, kinds["'x'"])
+	assert.Equal(t, TokenNumber, kinds["10"])
+	assert.Equal(t, TokenOperator, kinds[">="])
+	assert.Equal(t, TokenOperator, kinds["*"])
+	assert.Equal(t, TokenPunctuation, kinds[","])
+	assert.Equal(t, TokenPunctuation, kinds["."])
+	assert.Equal(t, TokenPunctuation, kinds["("])
+	assert.Equal(t, TokenComment, kinds["-- total\n"])
+	assert.Equal(t, TokenWhitespace, kinds[" "])
+
+	tb = Tokenize(`select 1.5, .5e-3+2.E3, t.5, 0x1F, $1 from t where x = $12`, &TokenizeOptions{Dialect: DialectPostgres})
+	kinds = map[string]TokenKind{}
+	for _, tok := range tb.Tokens {
+		kinds[tok.Text] = tok.Kind
+	}
+	for _, text := range []string{"1.5", ".5e-3", "2.E3", "5", "0x1F"} {
+		assert.Equal(t, TokenNumber, kinds[text], text)
+	}
+	assert.Equal(t, TokenPunctuation, kinds["."])
+	assert.Equal(t, TokenOperator, kinds["+"])
+	assert.Equal(t, TokenParameter, kinds["$1"])
+	assert.Equal(t, TokenParameter, kinds["$12"])
+
+	// punctuation is a token of its own next to operators
+	var texts []string
+	for _, tok := range Tokenize("select t.*, -1,a<>b;select u.*from u", nil).Tokens {
+		if !tok.IsWhitespace() {
+			texts = append(texts, tok.Text)
+		}
+	}
+	assert.Equal(t, []string{"select", "t", ".", "*", ",", "-", "1", ",", "a", "<>", "b", ";", "select", "u", ".", "*", "from", "u"}, texts)
+}